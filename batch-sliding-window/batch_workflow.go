@@ -2,9 +2,10 @@ package batch_sliding_window
 
 import (
 	"fmt"
+	"time"
+
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
-	"time"
 )
 
 // ProcessBatchWorkflowInput input of the ProcessBatchWorkflow.
@@ -13,67 +14,87 @@ type ProcessBatchWorkflowInput struct {
 	PageSize          int // Number of children started by a single sliding window workflow run
 	SlidingWindowSize int // Maximum number of children to run in parallel.
 	Partitions        int // How many sliding windows to run in parallel.
-}
 
-// ProcessBatchWorkflow sample Partitions the data set into continuous ranges.
-// A real application can choose any other way to divide the records into multiple collections.
-func ProcessBatchWorkflow(ctx workflow.Context, input ProcessBatchWorkflowInput) (processed int, err error) {
-	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-		StartToCloseTimeout: 5 * time.Second,
-	})
-
-	var recordLoader *RecordLoader // RecordLoader activity reference
-	var recordCount int
-	err = workflow.ExecuteActivity(ctx, recordLoader.GetRecordCount).Get(ctx, &recordCount)
-	if err != nil {
-		return 0, err
-	}
+	// LoaderActivity is the name of the activity that loads a page of records of a partition,
+	// see LoadRecordsInput and LoadRecordsOutput.
+	LoaderActivity string
+	// RecordWorkflow is the name of the child workflow that processes a single Record.
+	// It must call ReportCompletion when done. Exactly one of RecordWorkflow and RecordActivity is required.
+	RecordWorkflow string
+	// RecordActivity is the name of the activity that processes a single Record.
+	RecordActivity string
+	// ActivityTimeout is the StartToCloseTimeout of the loader and record activities. Defaults to 5 seconds.
+	ActivityTimeout time.Duration
+	// MaxRecordAttempts is how many times a failed record is processed before it is given up. Defaults to 1.
+	// Record activities are not retried by Temporal, only up to MaxRecordAttempts.
+	MaxRecordAttempts int
+	// DeadLetterActivity is the name of an optional activity that receives a DeadLetterInput for every record that
+	// failed all attempts, for example to store it for manual processing.
+	DeadLetterActivity string
+}
 
+// ProcessBatchWorkflow processes all records returned by the loader activity.
+// It starts a SlidingWindowWorkflow for each partition, and the loader activity decides which records belong to
+// which partition. The records that failed are returned by the FailuresQueryType query.
+func ProcessBatchWorkflow(ctx workflow.Context, input ProcessBatchWorkflowInput) (summary BatchSummary, err error) {
 	if input.SlidingWindowSize < input.Partitions {
-		return 0, temporal.NewApplicationError(
+		return BatchSummary{}, temporal.NewApplicationError(
 			"SlidingWindowSize cannot be less than number of partitions", "invalidInput")
 	}
-	partitions := divideIntoPartitions(recordCount, input.Partitions)
+	if input.LoaderActivity == "" {
+		return BatchSummary{}, temporal.NewApplicationError("LoaderActivity is required", "invalidInput")
+	}
+	if (input.RecordWorkflow == "") == (input.RecordActivity == "") {
+		return BatchSummary{}, temporal.NewApplicationError(
+			"exactly one of RecordWorkflow and RecordActivity is required", "invalidInput")
+	}
+	var failures []RecordFailure
+	err = setFailuresQueryHandler(ctx, func() []RecordFailure {
+		return failures
+	})
+	if err != nil {
+		return BatchSummary{}, err
+	}
 	windowSizes := divideIntoPartitions(input.SlidingWindowSize, input.Partitions)
 
 	workflow.GetLogger(ctx).Info("ProcessBatchWorkflow",
 		"input", input,
-		"recordCount", recordCount,
-		"partitions", partitions,
 		"windowSizes", windowSizes)
 
 	var results []workflow.ChildWorkflowFuture
-	offset := 0
 	for i := 0; i < input.Partitions; i++ {
 		// Makes child id more user-friendly
 		childId := fmt.Sprintf("%s/%d", workflow.GetInfo(ctx).WorkflowExecution.ID, i)
 		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{WorkflowID: childId})
-		// Define partition boundaries.
-		maximumPartitionOffset := offset + partitions[i]
-		if maximumPartitionOffset > recordCount {
-			maximumPartitionOffset = recordCount
-		}
 		input := SlidingWindowWorkflowInput{
-			PageSize:          input.PageSize,
-			SlidingWindowSize: windowSizes[i],
-			Offset:            offset,                 // inclusive
-			MaximumOffset:     maximumPartitionOffset, // exclusive
+			LoaderActivity:     input.LoaderActivity,
+			RecordWorkflow:     input.RecordWorkflow,
+			RecordActivity:     input.RecordActivity,
+			ActivityTimeout:    input.ActivityTimeout,
+			MaxRecordAttempts:  input.MaxRecordAttempts,
+			DeadLetterActivity: input.DeadLetterActivity,
+			Partition:          i,
+			Partitions:         input.Partitions,
+			PageSize:           input.PageSize,
+			SlidingWindowSize:  windowSizes[i],
 		}
 		child := workflow.ExecuteChildWorkflow(childCtx, SlidingWindowWorkflow, input)
 		results = append(results, child)
-		offset += partitions[i]
 	}
+	// Forwards pause, resume, resize and cancel signals to all partitions
+	forwardControlSignals(ctx, results, input)
+
 	// Waits for all child workflows to complete
-	result := 0
 	for _, partitionResult := range results {
-		var r int
+		var r SlidingWindowResult
 		err := partitionResult.Get(ctx, &r) // blocks until the child completion
 		if err != nil {
-			return 0, err
+			return BatchSummary{}, err
 		}
-		result += r
+		summary.add(r.Summary)
+		failures = append(failures, r.Failures...)
 	}
-	return result, nil
+	return summary, nil
 }
 
 func divideIntoPartitions(number int, n int) []int {
@@ -0,0 +1,252 @@
+package batch_sliding_window
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func Test_ProcessBatchWorkflow_RecordWorkflow(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SlidingWindowWorkflow)
+	env.RegisterWorkflow(RecordProcessorWorkflow)
+	env.RegisterActivity(&RecordLoader{RecordCount: 23})
+
+	env.ExecuteWorkflow(ProcessBatchWorkflow, ProcessBatchWorkflowInput{
+		PageSize:          5,
+		SlidingWindowSize: 6,
+		Partitions:        3,
+		LoaderActivity:    "GetRecords",
+		RecordWorkflow:    "RecordProcessorWorkflow",
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var summary BatchSummary
+	require.NoError(t, env.GetWorkflowResult(&summary))
+	require.Equal(t, BatchSummary{Succeeded: 23}, summary)
+}
+
+func Test_ProcessBatchWorkflow_RecordActivity(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SlidingWindowWorkflow)
+	env.RegisterActivity(&RecordLoader{RecordCount: 12})
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	env.RegisterActivityWithOptions(func(ctx context.Context, r Record) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.Key] = true
+		return nil
+	}, activity.RegisterOptions{Name: "ProcessRecordActivity"})
+
+	env.ExecuteWorkflow(ProcessBatchWorkflow, ProcessBatchWorkflowInput{
+		PageSize:          3,
+		SlidingWindowSize: 4,
+		Partitions:        2,
+		LoaderActivity:    "GetRecords",
+		RecordActivity:    "ProcessRecordActivity",
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var summary BatchSummary
+	require.NoError(t, env.GetWorkflowResult(&summary))
+	require.Equal(t, BatchSummary{Succeeded: 12}, summary)
+	require.Len(t, seen, 12)
+}
+
+func Test_ProcessBatchWorkflow_InvalidInput(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+
+	env.ExecuteWorkflow(ProcessBatchWorkflow, ProcessBatchWorkflowInput{
+		PageSize:          3,
+		SlidingWindowSize: 4,
+		Partitions:        2,
+		LoaderActivity:    "GetRecords",
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), "exactly one of RecordWorkflow and RecordActivity is required")
+}
+
+func Test_SlidingWindowWorkflow_PauseResizeResume(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&RecordLoader{RecordCount: 10})
+	env.RegisterActivityWithOptions(func(ctx context.Context, r Record) error {
+		return nil
+	}, activity.RegisterOptions{Name: "ProcessRecordActivity"})
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(PauseSignalName, nil)
+	}, 0)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(ResizeSignalName, 5)
+	}, time.Minute)
+	env.RegisterDelayedCallback(func() {
+		result, err := env.QueryWorkflow("state")
+		require.NoError(t, err)
+		var state SlidingWindowState
+		require.NoError(t, result.Get(&state))
+		require.True(t, state.Paused)
+		require.Equal(t, 5, state.SlidingWindowSize)
+		require.Equal(t, 0, state.Progress)
+		env.SignalWorkflow(ResumeSignalName, nil)
+	}, time.Minute*2)
+
+	env.ExecuteWorkflow(SlidingWindowWorkflow, SlidingWindowWorkflowInput{
+		LoaderActivity:    "GetRecords",
+		RecordActivity:    "ProcessRecordActivity",
+		Partitions:        1,
+		PageSize:          20,
+		SlidingWindowSize: 2,
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result SlidingWindowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, BatchSummary{Succeeded: 10}, result.Summary)
+}
+
+func Test_ProcessBatchWorkflow_Cancel(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SlidingWindowWorkflow)
+	env.RegisterActivity(&RecordLoader{RecordCount: 60})
+	// Every record takes a minute, so each partition completes two records and has two in flight when
+	// the batch is cancelled.
+	env.RegisterWorkflowWithOptions(func(ctx workflow.Context, r Record) error {
+		err := workflow.Sleep(ctx, time.Minute)
+		if signalErr := ReportCompletion(ctx, r, err); signalErr != nil {
+			return signalErr
+		}
+		return err
+	}, workflow.RegisterOptions{Name: "MinuteRecordWorkflow"})
+	var mu sync.Mutex
+	var deadLettered []string
+	env.RegisterActivityWithOptions(func(ctx context.Context, input DeadLetterInput) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deadLettered = append(deadLettered, input.Record.Key)
+		return nil
+	}, activity.RegisterOptions{Name: "DeadLetterActivity"})
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(CancelSignalName, CancelBatchRequest{CancelInFlight: true})
+	}, time.Minute+30*time.Second)
+
+	env.ExecuteWorkflow(ProcessBatchWorkflow, ProcessBatchWorkflowInput{
+		PageSize:           5,
+		SlidingWindowSize:  6,
+		Partitions:         3,
+		LoaderActivity:     "GetRecords",
+		RecordWorkflow:     "MinuteRecordWorkflow",
+		MaxRecordAttempts:  3,
+		DeadLetterActivity: "DeadLetterActivity",
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var summary BatchSummary
+	require.NoError(t, env.GetWorkflowResult(&summary))
+	require.Equal(t, BatchSummary{Succeeded: 6, Failed: 6}, summary)
+	// The cancelled records are neither retried nor dead-lettered.
+	require.Empty(t, deadLettered)
+
+	result, err := env.QueryWorkflow(FailuresQueryType, 0, 0)
+	require.NoError(t, err)
+	var page FailuresPage
+	require.NoError(t, result.Get(&page))
+	require.Len(t, page.Failures, 6)
+	for _, failure := range page.Failures {
+		require.Equal(t, 1, failure.Attempts)
+		require.False(t, failure.DeadLettered)
+		require.Contains(t, failure.Error, "canceled")
+	}
+}
+
+func Test_ProcessBatchWorkflow_RetryAndDeadLetter(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SlidingWindowWorkflow)
+	env.RegisterActivity(&RecordLoader{RecordCount: 12})
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	env.RegisterActivityWithOptions(func(ctx context.Context, r Record) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[r.Key]++
+		switch r.Key {
+		case "3", "7":
+			// Always fails
+			return errors.New("bad record " + r.Key)
+		case "5":
+			// Succeeds on the second attempt
+			if r.Attempt == 1 {
+				return errors.New("flaky record")
+			}
+		}
+		return nil
+	}, activity.RegisterOptions{Name: "ProcessRecordActivity"})
+	var deadLettered []string
+	env.RegisterActivityWithOptions(func(ctx context.Context, input DeadLetterInput) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if input.Record.Key == "7" {
+			return temporal.NewNonRetryableApplicationError("dead letter queue unavailable", "unavailable", nil)
+		}
+		deadLettered = append(deadLettered, input.Record.Key)
+		return nil
+	}, activity.RegisterOptions{Name: "DeadLetterActivity"})
+
+	env.ExecuteWorkflow(ProcessBatchWorkflow, ProcessBatchWorkflowInput{
+		PageSize:           3,
+		SlidingWindowSize:  4,
+		Partitions:         2,
+		LoaderActivity:     "GetRecords",
+		RecordActivity:     "ProcessRecordActivity",
+		MaxRecordAttempts:  3,
+		DeadLetterActivity: "DeadLetterActivity",
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var summary BatchSummary
+	require.NoError(t, env.GetWorkflowResult(&summary))
+	require.Equal(t, BatchSummary{Succeeded: 10, Failed: 1, DeadLettered: 1}, summary)
+	require.Equal(t, 3, attempts["3"])
+	require.Equal(t, 2, attempts["5"])
+	require.Equal(t, []string{"3"}, deadLettered)
+
+	result, err := env.QueryWorkflow(FailuresQueryType, 0, 1)
+	require.NoError(t, err)
+	var page FailuresPage
+	require.NoError(t, result.Get(&page))
+	require.Len(t, page.Failures, 1)
+	require.Equal(t, "3", page.Failures[0].Key)
+	require.Equal(t, 3, page.Failures[0].Attempts)
+	require.True(t, page.Failures[0].DeadLettered)
+	require.Equal(t, 1, page.NextOffset)
+
+	result, err = env.QueryWorkflow(FailuresQueryType, page.NextOffset, 1)
+	require.NoError(t, err)
+	require.NoError(t, result.Get(&page))
+	require.Len(t, page.Failures, 1)
+	require.Equal(t, "7", page.Failures[0].Key)
+	require.False(t, page.Failures[0].DeadLettered)
+	require.Contains(t, page.Failures[0].Error, "bad record 7")
+	require.Zero(t, page.NextOffset)
+}
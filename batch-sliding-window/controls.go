@@ -0,0 +1,189 @@
+package batch_sliding_window
+
+import (
+	"fmt"
+	"sort"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// Signals that control a running batch. Send them to ProcessBatchWorkflow to control all partitions,
+// or to a single SlidingWindowWorkflow to control one partition.
+const (
+	// PauseSignalName stops starting new records. Records in flight keep running.
+	PauseSignalName = "pause"
+	// ResumeSignalName starts new records again after a pause.
+	ResumeSignalName = "resume"
+	// ResizeSignalName changes the sliding window size. The value is the new size as int.
+	// ProcessBatchWorkflow divides it between the partitions.
+	ResizeSignalName = "resize"
+	// CancelSignalName stops starting new records and completes the batch once no records are in flight.
+	// The value is a CancelBatchRequest.
+	CancelSignalName = "cancel"
+)
+
+// CancelBatchRequest is the value of the CancelSignalName signal.
+type CancelBatchRequest struct {
+	// CancelInFlight cancels the records in flight instead of waiting for them to complete.
+	CancelInFlight bool
+}
+
+// addControlReceivers adds the control signals of the partition to the signal pump selector.
+func (s *SlidingWindow) addControlReceivers(ctx workflow.Context, selector workflow.Selector) {
+	selector.AddReceive(workflow.GetSignalChannel(ctx, PauseSignalName), func(c workflow.ReceiveChannel, more bool) {
+		c.Receive(ctx, nil)
+		s.pause(ctx)
+	})
+	selector.AddReceive(workflow.GetSignalChannel(ctx, ResumeSignalName), func(c workflow.ReceiveChannel, more bool) {
+		c.Receive(ctx, nil)
+		s.resume(ctx)
+	})
+	selector.AddReceive(workflow.GetSignalChannel(ctx, ResizeSignalName), func(c workflow.ReceiveChannel, more bool) {
+		var size int
+		c.Receive(ctx, &size)
+		s.resize(ctx, size)
+	})
+	selector.AddReceive(workflow.GetSignalChannel(ctx, CancelSignalName), func(c workflow.ReceiveChannel, more bool) {
+		var request CancelBatchRequest
+		c.Receive(ctx, &request)
+		s.cancel(ctx, request)
+	})
+}
+
+// drainControlSignalsAsync handles the control signals that arrived without blocking.
+func (s *SlidingWindow) drainControlSignalsAsync(ctx workflow.Context) {
+	for workflow.GetSignalChannel(ctx, PauseSignalName).ReceiveAsync(nil) {
+		s.pause(ctx)
+	}
+	for workflow.GetSignalChannel(ctx, ResumeSignalName).ReceiveAsync(nil) {
+		s.resume(ctx)
+	}
+	var size int
+	for workflow.GetSignalChannel(ctx, ResizeSignalName).ReceiveAsync(&size) {
+		s.resize(ctx, size)
+	}
+	var request CancelBatchRequest
+	for workflow.GetSignalChannel(ctx, CancelSignalName).ReceiveAsync(&request) {
+		s.cancel(ctx, request)
+	}
+}
+
+func (s *SlidingWindow) pause(ctx workflow.Context) {
+	workflow.GetLogger(ctx).Info("Paused")
+	s.paused = true
+}
+
+func (s *SlidingWindow) resume(ctx workflow.Context) {
+	workflow.GetLogger(ctx).Info("Resumed")
+	s.paused = false
+}
+
+func (s *SlidingWindow) resize(ctx workflow.Context, size int) {
+	if size < 1 {
+		workflow.GetLogger(ctx).Warn("Ignored invalid sliding window size", "SlidingWindowSize", size)
+		return
+	}
+	workflow.GetLogger(ctx).Info("Resized", "SlidingWindowSize", size)
+	s.windowSize = size
+}
+
+func (s *SlidingWindow) cancel(ctx workflow.Context, request CancelBatchRequest) {
+	workflow.GetLogger(ctx).Info("Cancel requested", "CancelInFlight", request.CancelInFlight)
+	s.cancelled = true
+	s.cancelInFlight = s.cancelInFlight || request.CancelInFlight
+}
+
+// cancelRecordsInFlight cancels the record activities started by this run and requests cancellation of the record
+// workflows in flight, including the ones started by previous runs. Cancelled record workflows still report their
+// completion.
+func (s *SlidingWindow) cancelRecordsInFlight(ctx workflow.Context) {
+	s.cancelActivities()
+	if s.input.RecordWorkflow == "" {
+		return
+	}
+	// Children must be started before they can be cancelled.
+	for _, child := range s.childrenStartedByThisRun {
+		_ = child.GetChildWorkflowExecution().Get(ctx, nil)
+	}
+	keys := make([]string, 0, len(s.currentRecords))
+	// Sorted below to keep the order of the cancellation requests deterministic.
+	//workflowcheck:ignore
+	for key := range s.currentRecords {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	workflowId := workflow.GetInfo(ctx).WorkflowExecution.ID
+	var cancellations []workflow.Future
+	for _, key := range keys {
+		cancellations = append(cancellations,
+			workflow.RequestCancelExternalWorkflow(ctx, recordWorkflowID(workflowId, s.currentRecords[key]), ""))
+	}
+	for i, cancellation := range cancellations {
+		// Fails when the child has already completed, its completion signal is on the way then.
+		if err := cancellation.Get(ctx, nil); err != nil {
+			workflow.GetLogger(ctx).Info("Failed to cancel record workflow", "Key", keys[i], "Error", err)
+		}
+	}
+}
+
+// recordWorkflowID is the ID of the workflow that processes an attempt of the record. Retries get their own ID, as
+// the workflow of the failed attempt may still be running after it reported the failure.
+func recordWorkflowID(slidingWindowWorkflowID string, record Record) string {
+	if record.Attempt > 1 {
+		return fmt.Sprintf("%s/%s/%d", slidingWindowWorkflowID, record.Key, record.Attempt)
+	}
+	return fmt.Sprintf("%s/%s", slidingWindowWorkflowID, record.Key)
+}
+
+// forwardControlSignals forwards the control signals received by ProcessBatchWorkflow to all partitions.
+func forwardControlSignals(ctx workflow.Context, partitions []workflow.ChildWorkflowFuture, input ProcessBatchWorkflowInput) {
+	forward := func(signalName string, arg interface{}, argForPartition func(i int) interface{}) {
+		for i, partition := range partitions {
+			var execution workflow.Execution
+			// Partitions must be started before they can be signaled.
+			if err := partition.GetChildWorkflowExecution().Get(ctx, &execution); err != nil {
+				continue
+			}
+			if argForPartition != nil {
+				arg = argForPartition(i)
+			}
+			// Doesn't specify runId as partitions call continue-as-new.
+			err := workflow.SignalExternalWorkflow(ctx, execution.ID, "", signalName, arg).Get(ctx, nil)
+			if err != nil {
+				// The partition has completed already.
+				workflow.GetLogger(ctx).Info("Failed to forward signal", "Signal", signalName, "Partition", i, "Error", err)
+			}
+		}
+	}
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(workflow.GetSignalChannel(ctx, PauseSignalName), func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			forward(PauseSignalName, nil, nil)
+		})
+		selector.AddReceive(workflow.GetSignalChannel(ctx, ResumeSignalName), func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			forward(ResumeSignalName, nil, nil)
+		})
+		selector.AddReceive(workflow.GetSignalChannel(ctx, ResizeSignalName), func(c workflow.ReceiveChannel, more bool) {
+			var size int
+			c.Receive(ctx, &size)
+			if size < input.Partitions {
+				workflow.GetLogger(ctx).Warn("SlidingWindowSize cannot be less than number of partitions",
+					"SlidingWindowSize", size)
+				return
+			}
+			windowSizes := divideIntoPartitions(size, input.Partitions)
+			forward(ResizeSignalName, nil, func(i int) interface{} { return windowSizes[i] })
+		})
+		selector.AddReceive(workflow.GetSignalChannel(ctx, CancelSignalName), func(c workflow.ReceiveChannel, more bool) {
+			var request CancelBatchRequest
+			c.Receive(ctx, &request)
+			forward(CancelSignalName, request, nil)
+		})
+		for {
+			selector.Select(ctx)
+		}
+	})
+}
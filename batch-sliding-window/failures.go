@@ -0,0 +1,160 @@
+package batch_sliding_window
+
+import (
+	"encoding/json"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// FailuresQueryType pages through the records that failed all attempts. ProcessBatchWorkflow returns the failures of
+// the partitions that have completed, SlidingWindowWorkflow the failures of its partition so far.
+// The query takes the offset of the first failure and the page size as arguments and returns a FailuresPage.
+const FailuresQueryType = "failures"
+
+const defaultFailuresPageSize = 100
+
+type (
+	// RecordCompletion is sent with the ReportCompletionSignalName signal.
+	RecordCompletion struct {
+		Key     string
+		Attempt int
+		// Error is the failure message, empty when the record was processed successfully.
+		Error string
+	}
+
+	// RecordFailure is a record that failed all attempts.
+	RecordFailure struct {
+		Partition int
+		Key       string
+		Attempts  int
+		// Error is the failure message of the last attempt.
+		Error string
+		// DeadLettered is set when the record was handed to the dead letter activity.
+		DeadLettered bool
+	}
+
+	// DeadLetterInput is the input of the dead letter activity.
+	DeadLetterInput struct {
+		Record Record
+		Error  string
+	}
+
+	// BatchSummary counts the records by outcome.
+	BatchSummary struct {
+		Succeeded int
+		// Failed counts records that failed all attempts and were not dead-lettered.
+		Failed       int
+		DeadLettered int
+	}
+
+	// FailuresPage is the result of the FailuresQueryType query.
+	FailuresPage struct {
+		Failures []RecordFailure
+		// NextOffset is the offset of the next page, zero when there are no more failures.
+		NextOffset int
+	}
+)
+
+// UnmarshalJSON also accepts the plain record key that record workflows sent before completions reported errors,
+// so that record workflows that are still running can report their completion.
+func (c *RecordCompletion) UnmarshalJSON(data []byte) error {
+	var key string
+	if err := json.Unmarshal(data, &key); err == nil {
+		*c = RecordCompletion{Key: key}
+		return nil
+	}
+	type recordCompletion RecordCompletion
+	return json.Unmarshal(data, (*recordCompletion)(c))
+}
+
+func (s *BatchSummary) add(other BatchSummary) {
+	s.Succeeded += other.Succeeded
+	s.Failed += other.Failed
+	s.DeadLettered += other.DeadLettered
+}
+
+// setFailuresQueryHandler registers the FailuresQueryType query that pages through the failures returned by
+// failures.
+func setFailuresQueryHandler(ctx workflow.Context, failures func() []RecordFailure) error {
+	return workflow.SetQueryHandler(ctx, FailuresQueryType, func(offset, pageSize int) (FailuresPage, error) {
+		all := failures()
+		if pageSize <= 0 {
+			pageSize = defaultFailuresPageSize
+		}
+		if offset < 0 || offset > len(all) {
+			offset = len(all)
+		}
+		end := offset + pageSize
+		if end >= len(all) {
+			return FailuresPage{Failures: all[offset:]}, nil
+		}
+		return FailuresPage{Failures: all[offset:end], NextOffset: end}, nil
+	})
+}
+
+// recordCompletion handles the completion of a record. A failed record is retried until it has used up
+// MaxRecordAttempts, and then handed to the dead letter activity if there is one.
+func (s *SlidingWindow) recordCompletion(ctx workflow.Context, completion RecordCompletion) {
+	record, ok := s.currentRecords[completion.Key]
+	// duplicate signal check, or a late completion of a previous attempt.
+	// Completions without an attempt were sent by record workflows started before attempts were tracked.
+	if !ok || completion.Attempt != 0 && completion.Attempt != record.Attempt {
+		return
+	}
+	delete(s.currentRecords, completion.Key)
+	if completion.Error == "" {
+		s.progress += 1
+		s.summary.Succeeded++
+		return
+	}
+	if record.Attempt < s.input.MaxRecordAttempts && !s.cancelled {
+		workflow.GetLogger(ctx).Info("Retrying record", "Key", record.Key, "Attempt", record.Attempt, "Error", completion.Error)
+		record.Attempt++
+		s.retries = append(s.retries, record)
+		return
+	}
+	s.progress += 1
+	s.recordFailure(ctx, record, completion.Error)
+}
+
+// recordFailure records a record that will not be retried.
+func (s *SlidingWindow) recordFailure(ctx workflow.Context, record Record, errorMessage string) {
+	failure := RecordFailure{
+		Partition: s.input.Partition,
+		Key:       record.Key,
+		Attempts:  record.Attempt,
+		Error:     errorMessage,
+	}
+	workflow.GetLogger(ctx).Error("Record failed", "Key", record.Key, "Attempts", record.Attempt, "Error", errorMessage)
+	if s.input.DeadLetterActivity == "" || s.cancelled {
+		s.summary.Failed++
+		s.failures = append(s.failures, failure)
+		return
+	}
+	s.deadLettersInFlight++
+	// Uses the workflow context, ctx may belong to the signal pump that is stopped before continue-as-new.
+	deadLettered := workflow.ExecuteActivity(s.ctx, s.input.DeadLetterActivity,
+		DeadLetterInput{Record: record, Error: errorMessage})
+	workflow.Go(s.ctx, func(ctx workflow.Context) {
+		if err := deadLettered.Get(ctx, nil); err != nil {
+			workflow.GetLogger(ctx).Error("Dead letter activity failed", "Key", record.Key, "Error", err)
+			s.summary.Failed++
+		} else {
+			failure.DeadLettered = true
+			s.summary.DeadLettered++
+		}
+		s.failures = append(s.failures, failure)
+		s.deadLettersInFlight--
+	})
+}
+
+// failRetries records the records waiting for a retry as failed when the batch was cancelled.
+func (s *SlidingWindow) failRetries(ctx workflow.Context) {
+	for _, record := range s.retries {
+		// The attempt was already counted for the retry.
+		record.Attempt--
+		s.progress += 1
+		s.recordFailure(ctx, record, "batch was cancelled before the record was retried")
+	}
+	s.retries = nil
+}
@@ -0,0 +1,64 @@
+package batch_sliding_window
+
+import (
+	"encoding/json"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// ReportCompletionSignalName is the signal a record workflow sends to its SlidingWindowWorkflow with a
+// RecordCompletion when it is done.
+const ReportCompletionSignalName = "ReportCompletion"
+
+type (
+	// Record is a single record of a batch.
+	Record struct {
+		// Key identifies the record. It must be unique within the batch as it is part of the record workflow ID.
+		Key string
+		// Data is passed to the record workflow or activity as is.
+		Data json.RawMessage `json:",omitempty"`
+		// Attempt is set by SlidingWindowWorkflow, starting with 1. It is increased when the record is retried.
+		Attempt int `json:",omitempty"`
+	}
+
+	// LoadRecordsInput is the input of the loader activity.
+	LoadRecordsInput struct {
+		// Partition is the index of the partition to load records of, out of Partitions.
+		Partition  int
+		Partitions int
+		// Cursor is the NextCursor returned by the previous call, or empty for the first page.
+		Cursor   string
+		PageSize int
+	}
+
+	// LoadRecordsOutput is the result of the loader activity.
+	LoadRecordsOutput struct {
+		// Records contains up to PageSize records.
+		Records []Record
+		// NextCursor is where the next page starts. Empty when the partition has no more records.
+		NextCursor string
+	}
+)
+
+// ReportCompletion notifies the parent SlidingWindowWorkflow that the record workflow is done with the record.
+// Record workflows must call it before they complete, with the error that failed processing of the record if any.
+func ReportCompletion(ctx workflow.Context, record Record, processingErr error) error {
+	parent := workflow.GetInfo(ctx).ParentWorkflowExecution
+	// A record workflow is always expected to have a parent.
+	// But for unit testing it might be useful to skip the notification if there is none.
+	if parent == nil {
+		return nil
+	}
+	// Reports completion also when the record workflow was cancelled.
+	ctx, cancel := workflow.NewDisconnectedContext(ctx)
+	defer cancel()
+	// Doesn't specify runId as parent calls continue-as-new.
+	completion := RecordCompletion{Key: record.Key, Attempt: record.Attempt}
+	if processingErr != nil {
+		completion.Error = processingErr.Error()
+	}
+	signaled := workflow.SignalExternalWorkflow(ctx, parent.ID, "", ReportCompletionSignalName, completion)
+	// Ensure that signal is delivered.
+	// Completing workflow before this Future is ready might lead to the signal loss.
+	return signaled.Get(ctx, nil)
+}
@@ -1,47 +1,41 @@
 package batch_sliding_window
 
-import "fmt"
-
-type (
-	// RecordLoader activities structure.
-	RecordLoader struct {
-		RecordCount int
-	}
-
-	GetRecordsInput struct {
-		PageSize  int
-		Offset    int
-		MaxOffset int
-	}
-
-	SingleRecord struct {
-		Id int
-	}
-
-	GetRecordsOutput struct {
-		Records []SingleRecord
-	}
+import (
+	"fmt"
+	"strconv"
 )
 
-// GetRecordCount activity returns the total record count.
-// Used to partition processing across parallel sliding windows.
-// The sample implementation just returns a fake value passed during worker initialization.
-func (p *RecordLoader) GetRecordCount() (int, error) {
-	return p.RecordCount, nil
+// RecordLoader activities structure.
+type RecordLoader struct {
+	RecordCount int
 }
 
 // GetRecords activity returns records loaded from an external data source. The sample returns fake records.
-func (p *RecordLoader) GetRecords(input GetRecordsInput) (output GetRecordsOutput, err error) {
-	if input.MaxOffset > p.RecordCount {
-		panic(fmt.Sprintf("maxOffset(%d)>recordCount(%d", input.MaxOffset, p.RecordCount))
+// It divides the records into continuous ranges, one per partition, and uses the offset of the next record as the
+// cursor. A real application can choose any other way to divide the records into partitions.
+func (p *RecordLoader) GetRecords(input LoadRecordsInput) (output LoadRecordsOutput, err error) {
+	offset, maxOffset := 0, 0
+	for i, size := range divideIntoPartitions(p.RecordCount, input.Partitions) {
+		if i == input.Partition {
+			maxOffset = offset + size
+			break
+		}
+		offset += size
+	}
+	if input.Cursor != "" {
+		if offset, err = strconv.Atoi(input.Cursor); err != nil {
+			return LoadRecordsOutput{}, fmt.Errorf("invalid cursor %q: %w", input.Cursor, err)
+		}
+	}
+	limit := offset + input.PageSize
+	if limit > maxOffset {
+		limit = maxOffset
 	}
-	var records []SingleRecord
-	limit := input.Offset + input.PageSize
-	if limit > input.MaxOffset {
-		limit = input.MaxOffset
+	for i := offset; i < limit; i++ {
+		output.Records = append(output.Records, Record{Key: strconv.Itoa(i)})
 	}
-	for i := input.Offset; i < limit; i++ {
-		records = append(records, SingleRecord{Id: i})
+	if limit < maxOffset {
+		output.NextCursor = strconv.Itoa(limit)
 	}
-	return GetRecordsOutput{Records: records}, nil
+	return output, nil
 }
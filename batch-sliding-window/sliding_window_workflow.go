@@ -1,8 +1,8 @@
 package batch_sliding_window
 
 import (
-	"fmt"
 	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 	"sort"
 	"time"
@@ -11,67 +11,132 @@ import (
 type (
 	// SlidingWindowWorkflowInput contains SlidingWindowWorkflow arguments
 	SlidingWindowWorkflowInput struct {
+		// LoaderActivity, RecordWorkflow, RecordActivity, ActivityTimeout, MaxRecordAttempts and
+		// DeadLetterActivity are documented in ProcessBatchWorkflowInput.
+		LoaderActivity     string
+		RecordWorkflow     string
+		RecordActivity     string
+		ActivityTimeout    time.Duration
+		MaxRecordAttempts  int
+		DeadLetterActivity string
+		// Partition is the index of the partition out of Partitions that this workflow processes.
+		Partition         int
+		Partitions        int
 		PageSize          int
 		SlidingWindowSize int
-		Offset            int // inclusive
-		MaximumOffset     int // exclusive
-		Progress          int
-		// The set of ids
-		CurrentRecords map[int]bool // recordId -> ignored boolean
+		// Paused is set when the previous run was paused, see PauseSignalName.
+		Paused bool
+		// Cursor is where the next page of records starts. Empty for the first run.
+		Cursor   string
+		Progress int
+		// The records in flight
+		CurrentRecords map[string]Record // record key -> record
+		// Retries are the failed records to process again.
+		Retries  []Record
+		Summary  BatchSummary
+		Failures []RecordFailure
+	}
+
+	// SlidingWindowResult is the result of SlidingWindowWorkflow.
+	SlidingWindowResult struct {
+		Summary  BatchSummary
+		Failures []RecordFailure
 	}
 
 	// SlidingWindow structure that implements the workflow logic
 	SlidingWindow struct {
 		input SlidingWindowWorkflowInput
+		// ctx is the workflow context with the activity options.
+		ctx workflow.Context
+		// activityCtx is used to execute record activities, it is cancelled to cancel them.
+		activityCtx workflow.Context
 		// currentRecords represents a set of records that are currently being processed by child workflows.
-		// key is recordId. values are ignored.
-		currentRecords map[int]bool
+		// key is Record.Key.
+		currentRecords map[string]Record
+		// retries are the failed records waiting to be processed again.
+		retries []Record
+		// summary counts the records processed by all runs.
+		summary BatchSummary
+		// failures are the records that failed all attempts, including the ones of previous runs.
+		failures []RecordFailure
+		// deadLettersInFlight counts the dead letter activities that have not completed.
+		deadLettersInFlight int
 		// childrenStartedByThisRun is used to wait for children to start before calling continue as new.
 		childrenStartedByThisRun []workflow.ChildWorkflowFuture
-		// Offset into the next record to process.
-		offset int
+		// activitiesStartedByThisRun counts the records processed by RecordActivity in this run.
+		activitiesStartedByThisRun int
+		// cursor is where the page after the one processed by this run starts.
+		cursor string
 		// Count of completed records.
 		progress int
-		// completionSignalPumpCancellationHandler is used to request pump completion
-		completionSignalPumpCancellationHandler workflow.CancelFunc
-		// completionSignalPumpCompletion is used to wait for the pump completion.
-		completionSignalPumpCompletion workflow.Future
+		// windowSize is the current sliding window size, see ResizeSignalName.
+		windowSize int
+		// paused stops starting new records, see PauseSignalName.
+		paused bool
+		// cancelled stops starting new records and completes the workflow, see CancelSignalName.
+		cancelled bool
+		// cancelInFlight requests cancellation of the records in flight, see CancelBatchRequest.
+		cancelInFlight bool
+		// inFlightCancelled is set once the records in flight were cancelled.
+		inFlightCancelled bool
+		// cancelActivities cancels the record activities started by this run.
+		cancelActivities workflow.CancelFunc
+		// signalPumpCancellationHandler is used to request pump completion
+		signalPumpCancellationHandler workflow.CancelFunc
+		// signalPumpCompletion is used to wait for the pump completion.
+		signalPumpCompletion workflow.Future
 	}
 
 	// SlidingWindowState used as a "state" query result.
 	SlidingWindowState struct {
-		// currentRecords represents a set of record ids that are currently being processed by child workflows.
-		CurrentRecords           []int
+		// currentRecords represents a set of record keys that are currently being processed.
+		CurrentRecords           []string
 		ChildrenStartedByThisRun int
-		Offset                   int
+		Cursor                   string
 		Progress                 int
+		Summary                  BatchSummary
+		Retries                  int
+		SlidingWindowSize        int
+		Paused                   bool
+		Cancelled                bool
 	}
 )
 
-// SlidingWindowWorkflow workflow processes a range of records using a requested number of child workflows.
-// As soon as a child workflow completes a new one is started.
-func SlidingWindowWorkflow(ctx workflow.Context, input SlidingWindowWorkflowInput) (recordCount int, err error) {
+// SlidingWindowWorkflow workflow processes the records of a partition using a requested number of child workflows
+// or activities. As soon as a record completes processing of a new one is started.
+func SlidingWindowWorkflow(ctx workflow.Context, input SlidingWindowWorkflowInput) (result SlidingWindowResult, err error) {
 	workflow.GetLogger(ctx).Info("SlidingWindowWorkflow",
 		"input", input.SlidingWindowSize,
 		"PageSize", input.PageSize,
-		"Offset", input.Offset,
-		"MaximumOffset", input.MaximumOffset,
+		"Partition", input.Partition,
+		"Cursor", input.Cursor,
 		"Progress", input.Progress)
 
 	impl := &SlidingWindow{
 		input:          input,
 		currentRecords: input.CurrentRecords,
-		offset:         input.Offset,
+		cursor:         input.Cursor,
 		progress:       input.Progress,
+		windowSize:     input.SlidingWindowSize,
+		paused:         input.Paused,
+		retries:        input.Retries,
+		summary:        input.Summary,
+		failures:       input.Failures,
 	}
 	if impl.currentRecords == nil {
-		impl.currentRecords = make(map[int]bool)
+		impl.currentRecords = make(map[string]Record)
 	}
 	err = workflow.SetQueryHandler(ctx, "state", func() (SlidingWindowState, error) {
 		return impl.State()
 	})
 	if err != nil {
-		return 0, err
+		return SlidingWindowResult{}, err
+	}
+	err = setFailuresQueryHandler(ctx, func() []RecordFailure {
+		return impl.failures
+	})
+	if err != nil {
+		return SlidingWindowResult{}, err
 	}
 	return impl.Execute(ctx)
 }
@@ -79,76 +144,135 @@ func SlidingWindowWorkflow(ctx workflow.Context, input SlidingWindowWorkflowInpu
 // State returns the current state of the batch.
 // Used by the "state" workflow query.
 func (s *SlidingWindow) State() (SlidingWindowState, error) {
-	currentRecordIds := make([]int, len(s.currentRecords))
+	currentRecordKeys := make([]string, len(s.currentRecords))
 	i := 0
 	// Range over map is a nondeterministic operation.
 	// It is OK to have a non-deterministic operation in a query function.
 	// Sorting of results makes the result deterministic anyway.
 	//workflowcheck:ignore
 	for k := range s.currentRecords {
-		currentRecordIds[i] = k
+		currentRecordKeys[i] = k
 		i++
 	}
-	sort.Ints(currentRecordIds)
+	sort.Strings(currentRecordKeys)
 	return SlidingWindowState{
-		CurrentRecords:           currentRecordIds,
+		CurrentRecords:           currentRecordKeys,
 		ChildrenStartedByThisRun: len(s.childrenStartedByThisRun),
-		Offset:                   s.offset,
+		Cursor:                   s.cursor,
 		Progress:                 s.progress,
+		Summary:                  s.summary,
+		Retries:                  len(s.retries),
+		SlidingWindowSize:        s.windowSize,
+		Paused:                   s.paused,
+		Cancelled:                s.cancelled,
 	}, nil
 }
 
-func (s *SlidingWindow) Execute(ctx workflow.Context) (recordCount int, err error) {
+func (s *SlidingWindow) Execute(ctx workflow.Context) (result SlidingWindowResult, err error) {
+	activityTimeout := s.input.ActivityTimeout
+	if activityTimeout == 0 {
+		activityTimeout = 5 * time.Second
+	}
 	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-		StartToCloseTimeout: 5 * time.Second,
+		StartToCloseTimeout: activityTimeout,
 	})
+	s.ctx = ctx
+	activityCtx, cancelActivities := workflow.WithCancel(ctx)
+	// Failed records are retried by the workflow up to MaxRecordAttempts.
+	s.activityCtx = workflow.WithRetryPolicy(activityCtx, temporal.RetryPolicy{MaximumAttempts: 1})
+	s.cancelActivities = cancelActivities
 
-	// Starts processing child workflow completion signals asynchronously
-	s.completionSignalPump(ctx)
+	// Starts processing child workflow completion and control signals asynchronously
+	s.signalPump(ctx)
 
-	var getOutput GetRecordsOutput
-	if s.offset < s.input.MaximumOffset {
-		getInput := &GetRecordsInput{
-			PageSize:  s.input.PageSize,
-			Offset:    s.offset,
-			MaxOffset: s.input.MaximumOffset,
-		}
-		var loader *RecordLoader
-		err = workflow.ExecuteActivity(ctx, loader.GetRecords, getInput).Get(ctx, &getOutput)
-		if err != nil {
-			return 0, err
-		}
+	// Every run loads a page: only the first run starts with an empty cursor,
+	// and continue-as-new is only called while the partition has records left.
+	getInput := LoadRecordsInput{
+		Partition:  s.input.Partition,
+		Partitions: s.input.Partitions,
+		Cursor:     s.cursor,
+		PageSize:   s.input.PageSize,
+	}
+	var getOutput LoadRecordsOutput
+	err = workflow.ExecuteActivity(ctx, s.input.LoaderActivity, getInput).Get(ctx, &getOutput)
+	if err != nil {
+		return SlidingWindowResult{}, err
+	}
+	s.cursor = getOutput.NextCursor
+	// Retries carried over from the previous run go first.
+	records := append(s.retries, getOutput.Records...)
+	s.retries = nil
+	if err := s.process(ctx, records); err != nil {
+		return SlidingWindowResult{}, err
 	}
+	return s.continueAsNewOrComplete(ctx)
+}
+
+// process starts processing of the records, blocking while the sliding window is full or the batch is paused.
+func (s *SlidingWindow) process(ctx workflow.Context, records []Record) error {
 	workflowId := workflow.GetInfo(ctx).WorkflowExecution.ID
-	// Process records
-	for _, record := range getOutput.Records {
+	for _, record := range records {
 		// Blocks until the total number of children (including started by the previous runs)
-		// gets below the SlidingWindowSize.
+		// gets below the sliding window size and the batch is not paused.
 		err := workflow.Await(ctx, func() bool {
-			return len(s.currentRecords) < s.input.SlidingWindowSize
+			return s.cancelled || !s.paused && len(s.currentRecords) < s.windowSize
 		})
 		if err != nil {
-			return 0, err
+			return err
+		}
+		if s.cancelled {
+			// Records waiting for a retry are recorded as failed, the others were just not processed.
+			if record.Attempt > 0 {
+				s.retries = append(s.retries, record)
+			}
+			continue
 		}
 
+		if record.Attempt == 0 {
+			record.Attempt = 1
+		}
+		s.currentRecords[record.Key] = record
+		if s.input.RecordActivity != "" {
+			s.processWithActivity(record)
+			continue
+		}
 		options := workflow.ChildWorkflowOptions{
 			// Use ABANDON as child workflows have to survive the parent calling continue-as-new
 			ParentClosePolicy: enumspb.PARENT_CLOSE_POLICY_ABANDON,
 			// Human readable child id.
-			WorkflowID: fmt.Sprintf("%s/%d", workflowId, record.Id),
+			WorkflowID: recordWorkflowID(workflowId, record),
 		}
 		childCtx := workflow.WithChildOptions(ctx, options)
-		child := workflow.ExecuteChildWorkflow(childCtx, RecordProcessorWorkflow, record)
+		child := workflow.ExecuteChildWorkflow(childCtx, s.input.RecordWorkflow, record)
 
 		s.childrenStartedByThisRun = append(s.childrenStartedByThisRun, child)
-		s.currentRecords[record.Id] = true // value is ignored
+		// Children report their completion through a signal, so that children started by a previous run can
+		// notify the current one. Also waits for the children of this run to cover the ones that closed without
+		// reporting, for example because they were cancelled before they started.
+		completion := RecordCompletion{Key: record.Key, Attempt: record.Attempt}
+		workflow.Go(ctx, func(ctx workflow.Context) {
+			if err := child.Get(ctx, nil); err != nil {
+				completion.Error = err.Error()
+			}
+			s.recordCompletion(ctx, completion)
+		})
 	}
-	return s.continueAsNewOrComplete(ctx)
+	return nil
 }
 
-func (s *SlidingWindow) continueAsNewOrComplete(ctx workflow.Context) (int, error) {
+func (s *SlidingWindow) continueAsNewOrComplete(ctx workflow.Context) (SlidingWindowResult, error) {
 	// Continues-as-new after starting PageSize children
-	if s.offset < s.input.MaximumOffset {
+	if s.cursor != "" && !s.cancelled {
+		// Activities cannot outlive the run that started them, so waits for them to complete.
+		if s.activitiesStartedByThisRun > 0 {
+			if err := s.awaitRecordsInFlight(ctx); err != nil {
+				return SlidingWindowResult{}, err
+			}
+		}
+		// Dead letter activities cannot outlive the run either.
+		if err := workflow.Await(ctx, func() bool { return s.deadLettersInFlight == 0 }); err != nil {
+			return SlidingWindowResult{}, err
+		}
 		// Waits for all children to start. Without this wait, workflow completion through
 		// continue-as-new might lead to a situation when they never start.
 		for _, child := range s.childrenStartedByThisRun {
@@ -156,74 +280,111 @@ func (s *SlidingWindow) continueAsNewOrComplete(ctx workflow.Context) (int, erro
 			// Is not expected as children's automatically generated
 			// IDs are not expected to collide.
 			if err != nil {
-				return 0, err
+				return SlidingWindowResult{}, err
 			}
 		}
 		// Must drain the signal channel without blocking before calling continue-as-new.
 		// Failure to do so can lead to signal loss.
-		s.drainCompletionSignalChannelAsync(ctx)
-
-		// Returns ContinueAsNewError with new workflow input
-		newInput := SlidingWindowWorkflowInput{
-			PageSize:          s.input.PageSize,
-			SlidingWindowSize: s.input.SlidingWindowSize,
-			Offset:            s.input.Offset + len(s.childrenStartedByThisRun),
-			MaximumOffset:     s.input.MaximumOffset,
-			Progress:          s.progress,
-			CurrentRecords:    s.currentRecords,
-		}
-		return 0, workflow.NewContinueAsNewError(ctx, SlidingWindowWorkflow, newInput)
-	}
-	// The last run in the continue-as-new chain.
-	// Awaits for all children to complete
-	err := workflow.Await(ctx, func() bool {
-		return len(s.currentRecords) == 0
-	})
-	if err != nil {
-		return 0, err
+		s.drainSignalChannelsAsync(ctx)
+
+		if !s.cancelled {
+			// Returns ContinueAsNewError with new workflow input
+			newInput := s.input
+			newInput.SlidingWindowSize = s.windowSize
+			newInput.Paused = s.paused
+			newInput.Cursor = s.cursor
+			newInput.Progress = s.progress
+			newInput.CurrentRecords = s.currentRecords
+			newInput.Retries = s.retries
+			newInput.Summary = s.summary
+			newInput.Failures = s.failures
+			return SlidingWindowResult{}, workflow.NewContinueAsNewError(ctx, SlidingWindowWorkflow, newInput)
+		}
+		// A drained signal cancelled the batch, keeps processing completion signals until the records in flight
+		// are done.
+		s.signalPump(ctx)
+	}
+	// The last run in the continue-as-new chain, or the batch was cancelled.
+	// Awaits for all children to complete, and retries the failed ones.
+	for {
+		if err := s.awaitRecordsInFlight(ctx); err != nil {
+			return SlidingWindowResult{}, err
+		}
+		if len(s.retries) == 0 || s.cancelled {
+			break
+		}
+		retries := s.retries
+		s.retries = nil
+		if err := s.process(ctx, retries); err != nil {
+			return SlidingWindowResult{}, err
+		}
+	}
+	s.failRetries(ctx)
+	if err := workflow.Await(ctx, func() bool { return s.deadLettersInFlight == 0 }); err != nil {
+		return SlidingWindowResult{}, err
+	}
+	return SlidingWindowResult{Summary: s.summary, Failures: s.failures}, nil
+}
+
+// awaitRecordsInFlight blocks until no records are in flight. It cancels them when requested while waiting.
+func (s *SlidingWindow) awaitRecordsInFlight(ctx workflow.Context) error {
+	for {
+		err := workflow.Await(ctx, func() bool {
+			return len(s.currentRecords) == 0 || s.cancelInFlight && !s.inFlightCancelled
+		})
+		if err != nil {
+			return err
+		}
+		if len(s.currentRecords) == 0 {
+			return nil
+		}
+		s.inFlightCancelled = true
+		s.cancelRecordsInFlight(ctx)
 	}
-	return s.progress, nil
 }
 
-func (s *SlidingWindow) drainCompletionSignalChannelAsync(ctx workflow.Context) {
+func (s *SlidingWindow) drainSignalChannelsAsync(ctx workflow.Context) {
 	// Request pump completion
-	s.completionSignalPumpCancellationHandler()
+	s.signalPumpCancellationHandler()
 	// Wait for the pump to complete to avoid signal loss.
-	_ = s.completionSignalPumpCompletion.Get(ctx, nil)
+	_ = s.signalPumpCompletion.Get(ctx, nil)
+
+	s.drainControlSignalsAsync(ctx)
 
-	reportCompletionChannel := workflow.GetSignalChannel(ctx, "ReportCompletion")
+	reportCompletionChannel := workflow.GetSignalChannel(ctx, ReportCompletionSignalName)
 	// Drains signals async
 	for {
-		var recordId int
-		ok := reportCompletionChannel.ReceiveAsync(&recordId)
+		var completion RecordCompletion
+		ok := reportCompletionChannel.ReceiveAsync(&completion)
 		if !ok {
 			break
 		}
-		s.recordCompletion(ctx, recordId)
+		s.recordCompletion(ctx, completion)
 	}
 }
 
-// completionSignalPump asynchronously processes ReportCompletion signals.
+// signalPump asynchronously processes ReportCompletion and control signals.
 // There is no need to clean up the pump goroutine in case a workflow completes due to an error.
 // All goroutines are released back automatically upon workflow completion.
-func (s *SlidingWindow) completionSignalPump(ctx workflow.Context) {
-	// completionSignalPumpCancellationHandler is used to request pump completion
+func (s *SlidingWindow) signalPump(ctx workflow.Context) {
+	// signalPumpCancellationHandler is used to request pump completion
 	ctx, cancellationHandler := workflow.WithCancel(ctx)
-	s.completionSignalPumpCancellationHandler = cancellationHandler
+	s.signalPumpCancellationHandler = cancellationHandler
 
-	// completionSignalPumpCompletion is used to wait for the pump completion.
+	// signalPumpCompletion is used to wait for the pump completion.
 	completed, completedSettable := workflow.NewFuture(ctx)
-	s.completionSignalPumpCompletion = completed
+	s.signalPumpCompletion = completed
 
-	reportCompletionChannel := workflow.GetSignalChannel(ctx, "ReportCompletion")
+	reportCompletionChannel := workflow.GetSignalChannel(ctx, ReportCompletionSignalName)
 
 	workflow.Go(ctx, func(ctx workflow.Context) {
 		selector := workflow.NewSelector(ctx)
 		selector.AddReceive(reportCompletionChannel, func(c workflow.ReceiveChannel, more bool) {
-			var recordId int
-			_ = reportCompletionChannel.Receive(ctx, &recordId)
-			s.recordCompletion(ctx, recordId)
+			var completion RecordCompletion
+			_ = reportCompletionChannel.Receive(ctx, &completion)
+			s.recordCompletion(ctx, completion)
 		})
+		s.addControlReceivers(ctx, selector)
 		selector.AddReceive(ctx.Done(), func(c workflow.ReceiveChannel, more bool) {
 			completedSettable.Set(nil, nil)
 		})
@@ -233,10 +394,15 @@ func (s *SlidingWindow) completionSignalPump(ctx workflow.Context) {
 	})
 }
 
-func (s *SlidingWindow) recordCompletion(ctx workflow.Context, recordId int) {
-	// duplicate signal check
-	if _, ok := s.currentRecords[recordId]; ok {
-		delete(s.currentRecords, recordId)
-		s.progress += 1
-	}
+// processWithActivity processes a record with RecordActivity and records its completion when the activity is done.
+func (s *SlidingWindow) processWithActivity(record Record) {
+	s.activitiesStartedByThisRun++
+	processed := workflow.ExecuteActivity(s.activityCtx, s.input.RecordActivity, record)
+	workflow.Go(s.ctx, func(ctx workflow.Context) {
+		completion := RecordCompletion{Key: record.Key, Attempt: record.Attempt}
+		if err := processed.Get(ctx, nil); err != nil {
+			completion.Error = err.Error()
+		}
+		s.recordCompletion(ctx, completion)
+	})
 }
@@ -24,6 +24,8 @@ func main() {
 		PageSize:          5,
 		SlidingWindowSize: 10,
 		Partitions:        3,
+		LoaderActivity:    "GetRecords",
+		RecordWorkflow:    "RecordProcessorWorkflow",
 	}
 	we, err := c.ExecuteWorkflow(ctx, workflowOptions, batch_sliding_window.ProcessBatchWorkflow, input)
 	if err != nil {
@@ -33,7 +35,7 @@ func main() {
 
 	// Wait for Workflow Execution completion.
 	// This is rarely needed in real use cases as batch workflows are usually long-running.
-	var result int
+	var result batch_sliding_window.BatchSummary
 	err = we.Get(ctx, &result)
 	if err != nil {
 		panic(err)
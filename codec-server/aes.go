@@ -0,0 +1,109 @@
+package codecserver
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	// MetadataEncodingEncrypted is "binary/encrypted"
+	MetadataEncodingEncrypted = "binary/encrypted"
+
+	// MetadataEncryptionKeyID is "encryption-key-id"
+	MetadataEncryptionKeyID = "encryption-key-id"
+)
+
+// AESCodec implements converter.PayloadCodec with AES-GCM. It writes the same format as the encryption sample, so
+// the codec server can decode that sample's payloads too.
+type AESCodec struct {
+	activeKeyID string
+	keys        map[string][]byte
+}
+
+// NewAESCodec creates an AESCodec that encrypts with the key activeKeyID and decrypts with any of keys.
+func NewAESCodec(activeKeyID string, keys map[string][]byte) (*AESCodec, error) {
+	for keyID, key := range keys {
+		if l := len(key); l != 16 && l != 24 && l != 32 {
+			return nil, fmt.Errorf("key %s is %d bytes, AES needs 16, 24 or 32", keyID, l)
+		}
+	}
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active key %q is not configured", activeKeyID)
+	}
+	return &AESCodec{activeKeyID: activeKeyID, keys: keys}, nil
+}
+
+// Encode implements converter.PayloadCodec.Encode.
+func (e *AESCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	gcm, err := newGCM(e.keys[e.activeKeyID])
+	if err != nil {
+		return payloads, err
+	}
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		origBytes, err := p.Marshal()
+		if err != nil {
+			return payloads, err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return payloads, err
+		}
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				converter.MetadataEncoding: []byte(MetadataEncodingEncrypted),
+				MetadataEncryptionKeyID:    []byte(e.activeKeyID),
+			},
+			Data: gcm.Seal(nonce, nonce, origBytes, nil),
+		}
+	}
+	return result, nil
+}
+
+// Decode implements converter.PayloadCodec.Decode.
+func (e *AESCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		// Only if it's encrypted
+		if string(p.Metadata[converter.MetadataEncoding]) != MetadataEncodingEncrypted {
+			result[i] = p
+			continue
+		}
+		keyID := string(p.Metadata[MetadataEncryptionKeyID])
+		key, ok := e.keys[keyID]
+		if !ok {
+			return payloads, fmt.Errorf("unknown encryption key id %q", keyID)
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return payloads, err
+		}
+		if len(p.Data) < gcm.NonceSize() {
+			return payloads, fmt.Errorf("ciphertext too short")
+		}
+		nonce, data := p.Data[:gcm.NonceSize()], p.Data[gcm.NonceSize():]
+		b, err := gcm.Open(nil, nonce, data, nil)
+		if err != nil {
+			return payloads, err
+		}
+		result[i] = &commonpb.Payload{}
+		if err := result[i].Unmarshal(b); err != nil {
+			return payloads, err
+		}
+	}
+	return result, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
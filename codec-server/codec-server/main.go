@@ -59,11 +59,16 @@ func newPayloadCodecNamespacesHTTPHandler(encoders map[string][]converter.Payloa
 		if provider != nil {
 			handler = newPayloadEncoderOauthHTTPHandler(provider, namespace, handler)
 		}
+		handler = newMetricsHTTPHandler(namespace, handler)
 		mux.Handle("/"+namespace+"/", handler)
 
 		codecHandlers[namespace] = handler
 	}
 
+	// Health checks and metrics are not namespaced and do not require oauth.
+	mux.Handle("/health", newHealthHandler())
+	mux.Handle("/metrics", newMetricsHandler())
+
 	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		namespace := r.Header.Get("X-Namespace")
 		if namespace != "" {
@@ -79,6 +84,7 @@ func newPayloadCodecNamespacesHTTPHandler(encoders map[string][]converter.Payloa
 }
 
 var portFlag int
+var configFlag string
 var providerFlag string
 var audienceFlag string
 var webFlag string
@@ -89,6 +95,7 @@ func init() {
 	logger = log.NewCLILogger()
 
 	flag.IntVar(&portFlag, "port", 8081, "Port to listen on")
+	flag.StringVar(&configFlag, "config", "", "Codec config file mapping namespaces to codec chains. Optional: defaults to snappy for the default namespace")
 	flag.StringVar(&providerFlag, "provider", "", "OIDC Provider URL. Optional: Enforces oauth authentication")
 	flag.StringVar(&audienceFlag, "audience", "", "OIDC Audience. Optional")
 	flag.StringVar(&webFlag, "web", "", "Temporal Web URL. Optional: enables CORS which is required for access from Temporal Web")
@@ -97,11 +104,19 @@ func init() {
 func main() {
 	flag.Parse()
 
-	// Set codecs per namespace here.
-	// Only handle codecs for the default namespace in this example.
+	// Without a config file only handle codecs for the default namespace.
 	codecs := map[string][]converter.PayloadCodec{
 		"default": {codecserver.NewPayloadCodec()},
 	}
+	if configFlag != "" {
+		config, err := codecserver.LoadConfig(configFlag)
+		if err != nil {
+			logger.Fatal("failed to load codec config", tag.Error(err))
+		}
+		if codecs, err = config.Codecs(); err != nil {
+			logger.Fatal("failed to create codecs", tag.Error(err))
+		}
+	}
 
 	if providerFlag != "" {
 		p, err := newProvider(providerFlag)
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "codec_server_requests_total",
+		Help: "Number of /encode and /decode requests by namespace, operation and HTTP status code.",
+	}, []string{"namespace", "operation", "code"})
+
+	requestLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "codec_server_request_duration_seconds",
+		Help:    "Latency of /encode and /decode requests by namespace and operation.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"namespace", "operation"})
+
+	metricsRegistry = prometheus.NewRegistry()
+)
+
+func init() {
+	metricsRegistry.MustRegister(requestsTotal, requestLatency)
+}
+
+// statusRecorder remembers the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// newMetricsHTTPHandler wraps a codec HTTP handler with request counters and latency histograms.
+func newMetricsHTTPHandler(namespace string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operation := "unknown"
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/encode"):
+			operation = "encode"
+		case strings.HasSuffix(r.URL.Path, "/decode"):
+			operation = "decode"
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		requestLatency.WithLabelValues(namespace, operation).Observe(time.Since(start).Seconds())
+		requestsTotal.WithLabelValues(namespace, operation, strconv.Itoa(recorder.code)).Inc()
+	})
+}
+
+// newMetricsHandler serves the metrics in the Prometheus text format.
+func newMetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// newHealthHandler always answers 200 OK so that load balancers can check that the server is up.
+func newHealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	codecserver "github.com/temporalio/samples-go/codec-server"
+	"go.temporal.io/sdk/converter"
+)
+
+func TestMetricsHTTPHandler(t *testing.T) {
+	handler := newPayloadCodecNamespacesHTTPHandler(map[string][]converter.PayloadCodec{
+		"metrics-test": {codecserver.NewPayloadCodec()},
+	}, nil)
+	post := func(path, body string) int {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, post("/metrics-test/encode", `{"payloads":[]}`))
+	require.Equal(t, http.StatusOK, post("/metrics-test/decode", `{"payloads":[]}`))
+	require.Equal(t, http.StatusBadRequest, post("/metrics-test/decode", `not json`))
+
+	require.Equal(t, 1.0, testutil.ToFloat64(requestsTotal.WithLabelValues("metrics-test", "encode", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(requestsTotal.WithLabelValues("metrics-test", "decode", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(requestsTotal.WithLabelValues("metrics-test", "decode", "400")))
+	require.Equal(t, 2, testutil.CollectAndCount(requestLatency))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `codec_server_requests_total{code="200",namespace="metrics-test",operation="encode"} 1`)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+}
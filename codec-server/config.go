@@ -0,0 +1,119 @@
+package codecserver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.temporal.io/sdk/converter"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec types supported in a Config.
+const (
+	CodecTypeSnappy = "snappy"
+	CodecTypeZlib   = "zlib"
+	CodecTypeAES    = "aes"
+	CodecTypeRemote = "remote"
+)
+
+type (
+	// Config maps every namespace the codec server handles to its codec chain. The codecs of a chain are listed in
+	// the same order as they are passed to converter.NewCodecDataConverter, so encoding applies the last codec first
+	// and decoding applies the first codec first. See codec-config.yaml for an example.
+	Config struct {
+		Namespaces map[string][]CodecConfig `yaml:"namespaces"`
+	}
+
+	// CodecConfig configures a single codec of a chain.
+	CodecConfig struct {
+		// Type is one of snappy, zlib, aes or remote.
+		Type string `yaml:"type"`
+
+		// ActiveKeyID is the key aes encodes with. Keys maps key IDs to files holding base64 encoded AES keys,
+		// relative to the directory of the config file; decoding picks the key by the encryption-key-id metadata of
+		// the payload.
+		ActiveKeyID string            `yaml:"activeKeyId"`
+		Keys        map[string]string `yaml:"keys"`
+
+		// Endpoint is the URL of the codec server a remote codec forwards to.
+		Endpoint string `yaml:"endpoint"`
+	}
+)
+
+// LoadConfig reads a codec server config file. Relative key files are resolved against the directory of the file,
+// so the config works from any working directory.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(config.Namespaces) == 0 {
+		return nil, fmt.Errorf("%s configures no namespaces", path)
+	}
+	dir := filepath.Dir(path)
+	for _, chain := range config.Namespaces {
+		for _, codecConfig := range chain {
+			for keyID, keyPath := range codecConfig.Keys {
+				if !filepath.IsAbs(keyPath) {
+					codecConfig.Keys[keyID] = filepath.Join(dir, keyPath)
+				}
+			}
+		}
+	}
+	return &config, nil
+}
+
+// Codecs builds the codec chain of every namespace.
+func (c *Config) Codecs() (map[string][]converter.PayloadCodec, error) {
+	codecs := make(map[string][]converter.PayloadCodec, len(c.Namespaces))
+	for namespace, chain := range c.Namespaces {
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("namespace %s has no codecs", namespace)
+		}
+		for i, codecConfig := range chain {
+			codec, err := codecConfig.newCodec()
+			if err != nil {
+				return nil, fmt.Errorf("namespace %s codec %d: %w", namespace, i, err)
+			}
+			codecs[namespace] = append(codecs[namespace], codec)
+		}
+	}
+	return codecs, nil
+}
+
+func (c CodecConfig) newCodec() (converter.PayloadCodec, error) {
+	switch c.Type {
+	case CodecTypeSnappy:
+		return NewPayloadCodec(), nil
+	case CodecTypeZlib:
+		return converter.NewZlibCodec(converter.ZlibCodecOptions{AlwaysEncode: true}), nil
+	case CodecTypeAES:
+		keys := make(map[string][]byte, len(c.Keys))
+		for keyID, path := range c.Keys {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+			if err != nil {
+				return nil, fmt.Errorf("key %s in %s is not base64: %w", keyID, path, err)
+			}
+			keys[keyID] = key
+		}
+		return NewAESCodec(c.ActiveKeyID, keys)
+	case CodecTypeRemote:
+		if c.Endpoint == "" {
+			return nil, fmt.Errorf("remote codec has no endpoint")
+		}
+		return converter.NewRemotePayloadCodec(converter.RemotePayloadCodecOptions{Endpoint: c.Endpoint}), nil
+	default:
+		return nil, fmt.Errorf("unknown codec type %q", c.Type)
+	}
+}
@@ -0,0 +1,106 @@
+package codecserver
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+)
+
+func TestConfig_Codecs(t *testing.T) {
+	config, err := LoadConfig("codec-config.yaml")
+	require.NoError(t, err)
+	codecs, err := config.Codecs()
+	require.NoError(t, err)
+	require.Len(t, codecs, 3)
+
+	require.Len(t, codecs["default"], 1)
+	require.IsType(t, &Codec{}, codecs["default"][0])
+	require.Len(t, codecs["encrypted"], 2)
+	require.IsType(t, &AESCodec{}, codecs["encrypted"][0])
+	require.Len(t, codecs["legacy"], 1)
+
+	for _, namespace := range []string{"default", "encrypted"} {
+		dc := converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), codecs[namespace]...)
+		payloads, err := dc.ToPayloads("Testing")
+		require.NoError(t, err)
+		var result string
+		require.NoError(t, dc.FromPayloads(payloads, &result))
+		require.Equal(t, "Testing", result)
+	}
+
+	// The aes codec is applied last when encoding, so the payload is encrypted with the active key.
+	dc := converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), codecs["encrypted"]...)
+	payload, err := dc.ToPayload("Testing")
+	require.NoError(t, err)
+	require.Equal(t, MetadataEncodingEncrypted, string(payload.Metadata[converter.MetadataEncoding]))
+	require.Equal(t, "test", string(payload.Metadata[MetadataEncryptionKeyID]))
+}
+
+func TestLoadConfig_KeyPathsRelativeToConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "keys"), 0o700))
+	key, err := os.ReadFile(filepath.Join("keys", "test.b64"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "keys", "test.b64"), key, 0o600))
+	configPath := filepath.Join(dir, "codec-config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+namespaces:
+  encrypted:
+    - type: aes
+      activeKeyId: test
+      keys:
+        test: keys/test.b64
+`), 0o600))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "keys", "test.b64"), config.Namespaces["encrypted"][0].Keys["test"])
+	_, err = config.Codecs()
+	require.NoError(t, err)
+}
+
+func TestConfig_RemoteCodec(t *testing.T) {
+	server := httptest.NewServer(converter.NewPayloadCodecHTTPHandler(NewPayloadCodec()))
+	defer server.Close()
+	config := &Config{Namespaces: map[string][]CodecConfig{
+		"legacy": {{Type: CodecTypeRemote, Endpoint: server.URL}},
+	}}
+	codecs, err := config.Codecs()
+	require.NoError(t, err)
+
+	remoteDc := converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), codecs["legacy"]...)
+	payloads, err := remoteDc.ToPayloads("Testing")
+	require.NoError(t, err)
+	var result string
+	require.NoError(t, remoteDc.FromPayloads(payloads, &result))
+	require.Equal(t, "Testing", result)
+
+	// The remote codec server encoded with snappy.
+	localDc := converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), NewPayloadCodec())
+	require.NoError(t, localDc.FromPayloads(payloads, &result))
+	require.Equal(t, "Testing", result)
+}
+
+func TestConfig_CodecsErrors(t *testing.T) {
+	tests := map[string]struct {
+		chain []CodecConfig
+		err   string
+	}{
+		"no codecs":       {chain: nil, err: "namespace ns has no codecs"},
+		"unknown type":    {chain: []CodecConfig{{Type: "rot13"}}, err: `namespace ns codec 0: unknown codec type "rot13"`},
+		"no endpoint":     {chain: []CodecConfig{{Type: CodecTypeSnappy}, {Type: CodecTypeRemote}}, err: "namespace ns codec 1: remote codec has no endpoint"},
+		"no active key":   {chain: []CodecConfig{{Type: CodecTypeAES, ActiveKeyID: "test"}}, err: `active key "test" is not configured`},
+		"missing keyfile": {chain: []CodecConfig{{Type: CodecTypeAES, ActiveKeyID: "test", Keys: map[string]string{"test": "keys/missing.b64"}}}, err: "missing.b64"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := &Config{Namespaces: map[string][]CodecConfig{"ns": test.chain}}
+			_, err := config.Codecs()
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
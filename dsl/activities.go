@@ -5,37 +5,86 @@ import (
 	"fmt"
 
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
 )
 
-type SampleActivities struct {
+type (
+	SampleActivities struct {
+	}
+
+	// Order is returned by GetOrder to show that DSL variables can hold JSON objects.
+	Order struct {
+		ID    string      `json:"id"`
+		Items []OrderItem `json:"items"`
+	}
+
+	// OrderItem is a single line of an Order.
+	OrderItem struct {
+		ID       string `json:"id"`
+		Quantity int    `json:"quantity"`
+	}
+)
+
+// The sample activities accept up to two positional arguments of any JSON type.
+
+func (a *SampleActivities) SampleActivity1(ctx context.Context, input1, input2 interface{}) (string, error) {
+	return runSampleActivity(ctx, input1, input2)
 }
 
-func (a *SampleActivities) SampleActivity1(ctx context.Context, input []string) (string, error) {
-	name := activity.GetInfo(ctx).ActivityType.Name
-	fmt.Printf("Run %s with input %v \n", name, input)
-	return "Result_" + name, nil
+func (a *SampleActivities) SampleActivity2(ctx context.Context, input1, input2 interface{}) (string, error) {
+	return runSampleActivity(ctx, input1, input2)
 }
 
-func (a *SampleActivities) SampleActivity2(ctx context.Context, input []string) (string, error) {
-	name := activity.GetInfo(ctx).ActivityType.Name
-	fmt.Printf("Run %s with input %v \n", name, input)
-	return "Result_" + name, nil
+func (a *SampleActivities) SampleActivity3(ctx context.Context, input1, input2 interface{}) (string, error) {
+	return runSampleActivity(ctx, input1, input2)
 }
 
-func (a *SampleActivities) SampleActivity3(ctx context.Context, input []string) (string, error) {
-	name := activity.GetInfo(ctx).ActivityType.Name
-	fmt.Printf("Run %s with input %v \n", name, input)
-	return "Result_" + name, nil
+func (a *SampleActivities) SampleActivity4(ctx context.Context, input1, input2 interface{}) (string, error) {
+	return runSampleActivity(ctx, input1, input2)
 }
 
-func (a *SampleActivities) SampleActivity4(ctx context.Context, input []string) (string, error) {
-	name := activity.GetInfo(ctx).ActivityType.Name
-	fmt.Printf("Run %s with input %v \n", name, input)
-	return "Result_" + name, nil
+func (a *SampleActivities) SampleActivity5(ctx context.Context, input1, input2 interface{}) (string, error) {
+	return runSampleActivity(ctx, input1, input2)
+}
+
+// IncrementActivity returns its input incremented by one. It is handy as the body of a While loop.
+func (a *SampleActivities) IncrementActivity(ctx context.Context, n int) (int, error) {
+	return n + 1, nil
+}
+
+// GetOrder returns a dummy order with a couple of items.
+func (a *SampleActivities) GetOrder(ctx context.Context, orderID string) (Order, error) {
+	fmt.Printf("Run GetOrder with input %v \n", orderID)
+	return Order{
+		ID: orderID,
+		Items: []OrderItem{
+			{ID: orderID + "-item-1", Quantity: 2},
+			{ID: orderID + "-item-2", Quantity: 1},
+		},
+	}, nil
+}
+
+// ShipItem takes a typed struct as its argument.
+func (a *SampleActivities) ShipItem(ctx context.Context, item OrderItem) (string, error) {
+	fmt.Printf("Run ShipItem with input %+v \n", item)
+	return "Shipped_" + item.ID, nil
 }
 
-func (a *SampleActivities) SampleActivity5(ctx context.Context, input []string) (string, error) {
+func runSampleActivity(ctx context.Context, inputs ...interface{}) (string, error) {
 	name := activity.GetInfo(ctx).ActivityType.Name
-	fmt.Printf("Run %s with input %v \n", name, input)
+	var args []interface{}
+	for _, input := range inputs {
+		if input != nil {
+			args = append(args, input)
+		}
+	}
+	fmt.Printf("Run %s with input %v \n", name, args)
 	return "Result_" + name, nil
 }
+
+// SampleFailingActivity always fails with an ApplicationError of type SampleError. It is used to show retry policies
+// and try/catch.
+func (a *SampleActivities) SampleFailingActivity(ctx context.Context, input interface{}) (string, error) {
+	fmt.Printf("Run SampleFailingActivity with input %v \n", input)
+	return "", temporal.NewApplicationError(fmt.Sprintf("failed to process %v", input), "SampleError")
+}
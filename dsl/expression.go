@@ -0,0 +1,415 @@
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The expression language used by If, Switch and While statements is intentionally small so that evaluating it is
+// always deterministic. It supports:
+//   - literals: 'single' or "double" quoted strings, numbers, true and false
+//   - variable references by path, e.g. result1 or result1.items[0].id
+//   - comparison operators: ==, !=, <, <=, >, >=
+//   - logical operators: &&, || and !
+//   - parentheses for grouping
+//
+// Values are compared as numbers when both sides look like numbers, otherwise they are compared as strings. Objects
+// and lists are compared by their JSON encoding.
+
+type (
+	tokenKind int
+
+	token struct {
+		kind tokenKind
+		text string
+		pos  int
+	}
+
+	// expression is a parsed expression that can be evaluated against bindings.
+	expression interface {
+		eval(bindings map[string]interface{}) (interface{}, error)
+	}
+
+	literalExpr struct {
+		value interface{}
+	}
+
+	variableExpr struct {
+		name string
+	}
+
+	notExpr struct {
+		operand expression
+	}
+
+	binaryExpr struct {
+		op          string
+		left, right expression
+	}
+
+	exprParser struct {
+		source string
+		tokens []token
+		pos    int
+	}
+)
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+// evaluateCondition parses and evaluates the expression and converts the result to a boolean.
+func evaluateCondition(source string, bindings map[string]interface{}) (bool, error) {
+	v, err := evaluateExpression(source, bindings)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// evaluateExpression parses and evaluates the expression against the bindings.
+func evaluateExpression(source string, bindings map[string]interface{}) (interface{}, error) {
+	expr, err := parseExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	return expr.eval(bindings)
+}
+
+func parseExpression(source string) (expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{source: source, tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return expr, nil
+}
+
+// expressionVariables returns the variable paths an expression refers to, in order of appearance.
+func expressionVariables(expr expression) []string {
+	switch e := expr.(type) {
+	case *variableExpr:
+		return []string{e.name}
+	case *notExpr:
+		return expressionVariables(e.operand)
+	case *binaryExpr:
+		return append(expressionVariables(e.left), expressionVariables(e.right)...)
+	}
+	return nil
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == '\'' || r == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("expression %q: unterminated string at offset %d", source, start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || strings.ContainsRune("_.[]", runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			op := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			switch op {
+			case "==", "!=", "<=", ">=", "&&", "||", "<", ">", "!":
+			default:
+				return nil, fmt.Errorf("expression %q: unexpected character %q at offset %d", source, r, start)
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("expression %q: %s at offset %d", p.source, fmt.Sprintf(format, args...), t.pos)
+}
+
+func (p *exprParser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOperator && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (expression, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOperator && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseComparison() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokenOperator {
+		switch t.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			p.next()
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryExpr{op: t.text, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (expression, error) {
+	if t := p.peek(); t.kind == tokenOperator && t.text == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (expression, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %q", t.text)
+		}
+		return &literalExpr{value: f}, nil
+	case tokenString:
+		return &literalExpr{value: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalExpr{value: true}, nil
+		case "false":
+			return &literalExpr{value: false}, nil
+		}
+		return &variableExpr{name: t.text}, nil
+	case tokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected ')'")
+		}
+		return expr, nil
+	case tokenEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	default:
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+}
+
+func (e *literalExpr) eval(map[string]interface{}) (interface{}, error) {
+	return e.value, nil
+}
+
+func (e *variableExpr) eval(bindings map[string]interface{}) (interface{}, error) {
+	return resolvePath(e.name, bindings)
+}
+
+func (e *notExpr) eval(bindings map[string]interface{}) (interface{}, error) {
+	v, err := e.operand.eval(bindings)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+func (e *binaryExpr) eval(bindings map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(bindings)
+	if err != nil {
+		return nil, err
+	}
+	// && and || short-circuit so the right side may reference variables that are only bound in some branches.
+	switch e.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+	}
+	right, err := e.right.eval(bindings)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "&&", "||":
+		return truthy(right), nil
+	case "==":
+		return compareValues(left, right) == 0, nil
+	case "!=":
+		return compareValues(left, right) != 0, nil
+	case "<":
+		return compareValues(left, right) < 0, nil
+	case "<=":
+		return compareValues(left, right) <= 0, nil
+	case ">":
+		return compareValues(left, right) > 0, nil
+	case ">=":
+		return compareValues(left, right) >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", e.op)
+}
+
+// compareValues compares two values numerically when both can be read as numbers and as strings otherwise.
+func compareValues(left, right interface{}) int {
+	lf, lok := toNumber(left)
+	rf, rok := toNumber(right)
+	if lok && rok {
+		switch {
+		case lf < rf:
+			return -1
+		case lf > rf:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(toString(left), toString(right))
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return ""
+	}
+	// Objects and lists are compared by their JSON encoding, which sorts object keys.
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return t != "" && t != "false" && t != "0"
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	}
+	return v != nil
+}
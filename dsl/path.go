@@ -0,0 +1,81 @@
+package dsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// resolvePath looks up a path expression such as result1.items[0].id in the bindings. The first segment names a
+// variable, every following .field selects a key of a JSON object and every [n] selects an element of a JSON list.
+func resolvePath(path string, bindings map[string]interface{}) (interface{}, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := bindings[segments[0]]
+	if !ok {
+		return nil, fmt.Errorf("variable %q is not bound", segments[0])
+	}
+	for _, segment := range segments[1:] {
+		if strings.HasPrefix(segment, "[") {
+			index, _ := strconv.Atoi(segment[1 : len(segment)-1])
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path %q: %s is applied to a %T, not a list", path, segment, value)
+			}
+			if index >= len(list) {
+				return nil, fmt.Errorf("path %q: index %d out of range, the list has %d elements", path, index, len(list))
+			}
+			value = list[index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("path %q: field %q is applied to a %T, not an object", path, segment, value)
+		}
+		if value, ok = object[segment]; !ok {
+			return nil, fmt.Errorf("path %q: field %q does not exist", path, segment)
+		}
+	}
+	return value, nil
+}
+
+// splitPath splits result1.items[0].id into result1, items, [0] and id.
+func splitPath(path string) ([]string, error) {
+	var segments []string
+	rest := path
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: missing ']'", path)
+			}
+			if _, err := strconv.Atoi(rest[1:end]); err != nil || rest[1] == '-' {
+				return nil, fmt.Errorf("path %q: invalid index %q", path, rest[1:end])
+			}
+			segments = append(segments, rest[:end+1])
+			rest = rest[end+1:]
+		case rest[0] == '.' && len(segments) > 0:
+			rest = rest[1:]
+			if rest == "" || rest[0] == '.' || rest[0] == '[' {
+				return nil, fmt.Errorf("path %q: empty field name", path)
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q: empty field name", path)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		}
+	}
+	if len(segments) == 0 || strings.HasPrefix(segments[0], "[") {
+		return nil, fmt.Errorf("path %q must start with a variable name", path)
+	}
+	return segments, nil
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/pborman/uuid"
 	"go.temporal.io/sdk/client"
-	"gopkg.in/yaml.v3"
 
 	"github.com/temporalio/samples-go/dsl"
 )
@@ -22,9 +21,10 @@ func main() {
 	if err != nil {
 		log.Fatalln("failed to load dsl config file", err)
 	}
-	var dslWorkflow dsl.Workflow
-	if err := yaml.Unmarshal(data, &dslWorkflow); err != nil {
-		log.Fatalln("failed to unmarshal dsl config", err)
+	// Catch mistakes in the yaml before the workflow starts instead of when it reaches them.
+	dslWorkflow, err := dsl.ParseWorkflow(data, dsl.ActivityNames(&dsl.SampleActivities{}))
+	if err != nil {
+		log.Fatalln("invalid dsl config", err)
 	}
 
 	// The client is a heavyweight object that should be created once per process.
@@ -0,0 +1,478 @@
+package dsl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// ValidationError is a single problem found in a DSL workflow. Line is the line in the YAML source, or zero when
+	// the workflow was not parsed from YAML.
+	ValidationError struct {
+		Line    int
+		Message string
+	}
+
+	// ValidationErrors is the list of problems returned by Validate.
+	ValidationErrors []ValidationError
+
+	validator struct {
+		activities map[string]bool
+		errs       ValidationErrors
+	}
+
+	// writes records the line of the first statement that stores into each variable.
+	writes map[string]int
+)
+
+func (e ValidationError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ParseWorkflow unmarshals a YAML workflow definition and validates it against the activity names the worker
+// registers. The returned error is a ValidationErrors when the YAML is well-formed but the workflow is not.
+func ParseWorkflow(data []byte, activityNames []string) (Workflow, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Workflow{}, err
+	}
+	var dslWorkflow Workflow
+	if err := doc.Decode(&dslWorkflow); err != nil {
+		return Workflow{}, err
+	}
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if errs := validate(dslWorkflow, root, activityNames); len(errs) > 0 {
+		return dslWorkflow, errs
+	}
+	return dslWorkflow, nil
+}
+
+// Validate checks a workflow definition without running it. It reports activities that are not in activityNames,
+// references to variables that are not bound at that point, variables written from more than one Parallel branch,
+// empty sequences, invalid timeouts or retry policies, and incomplete child workflows, sleeps and signal waits. A nil
+// activityNames skips the activity name check.
+func Validate(dslWorkflow Workflow, activityNames []string) ValidationErrors {
+	return validate(dslWorkflow, nil, activityNames)
+}
+
+// ActivityNames returns the names the worker registers for the exported methods of an activity struct, the same
+// way worker.RegisterActivity does.
+func ActivityNames(activities interface{}) []string {
+	t := reflect.TypeOf(activities)
+	names := make([]string, 0, t.NumMethod())
+	for i := 0; i < t.NumMethod(); i++ {
+		names = append(names, t.Method(i).Name)
+	}
+	return names
+}
+
+func validate(dslWorkflow Workflow, root *yaml.Node, activityNames []string) ValidationErrors {
+	v := &validator{}
+	if activityNames != nil {
+		v.activities = make(map[string]bool, len(activityNames))
+		for _, name := range activityNames {
+			v.activities[name] = true
+		}
+	}
+	bound := make(map[string]bool, len(dslWorkflow.Variables))
+	for name := range dslWorkflow.Variables {
+		bound[name] = true
+	}
+	v.workflow(dslWorkflow, root, bound)
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Line < v.errs[j].Line })
+	return v.errs
+}
+
+func (v *validator) workflow(dslWorkflow Workflow, node *yaml.Node, bound map[string]bool) {
+	v.statement(&dslWorkflow.Root, mappingValue(node, "root"), bound, writes{})
+	if dslWorkflow.Output != "" {
+		v.path(dslWorkflow.Output, mappingValue(node, "output"), node, bound)
+	}
+}
+
+func (v *validator) errorf(node *yaml.Node, format string, args ...interface{}) {
+	line := 0
+	if node != nil {
+		line = node.Line
+	}
+	v.errs = append(v.errs, ValidationError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+const statementKinds = "activity, sequence, parallel, if, switch, forEach, while, try, childWorkflow, sleep or awaitSignal"
+
+// statement validates s given the variables bound before it runs, adds the variables it binds to bound, and records
+// the variables it writes in w.
+func (v *validator) statement(s *Statement, node *yaml.Node, bound map[string]bool, w writes) {
+	if s == nil {
+		v.errorf(node, "missing statement")
+		return
+	}
+	kinds := 0
+	if s.Activity != nil {
+		kinds++
+		v.activity(s.Activity, mappingValue(node, "activity"), bound, w)
+	}
+	if s.Sequence != nil {
+		kinds++
+		n := mappingValue(node, "sequence")
+		if len(s.Sequence.Elements) == 0 {
+			v.errorf(n, "sequence has no elements")
+		}
+		elements := mappingValue(n, "elements")
+		for i, e := range s.Sequence.Elements {
+			v.statement(e, sequenceItem(elements, i), bound, w)
+		}
+	}
+	if s.Parallel != nil {
+		kinds++
+		v.parallel(s.Parallel, mappingValue(node, "parallel"), bound, w)
+	}
+	if s.If != nil {
+		kinds++
+		n := mappingValue(node, "if")
+		v.expression(s.If.Condition, mappingValue(n, "condition"), n, bound)
+		v.branches(bound, w,
+			branch{s.If.Then, mappingValue(n, "then")},
+			branch{s.If.Else, mappingValue(n, "else")})
+	}
+	if s.Switch != nil {
+		kinds++
+		n := mappingValue(node, "switch")
+		v.expression(s.Switch.Value, mappingValue(n, "value"), n, bound)
+		cases := mappingValue(n, "cases")
+		branches := []branch{{s.Switch.Default, mappingValue(n, "default")}}
+		for i, c := range s.Switch.Cases {
+			cn := sequenceItem(cases, i)
+			v.expression(c.Value, mappingValue(cn, "value"), cn, bound)
+			branches = append(branches, branch{c.Body, mappingValue(cn, "body")})
+		}
+		v.branches(bound, w, branches...)
+	}
+	if s.ForEach != nil {
+		kinds++
+		n := mappingValue(node, "forEach")
+		v.path(s.ForEach.In, mappingValue(n, "in"), n, bound)
+		if s.ForEach.Item == "" {
+			v.errorf(n, "forEach has no item variable")
+		}
+		// Iterations run on a copy of the bindings, so nothing the body binds is visible after the loop.
+		scope := copyBound(bound)
+		scope[s.ForEach.Item] = true
+		v.optionalStatement(s.ForEach.Body, mappingValue(n, "body"), n, "forEach", scope, writes{})
+	}
+	if s.While != nil {
+		kinds++
+		n := mappingValue(node, "while")
+		v.expression(s.While.Condition, mappingValue(n, "condition"), n, bound)
+		// The body may not run at all, so nothing it binds is visible after the loop.
+		v.optionalStatement(s.While.Body, mappingValue(n, "body"), n, "while", copyBound(bound), w)
+	}
+	if s.Try != nil {
+		kinds++
+		v.try(s.Try, mappingValue(node, "try"), bound, w)
+	}
+	if s.ChildWorkflow != nil {
+		kinds++
+		v.childWorkflow(s.ChildWorkflow, mappingValue(node, "childWorkflow"), bound, w)
+	}
+	if s.Sleep != nil {
+		kinds++
+		n := mappingValue(node, "sleep")
+		if s.Sleep.Duration <= 0 {
+			v.errorf(orNode(mappingValue(n, "duration"), n), "sleep duration must be positive")
+		}
+	}
+	if s.AwaitSignal != nil {
+		kinds++
+		n := mappingValue(node, "awaitSignal")
+		if s.AwaitSignal.Name == "" {
+			v.errorf(n, "awaitSignal has no signal name")
+		}
+		if s.AwaitSignal.Timeout < 0 {
+			v.errorf(orNode(mappingValue(n, "timeout"), n), "timeout must not be negative")
+		}
+		v.result(s.AwaitSignal.Result, n, bound, w)
+	}
+	switch {
+	case kinds == 0:
+		v.errorf(node, "statement has none of %s", statementKinds)
+	case kinds > 1:
+		v.errorf(node, "statement has more than one of %s", statementKinds)
+	}
+}
+
+func (v *validator) optionalStatement(s *Statement, node, parent *yaml.Node, kind string, bound map[string]bool, w writes) {
+	if s == nil {
+		v.errorf(parent, "%s has no body", kind)
+		return
+	}
+	v.statement(s, node, bound, w)
+}
+
+func (v *validator) activity(a *ActivityInvocation, node *yaml.Node, bound map[string]bool, w writes) {
+	if a.Name == "" {
+		v.errorf(node, "activity has no name")
+	} else if v.activities != nil && !v.activities[a.Name] {
+		v.errorf(orNode(mappingValue(node, "name"), node), "activity %q is not registered by the worker", a.Name)
+	}
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"startToCloseTimeout", a.StartToCloseTimeout},
+		{"scheduleToCloseTimeout", a.ScheduleToCloseTimeout},
+		{"heartbeatTimeout", a.HeartbeatTimeout},
+	} {
+		if timeout.value < 0 {
+			v.errorf(orNode(mappingValue(node, timeout.key), node), "%s must not be negative", timeout.key)
+		}
+	}
+	if r := a.RetryPolicy; r != nil {
+		rn := orNode(mappingValue(node, "retryPolicy"), node)
+		if r.BackoffCoefficient != 0 && r.BackoffCoefficient < 1 {
+			v.errorf(orNode(mappingValue(rn, "backoffCoefficient"), rn), "backoffCoefficient must be at least 1")
+		}
+		if r.MaximumAttempts < 0 {
+			v.errorf(orNode(mappingValue(rn, "maximumAttempts"), rn), "maximumAttempts must not be negative")
+		}
+		if r.MaximumInterval != 0 && r.MaximumInterval < r.InitialInterval {
+			v.errorf(orNode(mappingValue(rn, "maximumInterval"), rn), "maximumInterval must not be less than initialInterval")
+		}
+	}
+	args := mappingValue(node, "arguments")
+	for i, arg := range a.Arguments {
+		v.path(arg, sequenceItem(args, i), node, bound)
+	}
+	v.result(a.Result, node, bound, w)
+}
+
+func (v *validator) childWorkflow(c *ChildWorkflow, node *yaml.Node, bound map[string]bool, w writes) {
+	switch {
+	case c.WorkflowType == "" && c.Definition == nil:
+		v.errorf(node, "childWorkflow has neither a workflowType nor a definition")
+	case c.WorkflowType != "" && c.Definition != nil:
+		v.errorf(node, "childWorkflow has both a workflowType and a definition")
+	case c.Definition != nil:
+		if len(c.Arguments) > 0 {
+			v.errorf(orNode(mappingValue(node, "arguments"), node), "childWorkflow with a definition takes inputs, not arguments")
+		}
+		inputs := mappingValue(node, "inputs")
+		childBound := make(map[string]bool, len(c.Definition.Variables)+len(c.Inputs))
+		for name := range c.Definition.Variables {
+			childBound[name] = true
+		}
+		for _, name := range sortedKeys(c.Inputs) {
+			v.path(c.Inputs[name], mappingValue(inputs, name), orNode(inputs, node), bound)
+			childBound[name] = true
+		}
+		// The child is a workflow of its own with its own variables.
+		v.workflow(*c.Definition, mappingValue(node, "definition"), childBound)
+	default:
+		if len(c.Inputs) > 0 {
+			v.errorf(orNode(mappingValue(node, "inputs"), node), "childWorkflow with a workflowType takes arguments, not inputs")
+		}
+		args := mappingValue(node, "arguments")
+		for i, arg := range c.Arguments {
+			v.path(arg, sequenceItem(args, i), node, bound)
+		}
+	}
+	v.result(c.Result, node, bound, w)
+}
+
+// result records that the statement at node stores into the variable name.
+func (v *validator) result(name string, node *yaml.Node, bound map[string]bool, w writes) {
+	if name == "" {
+		return
+	}
+	bound[name] = true
+	if _, ok := w[name]; !ok {
+		w[name] = lineOf(orNode(mappingValue(node, "result"), node))
+	}
+}
+
+func (v *validator) try(t *Try, node *yaml.Node, bound map[string]bool, w writes) {
+	before := copyBound(bound)
+	v.optionalStatement(t.Body, mappingValue(node, "body"), node, "try", bound, w)
+	if t.Catch != nil {
+		// Body may have failed at any point, so Catch only sees what was bound before the try.
+		scope := copyBound(before)
+		if t.Error != "" {
+			scope[t.Error] = true
+		}
+		v.statement(t.Catch, mappingValue(node, "catch"), scope, w)
+		for name := range bound {
+			if !scope[name] {
+				delete(bound, name)
+			}
+		}
+	}
+	if t.Finally != nil {
+		// Finally also runs when Body failed, so it only sees what was bound before the try.
+		scope := copyBound(before)
+		v.statement(t.Finally, mappingValue(node, "finally"), scope, w)
+		for name := range scope {
+			bound[name] = true
+		}
+	}
+}
+
+func (v *validator) parallel(p *Parallel, node *yaml.Node, bound map[string]bool, w writes) {
+	if len(p.Branches) == 0 {
+		v.errorf(node, "parallel has no branches")
+	}
+	branchNodes := mappingValue(node, "branches")
+	writtenBy := map[string]int{}
+	var added []string
+	for i, b := range p.Branches {
+		// Every branch only sees the variables bound before the parallel block.
+		scope := copyBound(bound)
+		bw := writes{}
+		v.statement(b, sequenceItem(branchNodes, i), scope, bw)
+		for _, name := range sortedKeys(bw) {
+			if first, ok := writtenBy[name]; ok {
+				v.errs = append(v.errs, ValidationError{
+					Line:    bw[name],
+					Message: fmt.Sprintf("variable %q is written by parallel branches %d and %d", name, first+1, i+1),
+				})
+				continue
+			}
+			writtenBy[name] = i
+			if _, ok := w[name]; !ok {
+				w[name] = bw[name]
+			}
+		}
+		for name := range scope {
+			if !bound[name] {
+				added = append(added, name)
+			}
+		}
+	}
+	// All branches complete before the statement after the parallel block runs.
+	for _, name := range added {
+		bound[name] = true
+	}
+}
+
+type branch struct {
+	statement *Statement
+	node      *yaml.Node
+}
+
+// branches validates statements of which at most one runs. A variable is bound afterwards only if every branch
+// binds it; a nil statement is a branch that binds nothing.
+func (v *validator) branches(bound map[string]bool, w writes, branches ...branch) {
+	var common map[string]bool
+	for _, b := range branches {
+		scope := copyBound(bound)
+		if b.statement != nil {
+			v.statement(b.statement, b.node, scope, w)
+		}
+		if common == nil {
+			common = scope
+			continue
+		}
+		for name := range common {
+			if !scope[name] {
+				delete(common, name)
+			}
+		}
+	}
+	for name := range common {
+		bound[name] = true
+	}
+}
+
+func (v *validator) expression(source string, node, parent *yaml.Node, bound map[string]bool) {
+	node = orNode(node, parent)
+	expr, err := parseExpression(source)
+	if err != nil {
+		v.errorf(node, "%v", err)
+		return
+	}
+	for _, name := range expressionVariables(expr) {
+		v.path(name, node, node, bound)
+	}
+}
+
+func (v *validator) path(path string, node, parent *yaml.Node, bound map[string]bool) {
+	node = orNode(node, parent)
+	segments, err := splitPath(path)
+	if err != nil {
+		v.errorf(node, "%v", err)
+		return
+	}
+	if !bound[segments[0]] {
+		v.errorf(node, "variable %q is not bound", segments[0])
+	}
+}
+
+// mappingValue returns the value node of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// sequenceItem returns the i-th item of a sequence node, or nil.
+func sequenceItem(node *yaml.Node, i int) *yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+		return nil
+	}
+	return node.Content[i]
+}
+
+func orNode(node, fallback *yaml.Node) *yaml.Node {
+	if node != nil {
+		return node
+	}
+	return fallback
+}
+
+func lineOf(node *yaml.Node) int {
+	if node == nil {
+		return 0
+	}
+	return node.Line
+}
+
+func copyBound(bound map[string]bool) map[string]bool {
+	c := make(map[string]bool, len(bound))
+	for k, v := range bound {
+		c[k] = v
+	}
+	return c
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/temporalio/samples-go/dsl"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [workflow.yaml ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"dsl/workflow1.yaml"}
+	}
+
+	// Check activity names against the same activities dsl/worker registers.
+	activityNames := dsl.ActivityNames(&dsl.SampleActivities{})
+
+	failed := false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatalln("failed to load dsl config file", err)
+		}
+		_, err = dsl.ParseWorkflow(data, activityNames)
+		if errs, ok := err.(dsl.ValidationErrors); ok {
+			failed = true
+			for _, e := range errs {
+				fmt.Printf("%s:%d: %s\n", file, e.Line, e.Message)
+			}
+			continue
+		}
+		if err != nil {
+			failed = true
+			fmt.Printf("%s: %v\n", file, err)
+			continue
+		}
+		fmt.Printf("%s: ok\n", file)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
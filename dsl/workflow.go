@@ -1,25 +1,41 @@
 package dsl
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
 type (
 	// Workflow is the type used to express the workflow definition. Variables are a map of valuables. Variables can be
-	// used as input to Activity.
+	// used as input to Activity. A variable can hold any JSON value: a string, number, boolean, list or object.
+	// Output is an optional path to the value the workflow returns, JSON encoded.
 	Workflow struct {
-		Variables map[string]string
+		Variables map[string]interface{}
 		Root      Statement
+		Output    string
 	}
 
 	// Statement is the building block of dsl workflow. A Statement can be a simple ActivityInvocation or it
-	// could be a Sequence or Parallel.
+	// could be a Sequence, Parallel, If, Switch, ForEach, While, Try, ChildWorkflow, Sleep or AwaitSignal.
 	Statement struct {
-		Activity *ActivityInvocation
-		Sequence *Sequence
-		Parallel *Parallel
+		Activity      *ActivityInvocation
+		Sequence      *Sequence
+		Parallel      *Parallel
+		If            *If
+		Switch        *Switch
+		ForEach       *ForEach `yaml:"forEach"`
+		While         *While
+		Try           *Try
+		ChildWorkflow *ChildWorkflow `yaml:"childWorkflow"`
+		Sleep         *Sleep
+		AwaitSignal   *AwaitSignal `yaml:"awaitSignal"`
 	}
 
 	// Sequence consist of a collection of Statements that runs in sequential.
@@ -32,27 +48,123 @@ type (
 		Branches []*Statement
 	}
 
+	// If runs Then when Condition evaluates to true and Else otherwise. Else is optional.
+	If struct {
+		Condition string
+		Then      *Statement
+		Else      *Statement
+	}
+
+	// Switch evaluates Value and runs the body of the first Case whose Value is equal to it. Default runs when no
+	// Case matches and is optional.
+	Switch struct {
+		Value   string
+		Cases   []*Case
+		Default *Statement
+	}
+
+	// Case is a single branch of a Switch. Value is an expression, usually a quoted literal.
+	Case struct {
+		Value string
+		Body  *Statement
+	}
+
+	// ForEach runs Body once for every element of the list at path In, with the element bound to Item. A string is
+	// treated as a list with elements separated by Separator, which defaults to a comma. Up to Concurrency
+	// iterations run at the same time; zero means one at a time. Every iteration gets its own copy of the bindings,
+	// so variables written by the Body are not visible after the loop.
+	ForEach struct {
+		In          string
+		Item        string
+		Separator   string
+		Concurrency int
+		Body        *Statement
+	}
+
+	// While runs Body as long as Condition evaluates to true. The loop fails once it has run MaxIterations times to
+	// protect the workflow from an unbounded history; zero means defaultMaxIterations.
+	While struct {
+		Condition     string
+		MaxIterations int `yaml:"maxIterations"`
+		Body          *Statement
+	}
+
+	// Try runs Body and, if it fails, runs Catch instead of failing the workflow. The error is bound to the Error
+	// variable as an object with a message and a type, where type is the ApplicationError type. Finally runs after
+	// Body and Catch whether they failed or not, even when the workflow is cancelled. Catch and Finally are optional.
+	Try struct {
+		Body    *Statement
+		Error   string
+		Catch   *Statement
+		Finally *Statement
+	}
+
+	// ChildWorkflow runs either a registered workflow type or another DSL workflow as a child workflow. A
+	// WorkflowType is called with Arguments as positional arguments, the same way as an Activity. A Definition runs
+	// as a SimpleDSLWorkflow whose variables are its own Variables plus Inputs, which maps a child variable name to a
+	// path in the parent. Result stores what the child returns; for a Definition that is the value at its Output.
+	ChildWorkflow struct {
+		WorkflowType string `yaml:"workflowType"`
+		Definition   *Workflow
+		Arguments    []string
+		Inputs       map[string]string
+		Result       string
+	}
+
+	// Sleep pauses the workflow for Duration using a durable timer.
+	Sleep struct {
+		Duration time.Duration
+	}
+
+	// AwaitSignal blocks until the workflow receives the signal Name and stores its payload into Result. When
+	// Timeout is set and no signal arrives in time the statement fails with an ApplicationError of type
+	// SignalTimeoutErrorType, which a Try can catch.
+	AwaitSignal struct {
+		Name    string
+		Result  string
+		Timeout time.Duration
+	}
+
 	// ActivityInvocation is used to express invoking an Activity. The Arguments defined expected arguments as input to
 	// the Activity, the result specify the name of variable that it will store the result as which can then be used as
-	// arguments to subsequent ActivityInvocation.
+	// arguments to subsequent ActivityInvocation. Every argument is a path such as result1.items[0].id and is passed
+	// to the Activity as a separate positional argument, so the Activity can take typed parameters such as structs.
+	// The timeouts and the RetryPolicy are optional and override the workflow defaults for this Activity only.
 	ActivityInvocation struct {
-		Name      string
-		Arguments []string
-		Result    string
+		Name                   string
+		Arguments              []string
+		Result                 string
+		StartToCloseTimeout    time.Duration `yaml:"startToCloseTimeout"`
+		ScheduleToCloseTimeout time.Duration `yaml:"scheduleToCloseTimeout"`
+		HeartbeatTimeout       time.Duration `yaml:"heartbeatTimeout"`
+		RetryPolicy            *RetryPolicy  `yaml:"retryPolicy"`
+	}
+
+	// RetryPolicy mirrors temporal.RetryPolicy. Activities that fail with an ApplicationError whose type is in
+	// NonRetryableErrorTypes are not retried.
+	RetryPolicy struct {
+		InitialInterval        time.Duration `yaml:"initialInterval"`
+		BackoffCoefficient     float64       `yaml:"backoffCoefficient"`
+		MaximumInterval        time.Duration `yaml:"maximumInterval"`
+		MaximumAttempts        int32         `yaml:"maximumAttempts"`
+		NonRetryableErrorTypes []string      `yaml:"nonRetryableErrorTypes"`
 	}
 
 	executable interface {
-		execute(ctx workflow.Context, bindings map[string]string) error
+		execute(ctx workflow.Context, bindings map[string]interface{}) error
 	}
 )
 
+const (
+	defaultMaxIterations = 100
+
+	// SignalTimeoutErrorType is the ApplicationError type of an AwaitSignal that timed out.
+	SignalTimeoutErrorType = "SignalTimeout"
+)
+
 // SimpleDSLWorkflow workflow definition
 func SimpleDSLWorkflow(ctx workflow.Context, dslWorkflow Workflow) ([]byte, error) {
-	bindings := make(map[string]string)
-	//workflowcheck:ignore Only iterates for building another map
-	for k, v := range dslWorkflow.Variables {
-		bindings[k] = v
-	}
+	bindings := copyBindings(dslWorkflow.Variables)
 
 	ao := workflow.ActivityOptions{
 		StartToCloseTimeout: 10 * time.Second,
@@ -66,11 +178,22 @@ func SimpleDSLWorkflow(ctx workflow.Context, dslWorkflow Workflow) ([]byte, erro
 		return nil, err
 	}
 
+	var output []byte
+	if dslWorkflow.Output != "" {
+		value, err := resolvePath(dslWorkflow.Output, bindings)
+		if err != nil {
+			return nil, err
+		}
+		if output, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+
 	logger.Info("DSL Workflow completed.")
-	return nil, err
+	return output, nil
 }
 
-func (b *Statement) execute(ctx workflow.Context, bindings map[string]string) error {
+func (b *Statement) execute(ctx workflow.Context, bindings map[string]interface{}) error {
 	if b.Parallel != nil {
 		err := b.Parallel.execute(ctx, bindings)
 		if err != nil {
@@ -89,13 +212,64 @@ func (b *Statement) execute(ctx workflow.Context, bindings map[string]string) er
 			return err
 		}
 	}
+	if b.If != nil {
+		err := b.If.execute(ctx, bindings)
+		if err != nil {
+			return err
+		}
+	}
+	if b.Switch != nil {
+		err := b.Switch.execute(ctx, bindings)
+		if err != nil {
+			return err
+		}
+	}
+	if b.ForEach != nil {
+		err := b.ForEach.execute(ctx, bindings)
+		if err != nil {
+			return err
+		}
+	}
+	if b.While != nil {
+		err := b.While.execute(ctx, bindings)
+		if err != nil {
+			return err
+		}
+	}
+	if b.Try != nil {
+		err := b.Try.execute(ctx, bindings)
+		if err != nil {
+			return err
+		}
+	}
+	if b.ChildWorkflow != nil {
+		err := b.ChildWorkflow.execute(ctx, bindings)
+		if err != nil {
+			return err
+		}
+	}
+	if b.Sleep != nil {
+		err := b.Sleep.execute(ctx, bindings)
+		if err != nil {
+			return err
+		}
+	}
+	if b.AwaitSignal != nil {
+		err := b.AwaitSignal.execute(ctx, bindings)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (a ActivityInvocation) execute(ctx workflow.Context, bindings map[string]string) error {
-	inputParam := makeInput(a.Arguments, bindings)
-	var result string
-	err := workflow.ExecuteActivity(ctx, a.Name, inputParam).Get(ctx, &result)
+func (a ActivityInvocation) execute(ctx workflow.Context, bindings map[string]interface{}) error {
+	args, err := makeInput(a.Arguments, bindings)
+	if err != nil {
+		return err
+	}
+	var result interface{}
+	err = workflow.ExecuteActivity(a.withActivityOptions(ctx), a.Name, args...).Get(ctx, &result)
 	if err != nil {
 		return err
 	}
@@ -105,7 +279,31 @@ func (a ActivityInvocation) execute(ctx workflow.Context, bindings map[string]st
 	return nil
 }
 
-func (s Sequence) execute(ctx workflow.Context, bindings map[string]string) error {
+// withActivityOptions overrides the options the workflow set on ctx with the ones set on the invocation.
+func (a ActivityInvocation) withActivityOptions(ctx workflow.Context) workflow.Context {
+	ao := workflow.GetActivityOptions(ctx)
+	if a.StartToCloseTimeout > 0 {
+		ao.StartToCloseTimeout = a.StartToCloseTimeout
+	}
+	if a.ScheduleToCloseTimeout > 0 {
+		ao.ScheduleToCloseTimeout = a.ScheduleToCloseTimeout
+	}
+	if a.HeartbeatTimeout > 0 {
+		ao.HeartbeatTimeout = a.HeartbeatTimeout
+	}
+	if a.RetryPolicy != nil {
+		ao.RetryPolicy = &temporal.RetryPolicy{
+			InitialInterval:        a.RetryPolicy.InitialInterval,
+			BackoffCoefficient:     a.RetryPolicy.BackoffCoefficient,
+			MaximumInterval:        a.RetryPolicy.MaximumInterval,
+			MaximumAttempts:        a.RetryPolicy.MaximumAttempts,
+			NonRetryableErrorTypes: a.RetryPolicy.NonRetryableErrorTypes,
+		}
+	}
+	return workflow.WithActivityOptions(ctx, ao)
+}
+
+func (s Sequence) execute(ctx workflow.Context, bindings map[string]interface{}) error {
 	for _, a := range s.Elements {
 		err := a.execute(ctx, bindings)
 		if err != nil {
@@ -115,7 +313,7 @@ func (s Sequence) execute(ctx workflow.Context, bindings map[string]string) erro
 	return nil
 }
 
-func (p Parallel) execute(ctx workflow.Context, bindings map[string]string) error {
+func (p Parallel) execute(ctx workflow.Context, bindings map[string]interface{}) error {
 	//
 	// You can use the context passed in to activity as a way to cancel the activity like standard GO way.
 	// Cancelling a parent context will cancel all the derived contexts as well.
@@ -148,7 +346,241 @@ func (p Parallel) execute(ctx workflow.Context, bindings map[string]string) erro
 	return nil
 }
 
-func executeAsync(exe executable, ctx workflow.Context, bindings map[string]string) workflow.Future {
+func (i If) execute(ctx workflow.Context, bindings map[string]interface{}) error {
+	ok, err := evaluateCondition(i.Condition, bindings)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return executeOptional(ctx, i.Then, bindings)
+	}
+	return executeOptional(ctx, i.Else, bindings)
+}
+
+func (s Switch) execute(ctx workflow.Context, bindings map[string]interface{}) error {
+	value, err := evaluateExpression(s.Value, bindings)
+	if err != nil {
+		return err
+	}
+	for _, c := range s.Cases {
+		caseValue, err := evaluateExpression(c.Value, bindings)
+		if err != nil {
+			return err
+		}
+		if compareValues(value, caseValue) == 0 {
+			return executeOptional(ctx, c.Body, bindings)
+		}
+	}
+	return executeOptional(ctx, s.Default, bindings)
+}
+
+func (f ForEach) execute(ctx workflow.Context, bindings map[string]interface{}) error {
+	value, err := resolvePath(f.In, bindings)
+	if err != nil {
+		return fmt.Errorf("forEach: %w", err)
+	}
+	var items []interface{}
+	switch list := value.(type) {
+	case []interface{}:
+		items = list
+	case string:
+		separator := f.Separator
+		if separator == "" {
+			separator = ","
+		}
+		for _, item := range strings.Split(list, separator) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	default:
+		return fmt.Errorf("forEach: %q is a %T, not a list", f.In, value)
+	}
+	if len(items) == 0 || f.Body == nil {
+		return nil
+	}
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// Same as Parallel, the first failed iteration cancels the ones still running.
+	childCtx, cancelHandler := workflow.WithCancel(ctx)
+	defer cancelHandler()
+	selector := workflow.NewSelector(ctx)
+	var iterationErr error
+	pending := 0
+	for i := 0; i < len(items) || pending > 0; {
+		if i < len(items) && pending < concurrency && iterationErr == nil {
+			scope := copyBindings(bindings)
+			scope[f.Item] = items[i]
+			selector.AddFuture(executeAsync(f.Body, childCtx, scope), func(future workflow.Future) {
+				if err := future.Get(ctx, nil); err != nil && iterationErr == nil {
+					cancelHandler()
+					iterationErr = err
+				}
+			})
+			pending++
+			i++
+			continue
+		}
+		if pending == 0 {
+			break
+		}
+		selector.Select(ctx)
+		pending--
+	}
+	return iterationErr
+}
+
+func (w While) execute(ctx workflow.Context, bindings map[string]interface{}) error {
+	maxIterations := w.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxIterations
+	}
+	for i := 0; ; i++ {
+		ok, err := evaluateCondition(w.Condition, bindings)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if i >= maxIterations {
+			return fmt.Errorf("while: condition %q still true after %d iterations", w.Condition, maxIterations)
+		}
+		if err := executeOptional(ctx, w.Body, bindings); err != nil {
+			return err
+		}
+	}
+}
+
+func (t Try) execute(ctx workflow.Context, bindings map[string]interface{}) error {
+	err := executeOptional(ctx, t.Body, bindings)
+	// A cancelled workflow is not caught, but Finally still runs below.
+	if err != nil && t.Catch != nil && !temporal.IsCanceledError(err) {
+		if t.Error != "" {
+			bindings[t.Error] = errorValue(err)
+		}
+		err = t.Catch.execute(ctx, bindings)
+	}
+	if t.Finally != nil {
+		finallyCtx := ctx
+		if ctx.Err() != nil {
+			// The workflow was cancelled, so Finally needs a context that is not.
+			var cancel workflow.CancelFunc
+			finallyCtx, cancel = workflow.NewDisconnectedContext(ctx)
+			defer cancel()
+		}
+		if finallyErr := t.Finally.execute(finallyCtx, bindings); finallyErr != nil && err == nil {
+			err = finallyErr
+		}
+	}
+	return err
+}
+
+func (c ChildWorkflow) execute(ctx workflow.Context, bindings map[string]interface{}) error {
+	if c.Definition != nil {
+		definition := *c.Definition
+		definition.Variables = copyBindings(c.Definition.Variables)
+		// Sorted so that a missing input is always reported the same way on replay.
+		names := make([]string, 0, len(c.Inputs))
+		//workflowcheck:ignore Only collects the keys, which are sorted below
+		for name := range c.Inputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, err := resolvePath(c.Inputs[name], bindings)
+			if err != nil {
+				return err
+			}
+			definition.Variables[name] = value
+		}
+		var output []byte
+		if err := workflow.ExecuteChildWorkflow(ctx, SimpleDSLWorkflow, definition).Get(ctx, &output); err != nil {
+			return err
+		}
+		if c.Result != "" && len(output) > 0 {
+			var result interface{}
+			if err := json.Unmarshal(output, &result); err != nil {
+				return err
+			}
+			bindings[c.Result] = result
+		}
+		return nil
+	}
+
+	args, err := makeInput(c.Arguments, bindings)
+	if err != nil {
+		return err
+	}
+	var result interface{}
+	if err := workflow.ExecuteChildWorkflow(ctx, c.WorkflowType, args...).Get(ctx, &result); err != nil {
+		return err
+	}
+	if c.Result != "" {
+		bindings[c.Result] = result
+	}
+	return nil
+}
+
+func (s Sleep) execute(ctx workflow.Context, _ map[string]interface{}) error {
+	return workflow.Sleep(ctx, s.Duration)
+}
+
+func (a AwaitSignal) execute(ctx workflow.Context, bindings map[string]interface{}) error {
+	var payload interface{}
+	ch := workflow.GetSignalChannel(ctx, a.Name)
+	if a.Timeout > 0 {
+		ok, _ := ch.ReceiveWithTimeout(ctx, a.Timeout, &payload)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !ok {
+			return temporal.NewApplicationError(
+				fmt.Sprintf("signal %q not received within %v", a.Name, a.Timeout), SignalTimeoutErrorType)
+		}
+	} else {
+		ch.Receive(ctx, &payload)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if a.Result != "" {
+		bindings[a.Result] = payload
+	}
+	return nil
+}
+
+// errorValue converts an error into the value bound to the Error variable of a Try.
+func errorValue(err error) map[string]interface{} {
+	value := map[string]interface{}{"message": err.Error(), "type": ""}
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		value["message"] = appErr.Message()
+		value["type"] = appErr.Type()
+	}
+	return value
+}
+
+func executeOptional(ctx workflow.Context, s *Statement, bindings map[string]interface{}) error {
+	if s == nil {
+		return nil
+	}
+	return s.execute(ctx, bindings)
+}
+
+func copyBindings(bindings map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(bindings))
+	//workflowcheck:ignore Only iterates for building another map
+	for k, v := range bindings {
+		c[k] = v
+	}
+	return c
+}
+
+func executeAsync(exe executable, ctx workflow.Context, bindings map[string]interface{}) workflow.Future {
 	future, settable := workflow.NewFuture(ctx)
 	workflow.Go(ctx, func(ctx workflow.Context) {
 		err := exe.execute(ctx, bindings)
@@ -157,10 +589,14 @@ func executeAsync(exe executable, ctx workflow.Context, bindings map[string]stri
 	return future
 }
 
-func makeInput(argNames []string, argsMap map[string]string) []string {
-	var args []string
+func makeInput(argNames []string, argsMap map[string]interface{}) ([]interface{}, error) {
+	var args []interface{}
 	for _, arg := range argNames {
-		args = append(args, argsMap[arg])
+		value, err := resolvePath(arg, argsMap)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
 	}
-	return args
+	return args, nil
 }
@@ -0,0 +1,295 @@
+package dsl
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"gopkg.in/yaml.v3"
+)
+
+func loadWorkflow(t *testing.T, path string) Workflow {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var dslWorkflow Workflow
+	require.NoError(t, yaml.Unmarshal(data, &dslWorkflow))
+	return dslWorkflow
+}
+
+func Test_ControlFlowWorkflow(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&SampleActivities{})
+
+	var mu sync.Mutex
+	var started []string
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+		mu.Lock()
+		defer mu.Unlock()
+		started = append(started, info.ActivityType.Name)
+	})
+
+	env.ExecuteWorkflow(SimpleDSLWorkflow, loadWorkflow(t, "workflow3.yaml"))
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, []string{
+		"SampleActivity1",
+		"SampleActivity2",
+		"SampleActivity3",
+		"SampleActivity4", "SampleActivity4", "SampleActivity4",
+		"IncrementActivity", "IncrementActivity", "IncrementActivity",
+	}, started)
+}
+
+func Test_WhileMaxIterations(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&SampleActivities{})
+
+	env.ExecuteWorkflow(SimpleDSLWorkflow, Workflow{
+		Variables: map[string]interface{}{"attempt": 0},
+		Root: Statement{While: &While{
+			Condition:     "attempt >= 0",
+			MaxIterations: 2,
+			Body: &Statement{Activity: &ActivityInvocation{
+				Name:      "IncrementActivity",
+				Arguments: []string{"attempt"},
+				Result:    "attempt",
+			}},
+		}},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), "after 2 iterations")
+}
+
+func Test_TypedValuesWorkflow(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := &SampleActivities{}
+	env.RegisterActivity(activities)
+	env.OnActivity(activities.SampleActivity1, mock.Anything, "order-1-item-2",
+		map[string]interface{}{"name": "Alice", "tier": "gold"}).Return("done", nil).Once()
+	env.OnActivity(activities.ShipItem, mock.Anything, OrderItem{ID: "order-1-item-1", Quantity: 2}).
+		Return("shipped", nil).Twice()
+	env.OnActivity(activities.ShipItem, mock.Anything, OrderItem{ID: "order-1-item-2", Quantity: 1}).
+		Return("shipped", nil).Once()
+
+	env.ExecuteWorkflow(SimpleDSLWorkflow, loadWorkflow(t, "workflow4.yaml"))
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func Test_TryCatchFinallyWorkflow(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := &SampleActivities{}
+	env.RegisterActivity(activities)
+	env.OnActivity(activities.SampleActivity2, mock.Anything, "failed to process Result_SampleActivity1", "SampleError").
+		Return("compensated", nil).Once()
+	env.OnActivity(activities.SampleActivity3, mock.Anything, "value1", nil).Return("finally", nil).Once()
+
+	var attempts int32
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+		if info.ActivityType.Name == "SampleFailingActivity" {
+			attempts = info.Attempt
+		}
+	})
+
+	env.ExecuteWorkflow(SimpleDSLWorkflow, loadWorkflow(t, "workflow5.yaml"))
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+	// SampleError is non retryable, so the activity only ran once.
+	require.Equal(t, int32(1), attempts)
+}
+
+func Test_TryWithoutCatchFails(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := &SampleActivities{}
+	env.RegisterActivity(activities)
+	env.OnActivity(activities.SampleActivity3, mock.Anything, nil, nil).Return("finally", nil).Once()
+
+	env.ExecuteWorkflow(SimpleDSLWorkflow, Workflow{
+		Root: Statement{Try: &Try{
+			Body: &Statement{Activity: &ActivityInvocation{
+				Name:        "SampleFailingActivity",
+				RetryPolicy: &RetryPolicy{MaximumAttempts: 2},
+			}},
+			Finally: &Statement{Activity: &ActivityInvocation{Name: "SampleActivity3"}},
+		}},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), "SampleError")
+	env.AssertExpectations(t)
+}
+
+func Test_ApprovalWorkflow(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := &SampleActivities{}
+	env.RegisterWorkflow(SimpleDSLWorkflow)
+	env.RegisterActivity(activities)
+	env.OnActivity(activities.SampleActivity5, mock.Anything, "expense-1", `signal "approval" not received within 10m0s`).
+		Return("escalated", nil).Once()
+	env.OnActivity(activities.SampleActivity2, mock.Anything, "expense-1", nil).Return("paid", nil).Once()
+
+	// The first approval arrives after the timeout, so the workflow escalates before it is paid.
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("approval", map[string]interface{}{"approved": true})
+	}, 15*time.Minute)
+
+	env.ExecuteWorkflow(SimpleDSLWorkflow, loadWorkflow(t, "workflow6.yaml"))
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func Test_ChildWorkflowOutput(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SimpleDSLWorkflow)
+	env.RegisterActivity(&SampleActivities{})
+
+	env.ExecuteWorkflow(SimpleDSLWorkflow, Workflow{
+		Variables: map[string]interface{}{"n": 1},
+		Output:    "result",
+		Root: Statement{ChildWorkflow: &ChildWorkflow{
+			Inputs: map[string]string{"start": "n"},
+			Result: "result",
+			Definition: &Workflow{
+				Output: "next",
+				Root: Statement{Activity: &ActivityInvocation{
+					Name:      "IncrementActivity",
+					Arguments: []string{"start"},
+					Result:    "next",
+				}},
+			},
+		}},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var output []byte
+	require.NoError(t, env.GetWorkflowResult(&output))
+	require.Equal(t, "2", string(output))
+}
+
+func Test_ResolvePath(t *testing.T) {
+	bindings := map[string]interface{}{
+		"result1": map[string]interface{}{
+			"items": []interface{}{map[string]interface{}{"id": "first"}},
+		},
+	}
+	v, err := resolvePath("result1.items[0].id", bindings)
+	require.NoError(t, err)
+	require.Equal(t, "first", v)
+
+	_, err = resolvePath("result1.items[1].id", bindings)
+	require.ErrorContains(t, err, "out of range")
+	_, err = resolvePath("result1.items.id", bindings)
+	require.ErrorContains(t, err, "not an object")
+	_, err = resolvePath("result1.items[x]", bindings)
+	require.ErrorContains(t, err, "invalid index")
+	_, err = resolvePath("result2", bindings)
+	require.ErrorContains(t, err, "not bound")
+}
+
+func Test_EvaluateExpression(t *testing.T) {
+	bindings := map[string]interface{}{
+		"count": "2",
+		"name":  "temporal",
+		"empty": "",
+		"order": map[string]interface{}{
+			"items": []interface{}{map[string]interface{}{"id": "a", "quantity": 3.0}},
+		},
+	}
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"count == 2", true},
+		{"count < 10", true},
+		{"count >= 3", false},
+		{"name == 'temporal'", true},
+		{`name != "temporal"`, false},
+		{"!empty", true},
+		{"count > 1 && name == 'temporal'", true},
+		{"count > 5 || (name < 'z' && !false)", true},
+		{"false && missing == 1", false},
+		{"true || missing", true},
+		{"order.items[0].quantity == 3", true},
+		{"order.items[0].id != 'a'", false},
+		{"order.items", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			ok, err := evaluateCondition(tt.expr, bindings)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, ok)
+		})
+	}
+
+	_, err := evaluateCondition("missing == 1", bindings)
+	require.ErrorContains(t, err, `"missing" is not bound`)
+	_, err = evaluateCondition("count ==", bindings)
+	require.Error(t, err)
+	_, err = evaluateCondition("(count == 2", bindings)
+	require.Error(t, err)
+}
+
+func Test_ParseWorkflowReportsErrors(t *testing.T) {
+	src := `variables:
+  arg1: value1
+
+root:
+  sequence:
+    elements:
+      - activity:
+          name: UnknownActivity
+          arguments:
+            - arg1
+      - parallel:
+          branches:
+            - activity:
+                name: SampleActivity1
+                arguments:
+                  - missing
+                result: shared
+            - activity:
+                name: SampleActivity2
+                result: shared
+      - if:
+          condition: shared == 'x' && other > 1
+          then:
+            sequence:
+              elements: []
+`
+	_, err := ParseWorkflow([]byte(src), ActivityNames(&SampleActivities{}))
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	require.Equal(t, ValidationErrors{
+		{Line: 8, Message: `activity "UnknownActivity" is not registered by the worker`},
+		{Line: 16, Message: `variable "missing" is not bound`},
+		{Line: 20, Message: `variable "shared" is written by parallel branches 1 and 2`},
+		{Line: 22, Message: `variable "other" is not bound`},
+		{Line: 25, Message: `sequence has no elements`},
+	}, errs)
+}
+
+func Test_SampleWorkflowsAreValid(t *testing.T) {
+	for _, file := range []string{"workflow1.yaml", "workflow2.yaml", "workflow3.yaml", "workflow4.yaml", "workflow5.yaml", "workflow6.yaml"} {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		_, err = ParseWorkflow(data, ActivityNames(&SampleActivities{}))
+		require.NoError(t, err, file)
+	}
+}
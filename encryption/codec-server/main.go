@@ -14,9 +14,11 @@ import (
 )
 
 var portFlag int
+var keyringFlag string
 
 func init() {
 	flag.IntVar(&portFlag, "port", 8081, "Port to listen on")
+	flag.StringVar(&keyringFlag, "keyring", "", "Optional keyring file such as keyring.json")
 }
 
 func main() {
@@ -26,7 +28,15 @@ func main() {
 	// decoding for the Temporal Web UI or oauth.
 	// For a more complete example of a codec server please see the codec-server sample at:
 	// ../../codec-server.
-	handler := converter.NewPayloadCodecHTTPHandler(&encryption.Codec{}, converter.NewZlibCodec(converter.ZlibCodecOptions{AlwaysEncode: true}))
+	codec := &encryption.Codec{}
+	if keyringFlag != "" {
+		keyring, err := encryption.NewFileKeyring(keyringFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		codec.KeyProvider = keyring
+	}
+	handler := converter.NewPayloadCodecHTTPHandler(codec, converter.NewZlibCodec(converter.ZlibCodecOptions{AlwaysEncode: true}))
 
 	srv := &http.Server{
 		Addr:    "0.0.0.0:" + strconv.Itoa(portFlag),
@@ -14,8 +14,14 @@ const (
 	// MetadataEncodingEncrypted is "binary/encrypted"
 	MetadataEncodingEncrypted = "binary/encrypted"
 
+	// MetadataEncodingEnvelopeEncrypted is "binary/envelope-encrypted"
+	MetadataEncodingEnvelopeEncrypted = "binary/envelope-encrypted"
+
 	// MetadataEncryptionKeyID is "encryption-key-id"
 	MetadataEncryptionKeyID = "encryption-key-id"
+
+	// MetadataEncryptedDataKey is "encryption-data-key", the wrapped data key of an envelope encrypted payload.
+	MetadataEncryptedDataKey = "encryption-data-key"
 )
 
 type DataConverter struct {
@@ -27,13 +33,23 @@ type DataConverter struct {
 
 type DataConverterOptions struct {
 	KeyID string
+	// KeyProvider supplies the encryption keys. Defaults to a single hard coded key.
+	KeyProvider KeyProvider
+	// Envelope encrypts every payload with its own data key, see Codec.Envelope.
+	Envelope bool
 	// Enable ZLib compression before encryption.
 	Compress bool
 }
 
 // Codec implements PayloadCodec using AES Crypt.
 type Codec struct {
+	// KeyID is the key payloads are encrypted with. When empty the active key of the KeyProvider is used.
 	KeyID string
+	// KeyProvider supplies the encryption keys. Defaults to a single hard coded key.
+	KeyProvider KeyProvider
+	// Envelope encrypts every payload with a fresh data key and stores the data key, wrapped by the key from the
+	// KeyProvider, in the payload metadata. Payloads encrypted without Envelope are still decoded.
+	Envelope bool
 }
 
 // TODO: Implement workflow.ContextAware in CodecDataConverter
@@ -72,16 +88,38 @@ func (dc *DataConverter) WithContext(ctx context.Context) converter.DataConverte
 	return dc
 }
 
-func (e *Codec) getKey(keyID string) (key []byte) {
-	// Key must be fetched from secure storage in production (such as a KMS).
-	// For testing here we just hard code a key.
-	return []byte("test-key-test-key-test-key-test!")
+func (e *Codec) keyProvider() KeyProvider {
+	if e.KeyProvider == nil {
+		return staticKeyProvider{}
+	}
+	return e.KeyProvider
+}
+
+// activeKeyID returns the ID of the key to encrypt new payloads with.
+func (e *Codec) activeKeyID() (string, error) {
+	if e.KeyID != "" {
+		return e.KeyID, nil
+	}
+	return e.keyProvider().ActiveKeyID()
+}
+
+// encryptionKey returns the ID and the key to encrypt new payloads with.
+func (e *Codec) encryptionKey() (string, []byte, error) {
+	keyID, err := e.activeKeyID()
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := e.keyProvider().GetKey(keyID)
+	if err != nil {
+		return "", nil, err
+	}
+	return keyID, key, nil
 }
 
 // NewEncryptionDataConverter creates a new instance of EncryptionDataConverter wrapping a DataConverter
 func NewEncryptionDataConverter(dataConverter converter.DataConverter, options DataConverterOptions) *DataConverter {
 	codecs := []converter.PayloadCodec{
-		&Codec{KeyID: options.KeyID},
+		&Codec{KeyID: options.KeyID, KeyProvider: options.KeyProvider, Envelope: options.Envelope},
 	}
 	// Enable compression if requested.
 	// Note that this must be done before encryption to provide any value. Encrypted data should by design not compress very well.
@@ -99,6 +137,15 @@ func NewEncryptionDataConverter(dataConverter converter.DataConverter, options D
 
 // Encode implements converter.PayloadCodec.Encode.
 func (e *Codec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	if e.Envelope {
+		return e.encodeEnvelope(payloads)
+	}
+
+	keyID, key, err := e.encryptionKey()
+	if err != nil {
+		return payloads, err
+	}
+
 	result := make([]*commonpb.Payload, len(payloads))
 	for i, p := range payloads {
 		origBytes, err := p.Marshal()
@@ -106,8 +153,6 @@ func (e *Codec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error
 			return payloads, err
 		}
 
-		key := e.getKey(e.KeyID)
-
 		b, err := encrypt(origBytes, key)
 		if err != nil {
 			return payloads, err
@@ -116,7 +161,7 @@ func (e *Codec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error
 		result[i] = &commonpb.Payload{
 			Metadata: map[string][]byte{
 				converter.MetadataEncoding: []byte(MetadataEncodingEncrypted),
-				MetadataEncryptionKeyID:    []byte(e.KeyID),
+				MetadataEncryptionKeyID:    []byte(keyID),
 			},
 			Data: b,
 		}
@@ -129,6 +174,15 @@ func (e *Codec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error
 func (e *Codec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
 	result := make([]*commonpb.Payload, len(payloads))
 	for i, p := range payloads {
+		if string(p.Metadata[converter.MetadataEncoding]) == MetadataEncodingEnvelopeEncrypted {
+			decoded, err := e.decodeEnvelope(p)
+			if err != nil {
+				return payloads, err
+			}
+			result[i] = decoded
+			continue
+		}
+
 		// Only if it's encrypted
 		if string(p.Metadata[converter.MetadataEncoding]) != MetadataEncodingEncrypted {
 			result[i] = p
@@ -140,7 +194,10 @@ func (e *Codec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error
 			return payloads, fmt.Errorf("no encryption key id")
 		}
 
-		key := e.getKey(string(keyID))
+		key, err := e.keyProvider().GetKey(string(keyID))
+		if err != nil {
+			return payloads, err
+		}
 
 		b, err := decrypt(p.Data, key)
 		if err != nil {
@@ -156,3 +213,34 @@ func (e *Codec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error
 
 	return result, nil
 }
+
+// ReEncrypt decrypts every encrypted payload and encrypts it again with the active key. Payloads that are not
+// encrypted are returned unchanged. It is used to rotate keys of payloads that are stored outside of Temporal, such as
+// exported histories. Envelope encrypted payloads only get their data key wrapped again.
+func (e *Codec) ReEncrypt(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if string(p.Metadata[converter.MetadataEncoding]) == MetadataEncodingEnvelopeEncrypted {
+			rewrapped, err := e.rewrapEnvelope(p)
+			if err != nil {
+				return payloads, err
+			}
+			result[i] = rewrapped
+			continue
+		}
+		if string(p.Metadata[converter.MetadataEncoding]) != MetadataEncodingEncrypted {
+			result[i] = p
+			continue
+		}
+		decoded, err := e.Decode([]*commonpb.Payload{p})
+		if err != nil {
+			return payloads, err
+		}
+		encoded, err := e.Encode(decoded)
+		if err != nil {
+			return payloads, err
+		}
+		result[i] = encoded[0]
+	}
+	return result, nil
+}
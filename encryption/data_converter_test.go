@@ -2,10 +2,16 @@ package encryption
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
 )
 
 func Test_DataConverter(t *testing.T) {
@@ -34,3 +40,153 @@ func Test_DataConverter(t *testing.T) {
 
 	require.Equal(t, "Testing", result)
 }
+
+func writeKeyring(t *testing.T, path, activeKeyID string, keyIDs ...string) {
+	keys := map[string]string{}
+	for _, id := range keyIDs {
+		keys[id] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%-32s", id)))
+	}
+	data, err := json.Marshal(map[string]interface{}{"activeKeyId": activeKeyID, "keys": keys})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func Test_KeyRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, "key-1", "key-1")
+	keyring, err := NewFileKeyring(path)
+	require.NoError(t, err)
+	codec := &Codec{KeyProvider: keyring}
+
+	original, err := converter.GetDefaultDataConverter().ToPayloads("Testing")
+	require.NoError(t, err)
+	oldPayloads, err := codec.Encode(original.Payloads)
+	require.NoError(t, err)
+	require.Equal(t, "key-1", string(oldPayloads[0].Metadata[MetadataEncryptionKeyID]))
+
+	// Rotate to key-2, keeping key-1 so that old payloads can still be decoded.
+	writeKeyring(t, path, "key-2", "key-1", "key-2")
+	require.NoError(t, keyring.Reload())
+
+	newPayloads, err := codec.Encode(original.Payloads)
+	require.NoError(t, err)
+	require.Equal(t, "key-2", string(newPayloads[0].Metadata[MetadataEncryptionKeyID]))
+
+	decoded, err := codec.Decode(oldPayloads)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(original.Payloads[0], decoded[0]))
+
+	reEncrypted, err := codec.ReEncrypt(oldPayloads)
+	require.NoError(t, err)
+	require.Equal(t, "key-2", string(reEncrypted[0].Metadata[MetadataEncryptionKeyID]))
+
+	// Once key-1 is retired only the re-encrypted payloads can be decoded.
+	writeKeyring(t, path, "key-2", "key-2")
+	require.NoError(t, keyring.Reload())
+	_, err = codec.Decode(oldPayloads)
+	require.ErrorContains(t, err, `unknown encryption key id "key-1"`)
+	decoded, err = codec.Decode(reEncrypted)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(original.Payloads[0], decoded[0]))
+}
+
+func Test_KeyringDecodesHardCodedKeyPayloads(t *testing.T) {
+	// Payloads encrypted with the hard coded key before switching to keyring.json.
+	ctx := context.WithValue(context.Background(), PropagateKey, CryptContext{KeyID: "test"})
+	hardCodedDc := NewEncryptionDataConverter(converter.GetDefaultDataConverter(), DataConverterOptions{})
+	payloads, err := hardCodedDc.WithContext(ctx).ToPayloads("Testing")
+	require.NoError(t, err)
+	require.Equal(t, "test", string(payloads.Payloads[0].Metadata[MetadataEncryptionKeyID]))
+
+	keyring, err := NewFileKeyring("keyring.json")
+	require.NoError(t, err)
+	keyringDc := NewEncryptionDataConverter(converter.GetDefaultDataConverter(), DataConverterOptions{KeyProvider: keyring})
+	var result string
+	require.NoError(t, keyringDc.FromPayloads(payloads, &result))
+	require.Equal(t, "Testing", result)
+}
+
+func Test_KeyringRejectsMissingActiveKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, "key-2", "key-1")
+	_, err := NewFileKeyring(path)
+	require.ErrorContains(t, err, `active key "key-2" is not in the keyring`)
+}
+
+type testKeyWrapper struct {
+	staticKeyProvider
+	wrapped, unwrapped int
+}
+
+func (w *testKeyWrapper) WrapKey(keyID string, dataKey []byte) ([]byte, error) {
+	w.wrapped++
+	return append([]byte(keyID+":"), dataKey...), nil
+}
+
+func (w *testKeyWrapper) UnwrapKey(keyID string, wrappedKey []byte) ([]byte, error) {
+	w.unwrapped++
+	return wrappedKey[len(keyID)+1:], nil
+}
+
+func Test_EnvelopeEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, "key-1", "key-1")
+	keyring, err := NewFileKeyring(path)
+	require.NoError(t, err)
+	legacyCodec := &Codec{KeyProvider: keyring}
+	codec := &Codec{KeyProvider: keyring, Envelope: true}
+
+	original, err := converter.GetDefaultDataConverter().ToPayloads("Testing", "Testing")
+	require.NoError(t, err)
+	encoded, err := codec.Encode(original.Payloads)
+	require.NoError(t, err)
+	for _, p := range encoded {
+		require.Equal(t, MetadataEncodingEnvelopeEncrypted, string(p.Metadata[converter.MetadataEncoding]))
+		require.Equal(t, "key-1", string(p.Metadata[MetadataEncryptionKeyID]))
+		require.NotEmpty(t, p.Metadata[MetadataEncryptedDataKey])
+	}
+	// Every payload gets its own data key.
+	require.NotEqual(t, encoded[0].Metadata[MetadataEncryptedDataKey], encoded[1].Metadata[MetadataEncryptedDataKey])
+
+	decoded, err := codec.Decode(encoded)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(original.Payloads[0], decoded[0]))
+
+	// binary/encrypted payloads still decode with an envelope codec.
+	legacy, err := legacyCodec.Encode(original.Payloads)
+	require.NoError(t, err)
+	decoded, err = codec.Decode(legacy)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(original.Payloads[0], decoded[0]))
+
+	// Rotating only wraps the data key again; the encrypted data does not change.
+	writeKeyring(t, path, "key-2", "key-1", "key-2")
+	require.NoError(t, keyring.Reload())
+	rewrapped, err := codec.ReEncrypt(encoded)
+	require.NoError(t, err)
+	require.Equal(t, "key-2", string(rewrapped[0].Metadata[MetadataEncryptionKeyID]))
+	require.Equal(t, encoded[0].Data, rewrapped[0].Data)
+	writeKeyring(t, path, "key-2", "key-2")
+	require.NoError(t, keyring.Reload())
+	decoded, err = codec.Decode(rewrapped)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(original.Payloads[0], decoded[0]))
+}
+
+func Test_EnvelopeEncryptionWithKeyWrapper(t *testing.T) {
+	wrapper := &testKeyWrapper{}
+	dc := NewEncryptionDataConverter(converter.GetDefaultDataConverter(), DataConverterOptions{
+		KeyID:       "kms-key",
+		KeyProvider: wrapper,
+		Envelope:    true,
+		Compress:    true,
+	})
+
+	payloads, err := dc.ToPayloads("Testing")
+	require.NoError(t, err)
+	var result string
+	require.NoError(t, dc.FromPayloads(payloads, &result))
+	require.Equal(t, "Testing", result)
+	require.Equal(t, 1, wrapper.wrapped)
+	require.Equal(t, 1, wrapper.unwrapped)
+}
@@ -0,0 +1,131 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	commonpb "go.temporal.io/api/common/v1"
+
+	"go.temporal.io/sdk/converter"
+)
+
+// dataKeySize is the size of the AES-256 data key generated for every envelope encrypted payload.
+const dataKeySize = 32
+
+// KeyWrapper can be implemented by a KeyProvider whose keys never leave it, such as a KMS. Envelope encryption then
+// asks it to wrap and unwrap data keys instead of fetching the key-encryption key with GetKey.
+type KeyWrapper interface {
+	WrapKey(keyID string, dataKey []byte) ([]byte, error)
+	UnwrapKey(keyID string, wrappedKey []byte) ([]byte, error)
+}
+
+func (e *Codec) encodeEnvelope(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	keyID, err := e.activeKeyID()
+	if err != nil {
+		return payloads, err
+	}
+
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		origBytes, err := p.Marshal()
+		if err != nil {
+			return payloads, err
+		}
+
+		// A fresh data key per payload limits what a leaked data key exposes to a single payload.
+		dataKey := make([]byte, dataKeySize)
+		if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+			return payloads, err
+		}
+		b, err := encrypt(origBytes, dataKey)
+		if err != nil {
+			return payloads, err
+		}
+		wrappedKey, err := e.wrapKey(keyID, dataKey)
+		if err != nil {
+			return payloads, err
+		}
+
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				converter.MetadataEncoding: []byte(MetadataEncodingEnvelopeEncrypted),
+				MetadataEncryptionKeyID:    []byte(keyID),
+				MetadataEncryptedDataKey:   wrappedKey,
+			},
+			Data: b,
+		}
+	}
+
+	return result, nil
+}
+
+func (e *Codec) decodeEnvelope(p *commonpb.Payload) (*commonpb.Payload, error) {
+	dataKey, err := e.unwrapDataKey(p)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decrypt(p.Data, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	result := &commonpb.Payload{}
+	if err := result.Unmarshal(b); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// rewrapEnvelope wraps the data key of p with the active key. The encrypted data itself is left untouched.
+func (e *Codec) rewrapEnvelope(p *commonpb.Payload) (*commonpb.Payload, error) {
+	dataKey, err := e.unwrapDataKey(p)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := e.activeKeyID()
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := e.wrapKey(keyID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string][]byte, len(p.Metadata))
+	for k, v := range p.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataEncryptionKeyID] = []byte(keyID)
+	metadata[MetadataEncryptedDataKey] = wrappedKey
+	return &commonpb.Payload{Metadata: metadata, Data: p.Data}, nil
+}
+
+func (e *Codec) unwrapDataKey(p *commonpb.Payload) ([]byte, error) {
+	keyID, ok := p.Metadata[MetadataEncryptionKeyID]
+	if !ok {
+		return nil, fmt.Errorf("no encryption key id")
+	}
+	wrappedKey, ok := p.Metadata[MetadataEncryptedDataKey]
+	if !ok {
+		return nil, fmt.Errorf("no encrypted data key")
+	}
+	if wrapper, ok := e.keyProvider().(KeyWrapper); ok {
+		return wrapper.UnwrapKey(string(keyID), wrappedKey)
+	}
+	key, err := e.keyProvider().GetKey(string(keyID))
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(wrappedKey, key)
+}
+
+func (e *Codec) wrapKey(keyID string, dataKey []byte) ([]byte, error) {
+	if wrapper, ok := e.keyProvider().(KeyWrapper); ok {
+		return wrapper.WrapKey(keyID, dataKey)
+	}
+	key, err := e.keyProvider().GetKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(dataKey, key)
+}
@@ -0,0 +1,118 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type (
+	// KeyProvider supplies the AES keys used by Codec. Keys are looked up by the ID stored in the
+	// encryption-key-id metadata of every payload, so a provider must keep serving retired keys for as long as
+	// histories encrypted with them need to be decoded.
+	KeyProvider interface {
+		// ActiveKeyID returns the ID of the key new payloads are encrypted with.
+		ActiveKeyID() (string, error)
+		// GetKey returns the key with the given ID.
+		GetKey(keyID string) ([]byte, error)
+	}
+
+	// FileKeyring is a KeyProvider that reads its keys from a JSON file like keyring.json:
+	//
+	//	{
+	//	  "activeKeyId": "key-2",
+	//	  "keys": {
+	//	    "key-1": "<base64 encoded 32 byte key>",
+	//	    "key-2": "<base64 encoded 32 byte key>"
+	//	  }
+	//	}
+	//
+	// To rotate keys, add a new key to the file, make it the active key and call Reload. Keep the old keys in the
+	// file until no history that still needs to be decoded uses them.
+	FileKeyring struct {
+		path string
+
+		mu          sync.RWMutex
+		activeKeyID string
+		keys        map[string][]byte
+	}
+
+	keyringFile struct {
+		ActiveKeyID string            `json:"activeKeyId"`
+		Keys        map[string]string `json:"keys"`
+	}
+
+	// staticKeyProvider returns the same hard coded key for every key ID.
+	staticKeyProvider struct{}
+)
+
+// NewFileKeyring loads the keyring file at path.
+func NewFileKeyring(path string) (*FileKeyring, error) {
+	k := &FileKeyring{path: path}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload reads the keyring file again, for example after a key was added or the active key changed.
+func (k *FileKeyring) Reload() error {
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return fmt.Errorf("read keyring: %w", err)
+	}
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parse keyring %s: %w", k.path, err)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("keyring %s: key %q is not base64: %w", k.path, id, err)
+		}
+		if l := len(key); l != 16 && l != 24 && l != 32 {
+			return fmt.Errorf("keyring %s: key %q is %d bytes, AES needs 16, 24 or 32", k.path, id, l)
+		}
+		keys[id] = key
+	}
+	if _, ok := keys[file.ActiveKeyID]; !ok {
+		return fmt.Errorf("keyring %s: active key %q is not in the keyring", k.path, file.ActiveKeyID)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.activeKeyID = file.ActiveKeyID
+	k.keys = keys
+	return nil
+}
+
+// ActiveKeyID implements KeyProvider.ActiveKeyID.
+func (k *FileKeyring) ActiveKeyID() (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.activeKeyID, nil
+}
+
+// GetKey implements KeyProvider.GetKey.
+func (k *FileKeyring) GetKey(keyID string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key id %q", keyID)
+	}
+	return key, nil
+}
+
+func (staticKeyProvider) ActiveKeyID() (string, error) {
+	return "", nil
+}
+
+func (staticKeyProvider) GetKey(string) ([]byte, error) {
+	// Key must be fetched from secure storage in production (such as a KMS).
+	// For testing here we just hard code a key.
+	return []byte("test-key-test-key-test-key-test!"), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/proxy"
+	"go.temporal.io/api/temporalproto"
+	"go.temporal.io/sdk/client"
+
+	"github.com/temporalio/samples-go/encryption"
+)
+
+// Re-encrypts the payloads of an exported history with the active key of a keyring, so that the keys it was
+// encrypted with can be retired. Export a history with:
+//
+//	temporal workflow show --workflow-id encryption_workflowID --output json > history.json
+func main() {
+	var keyringPath, inPath, outPath string
+	flag.StringVar(&keyringPath, "keyring", "keyring.json", "Keyring holding the old keys and the new active key.")
+	flag.StringVar(&inPath, "in", "history.json", "Exported history to re-encrypt.")
+	flag.StringVar(&outPath, "out", "history.reencrypted.json", "Where to write the re-encrypted history.")
+	flag.Parse()
+
+	keyring, err := encryption.NewFileKeyring(keyringPath)
+	if err != nil {
+		log.Fatalln("Unable to load keyring", err)
+	}
+	codec := &encryption.Codec{KeyProvider: keyring}
+
+	in, err := os.Open(inPath)
+	if err != nil {
+		log.Fatalln("Unable to open history", err)
+	}
+	history, err := client.HistoryFromJSON(in, client.HistoryJSONOptions{})
+	_ = in.Close()
+	if err != nil {
+		log.Fatalln("Unable to read history", err)
+	}
+
+	count := 0
+	err = proxy.VisitPayloads(context.Background(), history, proxy.VisitPayloadsOptions{
+		Visitor: func(_ *proxy.VisitPayloadsContext, payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+			count += len(payloads)
+			return codec.ReEncrypt(payloads)
+		},
+	})
+	if err != nil {
+		log.Fatalln("Unable to re-encrypt history", err)
+	}
+
+	data, err := temporalproto.CustomJSONMarshalOptions{Indent: "  "}.Marshal(history)
+	if err != nil {
+		log.Fatalln("Unable to encode history", err)
+	}
+	if err := os.WriteFile(outPath, data, 0o644); err != nil {
+		log.Fatalln("Unable to write history", err)
+	}
+	activeKeyID, _ := keyring.ActiveKeyID()
+	log.Println("Re-encrypted history", "Events", len(history.Events), "Payloads", count, "KeyID", activeKeyID, "Output", outPath)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 
 	"go.temporal.io/sdk/client"
@@ -12,6 +13,21 @@ import (
 )
 
 func main() {
+	var keyringPath string
+	var envelope bool
+	flag.StringVar(&keyringPath, "keyring", "", "Optional keyring file such as keyring.json. Without it a single hard coded key is used.")
+	flag.BoolVar(&envelope, "envelope", false, "Encrypt every payload with its own data key wrapped by the key.")
+	flag.Parse()
+
+	var keyProvider encryption.KeyProvider
+	if keyringPath != "" {
+		keyring, err := encryption.NewFileKeyring(keyringPath)
+		if err != nil {
+			log.Fatalln("Unable to load keyring", err)
+		}
+		keyProvider = keyring
+	}
+
 	// The client is a heavyweight object that should be created once per process.
 	c, err := client.Dial(client.Options{
 		// If you intend to let the dataConverter to decide encryption key for all workflows
@@ -35,7 +51,7 @@ func main() {
 		// encrypted/decrypted as required.
 		DataConverter: encryption.NewEncryptionDataConverter(
 			converter.GetDefaultDataConverter(),
-			encryption.DataConverterOptions{Compress: true},
+			encryption.DataConverterOptions{KeyProvider: keyProvider, Envelope: envelope, Compress: true},
 		),
 		// Use a ContextPropagator so that the KeyID value set in the workflow context is
 		// also availble in the context for activities.
@@ -53,8 +69,11 @@ func main() {
 
 	ctx := context.Background()
 	// If you are using a ContextPropagator and varying keys per workflow you need to set
-	// the KeyID to use for this workflow in the context:
-	ctx = context.WithValue(ctx, encryption.PropagateKey, encryption.CryptContext{KeyID: "test"})
+	// the KeyID to use for this workflow in the context. With a keyring the active key is
+	// used instead so that keys can be rotated.
+	if keyProvider == nil {
+		ctx = context.WithValue(ctx, encryption.PropagateKey, encryption.CryptContext{KeyID: "test"})
+	}
 
 	// The workflow input "My Secret Friend" will be encrypted by the DataConverter before being sent to Temporal
 	we, err := c.ExecuteWorkflow(
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"github.com/temporalio/samples-go/encryption"
@@ -11,6 +12,21 @@ import (
 )
 
 func main() {
+	var keyringPath string
+	var envelope bool
+	flag.StringVar(&keyringPath, "keyring", "", "Optional keyring file such as keyring.json. Without it a single hard coded key is used.")
+	flag.BoolVar(&envelope, "envelope", false, "Encrypt every payload with its own data key wrapped by the key.")
+	flag.Parse()
+
+	var keyProvider encryption.KeyProvider
+	if keyringPath != "" {
+		keyring, err := encryption.NewFileKeyring(keyringPath)
+		if err != nil {
+			log.Fatalln("Unable to load keyring", err)
+		}
+		keyProvider = keyring
+	}
+
 	// The client and worker are heavyweight objects that should be created once per process.
 	c, err := client.Dial(client.Options{
 		// If you intend to let the dataConverter to decide encryption key for all workflows
@@ -34,7 +50,7 @@ func main() {
 		// encrypted/decrypted as required.
 		DataConverter: encryption.NewEncryptionDataConverter(
 			converter.GetDefaultDataConverter(),
-			encryption.DataConverterOptions{Compress: true},
+			encryption.DataConverterOptions{KeyProvider: keyProvider, Envelope: envelope, Compress: true},
 		),
 		// Use a ContextPropagator so that the KeyID value set in the workflow context is
 		// also availble in the context for activities.
@@ -7,16 +7,50 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"go.temporal.io/sdk/activity"
 )
 
-func CreateExpenseActivity(ctx context.Context, expenseID string) error {
+func CreateExpenseActivity(ctx context.Context, request ExpenseRequest) error {
+	if len(request.ID) == 0 {
+		return errors.New("expense id is empty")
+	}
+
+	query := url.Values{}
+	query.Set("is_api_call", "true")
+	query.Set("id", request.ID)
+	query.Set("approvers", strings.Join(request.Approvers, ","))
+	query.Set("escalation_approvers", strings.Join(request.EscalationApprovers, ","))
+	if request.Quorum > 0 {
+		query.Set("quorum", strconv.Itoa(request.Quorum))
+	}
+	resp, err := http.Get(expenseServerHostPort + "/create?" + query.Encode())
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if string(body) == "SUCCEED" {
+		activity.GetLogger(ctx).Info("Expense created.", "ExpenseID", request.ID)
+		return nil
+	}
+
+	return errors.New(string(body))
+}
+
+// EscalateExpenseActivity lets the escalation approvers of the expense vote on it.
+func EscalateExpenseActivity(ctx context.Context, expenseID string) error {
 	if len(expenseID) == 0 {
 		return errors.New("expense id is empty")
 	}
 
-	resp, err := http.Get(expenseServerHostPort + "/create?is_api_call=true&id=" + expenseID)
+	resp, err := http.Get(expenseServerHostPort + "/escalate?is_api_call=true&id=" + url.QueryEscape(expenseID))
 	if err != nil {
 		return err
 	}
@@ -27,7 +61,7 @@ func CreateExpenseActivity(ctx context.Context, expenseID string) error {
 	}
 
 	if string(body) == "SUCCEED" {
-		activity.GetLogger(ctx).Info("Expense created.", "ExpenseID", expenseID)
+		activity.GetLogger(ctx).Info("Expense escalated.", "ExpenseID", expenseID)
 		return nil
 	}
 
@@ -64,6 +98,11 @@ func WaitForDecisionActivity(ctx context.Context, expenseID string) (string, err
 	}
 
 	status := string(body)
+	if status == "APPROVED" || status == "REJECTED" {
+		// The expense was decided before the callback was registered.
+		logger.Info("Expense already decided.", "ExpenseID", expenseID, "ExpenseStatus", status)
+		return status, nil
+	}
 	if status == "SUCCEED" {
 		// register callback succeed
 		logger.Info("Successfully registered callback.", "ExpenseID", expenseID)
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/pborman/uuid"
 	"go.temporal.io/sdk/client"
@@ -26,7 +27,14 @@ func main() {
 		TaskQueue: "expense",
 	}
 
-	we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, expense.SampleExpenseWorkflow, expenseID)
+	request := expense.ExpenseRequest{
+		ID:                  expenseID,
+		Approvers:           []string{"alice", "bob", "carol"},
+		Quorum:              2,
+		EscalationTimeout:   2 * time.Minute,
+		EscalationApprovers: []string{"director"},
+	}
+	we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, expense.SampleExpenseWorkflow, request)
 	if err != nil {
 		log.Fatalln("Unable to execute workflow", err)
 	}
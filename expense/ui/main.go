@@ -2,15 +2,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"html"
+	"log"
 	"net/http"
-	"sort"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.temporal.io/sdk/client"
 )
 
 /**
- * Sample expense system that support to list expenses, create new expense, update expense state and checking expense state.
+ * Sample expense system that support to list expenses, create new expense, vote on and escalate expenses and
+ * checking expense state. Expenses are approved by a quorum of approvers, and stored with an audit trail in files
+ * so that they survive a restart.
  */
 
 type expenseState string
@@ -22,15 +31,39 @@ const (
 	completed expenseState = "COMPLETED"
 )
 
-// use memory store for this sample expense system
+// systemActor is the audit trail actor of the actions taken by the workflow.
+const systemActor = "system"
+
+// defaultApprover approves expenses created without approvers.
+const defaultApprover = "manager"
+
 var (
-	allExpense     = make(map[string]expenseState)
-	tokenMap       = make(map[string][]byte)
+	expenses       *store
 	workflowClient client.Client
 )
 
+// pendingApproval is the JSON representation of an approval returned by /pending.
+type pendingApproval struct {
+	ID         string    `json:"id"`
+	Approvers  []string  `json:"approvers"`
+	Quorum     int       `json:"quorum"`
+	Approvals  int       `json:"approvals"`
+	Rejections int       `json:"rejections"`
+	Escalated  bool      `json:"escalated"`
+	Votes      []vote    `json:"votes"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
 func main() {
+	var dataDir string
+	flag.StringVar(&dataDir, "data", "expense_data", "Directory of the approval store and the audit trail.")
+	flag.Parse()
+
 	var err error
+	expenses, err = openStore(dataDir)
+	if err != nil {
+		log.Fatalln("Unable to open store", err)
+	}
 	// The client is a heavyweight object that should be created once per process.
 	workflowClient, err = client.Dial(client.Options{
 		HostPort: client.DefaultHostPort,
@@ -43,8 +76,11 @@ func main() {
 	http.HandleFunc("/list", listHandler)
 	http.HandleFunc("/create", createHandler)
 	http.HandleFunc("/action", actionHandler)
+	http.HandleFunc("/escalate", escalateHandler)
 	http.HandleFunc("/status", statusHandler)
 	http.HandleFunc("/registerCallback", callbackHandler)
+	http.HandleFunc("/pending", pendingHandler)
+	http.HandleFunc("/audit", auditHandler)
 
 	fmt.Println("Expense system UI available at http://localhost:8099")
 	_ = http.ListenAndServe(":8099", nil)
@@ -52,122 +88,252 @@ func main() {
 
 func listHandler(w http.ResponseWriter, _ *http.Request) {
 	_, _ = fmt.Fprint(w, "<h1>SAMPLE EXPENSE SYSTEM</h1>"+"<a href=\"/list\">HOME</a>"+
-		"<h3>All expense requests:</h3><table border=1><tr><th>Expense ID</th><th>Status</th><th>Action</th>")
-	var keys []string
-	for k := range allExpense {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	for _, id := range keys {
-		state := allExpense[id]
+		"<h3>All expense requests:</h3><table border=1><tr><th>Expense ID</th><th>Status</th>"+
+		"<th>Approvals</th><th>Votes</th><th>Action</th>")
+	for _, a := range expenses.list() {
+		approvals, _ := a.count()
+		var votes []string
+		for _, v := range a.Votes {
+			decision := "rejected"
+			if v.Approved {
+				decision = "approved"
+			}
+			votes = append(votes, html.EscapeString(v.Approver)+" "+decision)
+		}
 		actionLink := ""
-		if state == created {
-			actionLink = fmt.Sprintf("<a href=\"/action?type=approve&id=%s\">"+
-				"<button style=\"background-color:#4CAF50;\">APPROVE</button></a>"+
-				"&nbsp;&nbsp;<a href=\"/action?type=reject&id=%s\">"+
-				"<button style=\"background-color:#f44336;\">REJECT</button></a>", id, id)
+		if a.State == created {
+			for _, approver := range a.eligible() {
+				if !a.canVote(approver) {
+					continue
+				}
+				query := "id=" + url.QueryEscape(a.ID) + "&approver=" + url.QueryEscape(approver)
+				actionLink += fmt.Sprintf("%s: <a href=\"/action?type=approve&%s\">"+
+					"<button style=\"background-color:#4CAF50;\">APPROVE</button></a>"+
+					"&nbsp;&nbsp;<a href=\"/action?type=reject&%s\">"+
+					"<button style=\"background-color:#f44336;\">REJECT</button></a><br>",
+					html.EscapeString(approver), query, query)
+			}
+		}
+		escalated := ""
+		if a.Escalated {
+			escalated = " (escalated)"
 		}
-		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td></tr>", id, state, actionLink)
+		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%s%s</td><td>%d/%d</td><td>%s</td><td>%s</td></tr>",
+			html.EscapeString(a.ID), a.State, escalated, approvals, a.Quorum, strings.Join(votes, "<br>"), actionLink)
 	}
 	_, _ = fmt.Fprint(w, "</table>")
 }
 
-func actionHandler(w http.ResponseWriter, r *http.Request) {
+// reply writes the result of an action, as plain text for API calls or as the expense list for the UI.
+func reply(w http.ResponseWriter, r *http.Request, err error) {
 	isAPICall := r.URL.Query().Get("is_api_call") == "true"
-	id := r.URL.Query().Get("id")
-	oldState, ok := allExpense[id]
-	if !ok {
-		_, _ = fmt.Fprint(w, "ERROR:INVALID_ID")
-		return
-	}
-	actionType := r.URL.Query().Get("type")
-	switch actionType {
-	case "approve":
-		allExpense[id] = approved
-	case "reject":
-		allExpense[id] = rejected
-	case "payment":
-		allExpense[id] = completed
-	}
-	if isAPICall {
+	switch {
+	case err != nil:
+		_, _ = fmt.Fprint(w, err.Error())
+	case isAPICall:
 		_, _ = fmt.Fprint(w, "SUCCEED")
-	} else {
+	default:
 		listHandler(w, r)
 	}
+}
+
+func actionHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	approver := r.URL.Query().Get("approver")
+	actionType := r.URL.Query().Get("type")
+
+	var oldState expenseState
+	a, err := expenses.update(id, func(a *approval) (auditEntry, error) {
+		oldState = a.State
+		switch actionType {
+		case "approve", "reject":
+			if err := a.vote(approver, actionType == "approve"); err != nil {
+				return auditEntry{}, err
+			}
+			return auditEntry{Actor: approver, Action: actionType, State: string(a.State)}, nil
+		case "payment":
+			if a.State != approved {
+				return auditEntry{}, errInvalidState
+			}
+			a.State = completed
+			return auditEntry{Actor: systemActor, Action: actionType, State: string(a.State)}, nil
+		default:
+			return auditEntry{}, fmt.Errorf("ERROR:INVALID_ACTION_TYPE")
+		}
+	})
+	reply(w, r, err)
+	if err != nil {
+		return
+	}
 
-	if oldState == created && (allExpense[id] == approved || allExpense[id] == rejected) {
+	if oldState == created && (a.State == approved || a.State == rejected) {
 		// report state change
-		notifyExpenseStateChange(id, string(allExpense[id]))
+		notifyExpenseStateChange(a)
 	}
 
-	fmt.Printf("Set state for %s from %s to %s.\n", id, oldState, allExpense[id])
+	fmt.Printf("Set state for %s from %s to %s.\n", id, oldState, a.State)
 }
 
 func createHandler(w http.ResponseWriter, r *http.Request) {
-	isAPICall := r.URL.Query().Get("is_api_call") == "true"
-	id := r.URL.Query().Get("id")
-	_, ok := allExpense[id]
-	if ok {
-		_, _ = fmt.Fprint(w, "ERROR:ID_ALREADY_EXISTS")
+	query := r.URL.Query()
+	a := approval{
+		ID:                  query.Get("id"),
+		State:               created,
+		Approvers:           splitList(query.Get("approvers")),
+		EscalationApprovers: splitList(query.Get("escalation_approvers")),
+		Quorum:              1,
+		CreatedAt:           time.Now(),
+	}
+	if a.ID == "" {
+		reply(w, r, errInvalidID)
 		return
 	}
+	if len(a.Approvers) == 0 {
+		a.Approvers = []string{defaultApprover}
+	}
+	if quorum := query.Get("quorum"); quorum != "" {
+		n, err := strconv.Atoi(quorum)
+		if err != nil || n < 1 || n > len(a.Approvers) {
+			reply(w, r, fmt.Errorf("ERROR:INVALID_QUORUM"))
+			return
+		}
+		a.Quorum = n
+	}
 
-	allExpense[id] = created
-	if isAPICall {
-		_, _ = fmt.Fprint(w, "SUCCEED")
-	} else {
-		listHandler(w, r)
+	err := expenses.create(a, systemActor)
+	reply(w, r, err)
+	if err == nil {
+		fmt.Printf("Created new expense id:%s.\n", a.ID)
+	}
+}
+
+// escalateHandler lets the escalation approvers vote on the expense, after it
+// was not decided before the deadline of the workflow.
+func escalateHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	_, err := expenses.update(id, func(a *approval) (auditEntry, error) {
+		if a.State != created {
+			return auditEntry{}, errInvalidState
+		}
+		a.Escalated = true
+		return auditEntry{Actor: systemActor, Action: "escalate", State: string(a.State)}, nil
+	})
+	reply(w, r, err)
+	if err == nil {
+		fmt.Printf("Escalated expense id:%s.\n", id)
 	}
-	fmt.Printf("Created new expense id:%s.\n", id)
 }
 
 func statusHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("id")
-	state, ok := allExpense[id]
+	a, ok := expenses.get(id)
 	if !ok {
-		_, _ = fmt.Fprint(w, "ERROR:INVALID_ID")
+		_, _ = fmt.Fprint(w, errInvalidID.Error())
 		return
 	}
 
-	_, _ = fmt.Fprint(w, state)
-	fmt.Printf("Checking status for %s: %s\n", id, state)
+	_, _ = fmt.Fprint(w, a.State)
+	fmt.Printf("Checking status for %s: %s\n", id, a.State)
 }
 
-func callbackHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Query().Get("id")
-	currState, ok := allExpense[id]
-	if !ok {
-		_, _ = fmt.Fprint(w, "ERROR:INVALID_ID")
-		return
+// pendingHandler returns the approvals waiting for a decision as JSON, only the
+// ones the approver may still vote on if the approver parameter is set.
+func pendingHandler(w http.ResponseWriter, r *http.Request) {
+	approver := r.URL.Query().Get("approver")
+	pending := []pendingApproval{}
+	for _, a := range expenses.list() {
+		if a.State != created || approver != "" && !a.canVote(approver) {
+			continue
+		}
+		approvals, rejections := a.count()
+		pending = append(pending, pendingApproval{
+			ID:         a.ID,
+			Approvers:  a.eligible(),
+			Quorum:     a.Quorum,
+			Approvals:  approvals,
+			Rejections: rejections,
+			Escalated:  a.Escalated,
+			Votes:      a.Votes,
+			CreatedAt:  a.CreatedAt,
+		})
 	}
-	if currState != created {
-		_, _ = fmt.Fprint(w, "ERROR:INVALID_STATE")
+	writeJSON(w, pending)
+}
+
+// auditHandler returns the audit trail of the expense, or of all expenses without the id parameter, as JSON.
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := expenses.audit(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if entries == nil {
+		entries = []auditEntry{}
+	}
+	writeJSON(w, entries)
+}
 
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("Failed to write response: %v\n", err)
+	}
+}
+
+func callbackHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
 	err := r.ParseForm()
 	if err != nil {
 		// Handle error here via logging and then return
 		_, _ = fmt.Fprint(w, "ERROR:INVALID_FORM_DATA")
 		return
 	}
-
 	taskToken := r.PostFormValue("task_token")
+
+	a, err := expenses.update(id, func(a *approval) (auditEntry, error) {
+		switch a.State {
+		case created:
+			a.TaskToken = []byte(taskToken)
+			return auditEntry{Actor: systemActor, Action: "register_callback", State: string(a.State)}, nil
+		case approved, rejected:
+			// Decided before the callback was registered, the activity completes with the decision right away.
+			return auditEntry{Actor: systemActor, Action: "report_decision", State: string(a.State)}, nil
+		default:
+			return auditEntry{}, errInvalidState
+		}
+	})
+	if err != nil {
+		_, _ = fmt.Fprint(w, err.Error())
+		return
+	}
+	if a.State != created {
+		_, _ = fmt.Fprint(w, a.State)
+		return
+	}
 	fmt.Printf("Registered callback for ID=%s, token=%s\n", id, taskToken)
-	tokenMap[id] = []byte(taskToken)
 	_, _ = fmt.Fprint(w, "SUCCEED")
 }
 
-func notifyExpenseStateChange(id, state string) {
-	token, ok := tokenMap[id]
-	if !ok {
-		fmt.Printf("Invalid id:%s\n", id)
+func notifyExpenseStateChange(a approval) {
+	if len(a.TaskToken) == 0 {
+		// The decision is returned when the callback gets registered.
+		fmt.Printf("No callback registered for id:%s\n", a.ID)
 		return
 	}
-	err := workflowClient.CompleteActivity(context.Background(), token, state, nil)
+	err := workflowClient.CompleteActivity(context.Background(), a.TaskToken, string(a.State), nil)
 	if err != nil {
 		fmt.Printf("Failed to complete activity with error: %+v\n", err)
 	} else {
-		fmt.Printf("Successfully complete activity: %s\n", token)
+		fmt.Printf("Successfully complete activity: %s\n", a.TaskToken)
+	}
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
+	return list
 }
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	errInvalidID    = errors.New("ERROR:INVALID_ID")
+	errIDExists     = errors.New("ERROR:ID_ALREADY_EXISTS")
+	errInvalidState = errors.New("ERROR:INVALID_STATE")
+	errNotApprover  = errors.New("ERROR:NOT_AN_APPROVER")
+	errAlreadyVoted = errors.New("ERROR:ALREADY_VOTED")
+)
+
+type (
+	// approval is an expense request waiting for, or decided by, its approvers.
+	approval struct {
+		ID    string       `json:"id"`
+		State expenseState `json:"state"`
+		// Approvers may vote on the request. Escalation adds EscalationApprovers to them.
+		Approvers           []string `json:"approvers"`
+		EscalationApprovers []string `json:"escalationApprovers,omitempty"`
+		// Quorum is the number of approvals that approve the request.
+		Quorum    int       `json:"quorum"`
+		Escalated bool      `json:"escalated"`
+		Votes     []vote    `json:"votes"`
+		CreatedAt time.Time `json:"createdAt"`
+		// TaskToken completes the WaitForDecisionActivity of the request. It is stored
+		// so that the decision still reaches the workflow after a restart of the service.
+		TaskToken []byte `json:"taskToken,omitempty"`
+	}
+
+	vote struct {
+		Approver string    `json:"approver"`
+		Approved bool      `json:"approved"`
+		Time     time.Time `json:"time"`
+	}
+
+	// auditEntry is a line of the audit trail.
+	auditEntry struct {
+		Time      time.Time `json:"time"`
+		ExpenseID string    `json:"expenseId"`
+		// Actor is the approver, or "system" for actions of the workflow.
+		Actor  string `json:"actor"`
+		Action string `json:"action"`
+		State  string `json:"state,omitempty"`
+	}
+)
+
+// eligible returns the approvers that may currently vote on the request.
+func (a *approval) eligible() []string {
+	if a.Escalated {
+		return append(append([]string(nil), a.Approvers...), a.EscalationApprovers...)
+	}
+	return a.Approvers
+}
+
+func (a *approval) canVote(approver string) bool {
+	for _, v := range a.Votes {
+		if v.Approver == approver {
+			return false
+		}
+	}
+	for _, eligible := range a.eligible() {
+		if eligible == approver {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *approval) count() (approvals, rejections int) {
+	for _, v := range a.Votes {
+		if v.Approved {
+			approvals++
+		} else {
+			rejections++
+		}
+	}
+	return approvals, rejections
+}
+
+// vote records the vote of the approver and decides the request if possible.
+func (a *approval) vote(approver string, approve bool) error {
+	if a.State != created {
+		return errInvalidState
+	}
+	if !a.canVote(approver) {
+		for _, eligible := range a.eligible() {
+			if eligible == approver {
+				return errAlreadyVoted
+			}
+		}
+		return errNotApprover
+	}
+	a.Votes = append(a.Votes, vote{Approver: approver, Approved: approve, Time: time.Now()})
+	a.decide()
+	return nil
+}
+
+// decide approves the request once Quorum approvers approved it, and rejects it
+// once too many approvers rejected it for the quorum to be reached. The
+// EscalationApprovers count towards the quorum before the request is escalated
+// too, since escalation lets them vote.
+func (a *approval) decide() {
+	approvals, rejections := a.count()
+	switch {
+	case approvals >= a.Quorum:
+		a.State = approved
+	case len(a.Approvers)+len(a.EscalationApprovers)-rejections < a.Quorum:
+		a.State = rejected
+	}
+}
+
+// store keeps the approvals in a JSON file that is rewritten on every change,
+// and appends the audit trail to a JSON lines file. It is safe for concurrent use.
+type store struct {
+	mu        sync.Mutex
+	path      string
+	auditPath string
+	approvals map[string]*approval
+}
+
+func openStore(dir string) (*store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &store{
+		path:      filepath.Join(dir, "approvals.json"),
+		auditPath: filepath.Join(dir, "audit.jsonl"),
+		approvals: make(map[string]*approval),
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.approvals); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// get returns a copy of the approval.
+func (s *store) get(id string) (approval, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.approvals[id]
+	if !ok {
+		return approval{}, false
+	}
+	return *a, true
+}
+
+// list returns copies of all approvals sorted by ID.
+func (s *store) list() []approval {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]approval, 0, len(s.approvals))
+	for _, a := range s.approvals {
+		list = append(list, *a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func (s *store) create(a approval, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.approvals[a.ID]; ok {
+		return errIDExists
+	}
+	s.approvals[a.ID] = &a
+	if err := s.persist(auditEntry{ExpenseID: a.ID, Actor: actor, Action: "create", State: string(a.State)}); err != nil {
+		delete(s.approvals, a.ID)
+		return err
+	}
+	return nil
+}
+
+// update applies fn to the approval, then saves it and writes the audit entry
+// returned by fn. The approval is left unchanged if fn fails.
+func (s *store) update(id string, fn func(a *approval) (auditEntry, error)) (approval, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.approvals[id]
+	if !ok {
+		return approval{}, errInvalidID
+	}
+	updated := *current
+	updated.Votes = append([]vote(nil), current.Votes...)
+	entry, err := fn(&updated)
+	if err != nil {
+		return approval{}, err
+	}
+	s.approvals[id] = &updated
+	entry.ExpenseID = id
+	if err := s.persist(entry); err != nil {
+		s.approvals[id] = current
+		return approval{}, err
+	}
+	return updated, nil
+}
+
+// persist writes all approvals to a temporary file that replaces the store
+// file, so that a crash never leaves a partially written store, and then
+// appends the audit entry.
+func (s *store) persist(entry auditEntry) error {
+	data, err := json.MarshalIndent(s.approvals, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	entry.Time = time.Now()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.auditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// audit returns the audit trail of the expense, or of all expenses if id is empty.
+func (s *store) audit(id string) ([]auditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.auditPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		if id == "" || entry.ExpenseID == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func castVote(approver string, approve bool) func(a *approval) (auditEntry, error) {
+	return func(a *approval) (auditEntry, error) {
+		err := a.vote(approver, approve)
+		return auditEntry{Actor: approver, Action: "vote", State: string(a.State)}, err
+	}
+}
+
+func TestStore_Quorum(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openStore(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, s.create(approval{
+		ID:                  "e1",
+		State:               created,
+		Approvers:           []string{"alice", "bob", "carol"},
+		EscalationApprovers: []string{"director"},
+		Quorum:              2,
+	}, systemActor))
+	require.ErrorIs(t, s.create(approval{ID: "e1"}, systemActor), errIDExists)
+
+	a, err := s.update("e1", castVote("alice", true))
+	require.NoError(t, err)
+	require.Equal(t, created, a.State)
+	_, err = s.update("e1", castVote("alice", true))
+	require.ErrorIs(t, err, errAlreadyVoted)
+	_, err = s.update("e1", castVote("director", true))
+	require.ErrorIs(t, err, errNotApprover)
+	a, err = s.update("e1", castVote("bob", true))
+	require.NoError(t, err)
+	require.Equal(t, approved, a.State)
+
+	// Reopening the store loads the approvals and the audit trail from the files.
+	reopened, err := openStore(dir)
+	require.NoError(t, err)
+	stored, ok := reopened.get("e1")
+	require.True(t, ok)
+	require.Equal(t, approved, stored.State)
+	require.Len(t, stored.Votes, 2)
+	entries, err := reopened.audit("e1")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "bob", entries[2].Actor)
+	require.Equal(t, string(approved), entries[2].State)
+}
+
+func TestStore_RejectAndEscalate(t *testing.T) {
+	s, err := openStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, s.create(approval{
+		ID:                  "e2",
+		State:               created,
+		Approvers:           []string{"alice", "bob"},
+		EscalationApprovers: []string{"director"},
+		Quorum:              2,
+	}, systemActor))
+
+	_, err = s.update("e2", func(a *approval) (auditEntry, error) {
+		a.Escalated = true
+		return auditEntry{Actor: systemActor, Action: "escalate"}, nil
+	})
+	require.NoError(t, err)
+	a, err := s.update("e2", castVote("alice", false))
+	require.NoError(t, err)
+	// alice rejected, but bob and the director can still reach the quorum.
+	require.Equal(t, created, a.State)
+	a, err = s.update("e2", castVote("director", false))
+	require.NoError(t, err)
+	require.Equal(t, rejected, a.State)
+}
+
+func TestStore_RejectBeforeEscalation(t *testing.T) {
+	s, err := openStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, s.create(approval{
+		ID:                  "e3",
+		State:               created,
+		Approvers:           []string{"alice", "bob"},
+		EscalationApprovers: []string{"director"},
+		Quorum:              2,
+	}, systemActor))
+
+	a, err := s.update("e3", castVote("alice", false))
+	require.NoError(t, err)
+	// bob and the director can still reach the quorum once the request is escalated.
+	require.Equal(t, created, a.State)
+	_, err = s.update("e3", func(a *approval) (auditEntry, error) {
+		a.Escalated = true
+		return auditEntry{Actor: systemActor, Action: "escalate"}, nil
+	})
+	require.NoError(t, err)
+	a, err = s.update("e3", castVote("bob", true))
+	require.NoError(t, err)
+	require.Equal(t, created, a.State)
+	a, err = s.update("e3", castVote("director", true))
+	require.NoError(t, err)
+	require.Equal(t, approved, a.State)
+}
@@ -24,6 +24,7 @@ func main() {
 	w.RegisterWorkflow(expense.SampleExpenseWorkflow)
 	w.RegisterActivity(expense.CreateExpenseActivity)
 	w.RegisterActivity(expense.WaitForDecisionActivity)
+	w.RegisterActivity(expense.EscalateExpenseActivity)
 	w.RegisterActivity(expense.PaymentActivity)
 
 	err = w.Run(worker.InterruptCh())
@@ -10,8 +10,23 @@ var (
 	expenseServerHostPort = "http://localhost:8099"
 )
 
+// ExpenseRequest is the input of SampleExpenseWorkflow.
+type ExpenseRequest struct {
+	ID string
+	// Approvers may vote on the expense. The expense system has a default approver when there are none.
+	Approvers []string
+	// Quorum is the number of approvals that approve the expense. Defaults to 1.
+	// The expense is rejected once so many approvers rejected it that the quorum cannot be reached.
+	Quorum int
+	// EscalationTimeout is how long the approvers have to decide before the expense is escalated,
+	// zero disables escalation.
+	EscalationTimeout time.Duration
+	// EscalationApprovers may vote on the expense in addition to the Approvers once it is escalated.
+	EscalationApprovers []string
+}
+
 // SampleExpenseWorkflow workflow definition
-func SampleExpenseWorkflow(ctx workflow.Context, expenseID string) (result string, err error) {
+func SampleExpenseWorkflow(ctx workflow.Context, request ExpenseRequest) (result string, err error) {
 	// step 1, create new expense report
 	ao := workflow.ActivityOptions{
 		StartToCloseTimeout: 10 * time.Second,
@@ -19,7 +34,7 @@ func SampleExpenseWorkflow(ctx workflow.Context, expenseID string) (result strin
 	ctx1 := workflow.WithActivityOptions(ctx, ao)
 	logger := workflow.GetLogger(ctx)
 
-	err = workflow.ExecuteActivity(ctx1, CreateExpenseActivity, expenseID).Get(ctx1, nil)
+	err = workflow.ExecuteActivity(ctx1, CreateExpenseActivity, request).Get(ctx1, nil)
 	if err != nil {
 		logger.Error("Failed to create expense report", "Error", err)
 		return "", err
@@ -34,7 +49,29 @@ func SampleExpenseWorkflow(ctx workflow.Context, expenseID string) (result strin
 	// complete (waiting for human to approve the request) is longer, you should set the timeout accordingly so the
 	// Temporal system will wait accordingly. Otherwise, Temporal system could mark the activity as failure by timeout.
 	var status string
-	err = workflow.ExecuteActivity(ctx2, WaitForDecisionActivity, expenseID).Get(ctx2, &status)
+	decision := workflow.ExecuteActivity(ctx2, WaitForDecisionActivity, request.ID)
+	selector := workflow.NewSelector(ctx)
+	selector.AddFuture(decision, func(f workflow.Future) {
+		err = f.Get(ctx2, &status)
+	})
+	// The escalation timer is cancelled once the decision is made.
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+	if request.EscalationTimeout > 0 {
+		selector.AddFuture(workflow.NewTimer(timerCtx, request.EscalationTimeout), func(f workflow.Future) {
+			if f.Get(timerCtx, nil) != nil {
+				return
+			}
+			logger.Info("Expense not decided in time, escalating.", "EscalationApprovers", request.EscalationApprovers)
+			if err := workflow.ExecuteActivity(ctx1, EscalateExpenseActivity, request.ID).Get(ctx1, nil); err != nil {
+				// The approvers can still decide.
+				logger.Error("Failed to escalate expense", "Error", err)
+			}
+		})
+	}
+	for status == "" && err == nil {
+		selector.Select(ctx)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -45,7 +82,7 @@ func SampleExpenseWorkflow(ctx workflow.Context, expenseID string) (result strin
 	}
 
 	// step 3, request payment to the expense
-	err = workflow.ExecuteActivity(ctx2, PaymentActivity, expenseID).Get(ctx2, nil)
+	err = workflow.ExecuteActivity(ctx2, PaymentActivity, request.ID).Get(ctx2, nil)
 	if err != nil {
 		logger.Info("Workflow completed with payment failed.", "Error", err)
 		return "", err
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -31,7 +32,7 @@ func (s *UnitTestSuite) Test_WorkflowWithMockActivities() {
 	env.OnActivity(WaitForDecisionActivity, mock.Anything, mock.Anything).Return("APPROVED", nil).Once()
 	env.OnActivity(PaymentActivity, mock.Anything, mock.Anything).Return(nil).Once()
 
-	env.ExecuteWorkflow(SampleExpenseWorkflow, "test-expense-id")
+	env.ExecuteWorkflow(SampleExpenseWorkflow, ExpenseRequest{ID: "test-expense-id"})
 
 	s.True(env.IsWorkflowCompleted())
 	s.NoError(env.GetWorkflowError())
@@ -69,7 +70,7 @@ func (s *UnitTestSuite) Test_WorkflowWithMockServer() {
 	// pointing server to test mock
 	expenseServerHostPort = server.URL
 
-	env.ExecuteWorkflow(SampleExpenseWorkflow, "test-expense-id")
+	env.ExecuteWorkflow(SampleExpenseWorkflow, ExpenseRequest{ID: "test-expense-id"})
 
 	s.True(env.IsWorkflowCompleted())
 	s.NoError(env.GetWorkflowError())
@@ -79,3 +80,52 @@ func (s *UnitTestSuite) Test_WorkflowWithMockServer() {
 	s.Equal("COMPLETED", workflowResult)
 	env.AssertExpectations(s.T())
 }
+
+func (s *UnitTestSuite) Test_WorkflowEscalation() {
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(CreateExpenseActivity)
+	env.RegisterActivity(WaitForDecisionActivity)
+	env.RegisterActivity(EscalateExpenseActivity)
+	env.RegisterActivity(PaymentActivity)
+
+	var createQuery url.Values
+	var escalatedAt time.Time
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/text")
+		switch r.URL.Path {
+		case "/create":
+			createQuery = r.URL.Query()
+		case "/registerCallback":
+			taskToken := []byte(r.PostFormValue("task_token"))
+			// simulate the expense is approved by the director after the escalation.
+			env.RegisterDelayedCallback(func() {
+				_ = env.CompleteActivity(taskToken, "APPROVED", nil)
+			}, 2*time.Hour)
+		case "/escalate":
+			escalatedAt = env.Now()
+		}
+		_, _ = io.WriteString(w, "SUCCEED")
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+	expenseServerHostPort = server.URL
+
+	start := env.Now()
+	env.ExecuteWorkflow(SampleExpenseWorkflow, ExpenseRequest{
+		ID:                  "test-expense-id",
+		Approvers:           []string{"alice", "bob"},
+		Quorum:              2,
+		EscalationTimeout:   time.Hour,
+		EscalationApprovers: []string{"director"},
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var workflowResult string
+	s.NoError(env.GetWorkflowResult(&workflowResult))
+	s.Equal("COMPLETED", workflowResult)
+	s.Equal("alice,bob", createQuery.Get("approvers"))
+	s.Equal("2", createQuery.Get("quorum"))
+	s.Equal("director", createQuery.Get("escalation_approvers"))
+	s.Equal(time.Hour, escalatedAt.Sub(start))
+}
@@ -2,9 +2,9 @@ package fileprocessing
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
-	"strings"
-	"time"
 
 	"go.temporal.io/sdk/activity"
 )
@@ -13,111 +13,231 @@ import (
  * Sample activities used by file processing sample workflow.
  */
 
+// Progress is recorded in the heartbeats of the activities streaming a file, and returned by them
+// once the file is complete. Passed back to the activity, it resumes the file where it stopped,
+// provided that the partial file is still on the host.
+type Progress struct {
+	// Local file being written
+	FileName string
+	// Bytes written to the file
+	Bytes int64
+	// Size of the complete file
+	Size int64
+}
+
 type Activities struct {
-	BlobStore *BlobStore
+	BlobStore BlobStore
 }
 
-func (a *Activities) DownloadFileActivity(ctx context.Context, fileID string) (string, error) {
+func (a *Activities) DownloadFileActivity(ctx context.Context, fileID string, checkpoint Progress) (Progress, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Downloading file...", "FileID", fileID)
-	data := a.BlobStore.downloadFile(fileID)
 
-	tmpFile, err := saveToTmpFile(data)
+	size, err := a.BlobStore.Size(ctx, fileID)
+	if err != nil {
+		logger.Error("downloadFileActivity failed to get file size.", "Error", err)
+		return Progress{}, err
+	}
+	tmpFile, progress, err := openCheckpoint(ctx, checkpoint)
+	if err != nil {
+		logger.Error("downloadFileActivity failed to open tmp file.", "Error", err)
+		return Progress{}, err
+	}
+	progress.Size = size
+	if progress.Bytes > 0 {
+		logger.Info("Resuming download.", "Offset", progress.Bytes, "Size", size)
+	}
+
+	w := &progressWriter{ctx: ctx, w: tmpFile, progress: &progress}
+	if progress.Bytes < size {
+		err = a.BlobStore.Download(ctx, fileID, progress.Bytes, w)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && progress.Bytes != size {
+		err = fmt.Errorf("downloaded %d bytes of %d", progress.Bytes, size)
+	}
 	if err != nil {
-		logger.Error("downloadFileActivity failed to save tmp file.", "Error", err)
-		return "", err
+		// The partial file is kept to resume from the last heartbeat
+		logger.Error("downloadFileActivity failed to download file.", "Error", err)
+		return progress, err
 	}
-	fileName := tmpFile.Name()
-	logger.Info("downloadFileActivity succeed.", "SavedFilePath", fileName)
-	return fileName, nil
+	logger.Info("downloadFileActivity succeed.", "SavedFilePath", progress.FileName)
+	return progress, nil
 }
 
-func (a *Activities) ProcessFileActivity(ctx context.Context, fileName string) (string, error) {
+func (a *Activities) ProcessFileActivity(ctx context.Context, fileName string, checkpoint Progress) (Progress, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("processFileActivity started.", "FileName", fileName)
 
-	defer func() { _ = os.Remove(fileName) }() // cleanup temp file
-
 	// read downloaded file
-	data, err := os.ReadFile(fileName)
+	in, err := os.Open(fileName)
 	if err != nil {
 		logger.Error("processFileActivity failed to read file.", "FileName", fileName, "Error", err)
-		return "", err
+		return Progress{}, err
+	}
+	defer func() { _ = in.Close() }()
+	info, err := in.Stat()
+	if err != nil {
+		return Progress{}, err
+	}
+
+	tmpFile, progress, err := openCheckpoint(ctx, checkpoint)
+	if err != nil {
+		logger.Error("processFileActivity failed to open tmp file.", "Error", err)
+		return Progress{}, err
+	}
+	progress.Size = info.Size()
+	if progress.Bytes > 0 {
+		logger.Info("Resuming processing.", "Offset", progress.Bytes, "Size", progress.Size)
 	}
 
 	// process the file
-	transData := transcodeData(ctx, data)
-	tmpFile, err := saveToTmpFile(transData)
+	_, err = in.Seek(progress.Bytes, io.SeekStart)
+	if err == nil {
+		err = transcodeData(in, &progressWriter{ctx: ctx, w: tmpFile, progress: &progress})
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		logger.Error("processFileActivity failed to save tmp file.", "Error", err)
-		return "", err
+		logger.Error("processFileActivity failed to process file.", "Error", err)
+		return progress, err
 	}
 
-	processedFileName := tmpFile.Name()
-	logger.Info("processFileActivity succeed.", "SavedFilePath", processedFileName)
-	return processedFileName, nil
+	_ = os.Remove(fileName) // cleanup downloaded file
+	logger.Info("processFileActivity succeed.", "SavedFilePath", progress.FileName)
+	return progress, nil
 }
 
-func (a *Activities) UploadFileActivity(ctx context.Context, fileName string) error {
+func (a *Activities) UploadFileActivity(ctx context.Context, fileName string, blobID string) error {
 	logger := activity.GetLogger(ctx)
-	logger.Info("uploadFileActivity begin.", "UploadedFileName", fileName)
+	logger.Info("uploadFileActivity begin.", "UploadedFileName", fileName, "BlobID", blobID)
 
-	defer func() { _ = os.Remove(fileName) }() // cleanup temp file
+	f, err := os.Open(fileName)
+	if err != nil {
+		logger.Error("uploadFileActivity failed to read file.", "FileName", fileName, "Error", err)
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
 
-	err := a.BlobStore.uploadFile(ctx, fileName)
+	// Uploads are not resumed, the heartbeats only report their progress
+	r := &progressReader{ctx: ctx, r: f, progress: Progress{FileName: fileName, Size: info.Size()}}
+	err = a.BlobStore.Upload(ctx, blobID, r, info.Size())
 	if err != nil {
 		logger.Error("uploadFileActivity uploading failed.", "Error", err)
 		return err
 	}
+
+	_ = os.Remove(fileName) // cleanup processed file
 	logger.Info("uploadFileActivity succeed.", "UploadedFileName", fileName)
 	return nil
 }
 
-type BlobStore struct{}
-
-func (b *BlobStore) downloadFile(fileID string) []byte {
-	// dummy downloader
-	dummyContent := "dummy content for fileID:" + fileID
-	return []byte(dummyContent)
-}
-
-func (b *BlobStore) uploadFile(ctx context.Context, filename string) error {
-	// dummy uploader
-	_, err := os.ReadFile(filename)
-	for i := 0; i < 5; i++ {
-		time.Sleep(1 * time.Second)
-		// Demonstrates that heartbeat accepts progress data.
-		// In case of a heartbeat timeout it is included into the error.
-		activity.RecordHeartbeat(ctx, i)
+// openCheckpoint opens the file of a checkpoint to continue writing it after the bytes recorded by
+// the checkpoint. The last heartbeat of a previous attempt of the activity takes precedence over
+// the given checkpoint. A new temporary file is created when there is no checkpoint or its file is
+// gone, for instance when the activity now runs on another host.
+func openCheckpoint(ctx context.Context, checkpoint Progress) (*os.File, Progress, error) {
+	if activity.HasHeartbeatDetails(ctx) {
+		var heartbeat Progress
+		if err := activity.GetHeartbeatDetails(ctx, &heartbeat); err == nil {
+			checkpoint = heartbeat
+		}
+	}
+	if checkpoint.FileName != "" {
+		f, err := os.OpenFile(checkpoint.FileName, os.O_WRONLY, 0)
+		if err == nil {
+			// The file may hold bytes written after the last heartbeat, drop them
+			info, err := f.Stat()
+			if err == nil && info.Size() >= checkpoint.Bytes {
+				if err = f.Truncate(checkpoint.Bytes); err == nil {
+					_, err = f.Seek(checkpoint.Bytes, io.SeekStart)
+				}
+				if err == nil {
+					return f, checkpoint, nil
+				}
+			}
+			_ = f.Close()
+		}
 	}
+
+	tmpFile, err := os.CreateTemp("", "temporal_sample")
 	if err != nil {
-		return err
+		return nil, Progress{}, err
 	}
-	return nil
+	return tmpFile, Progress{FileName: tmpFile.Name()}, nil
 }
 
-func transcodeData(ctx context.Context, data []byte) []byte {
-	// dummy file processor, just do upper case for the data.
-	// in real world case, you would want to avoid load entire file content into memory at once.
-	for i := 0; i < 5; i++ {
-		time.Sleep(1 * time.Second)
-		// Demonstrates that heartbeat accepts progress data.
-		// In case of a heartbeat timeout it is included into the error.
-		activity.RecordHeartbeat(ctx, i)
+// progressWriter counts the bytes written and records them with a heartbeat. Heartbeats are
+// throttled by the SDK, so recording one for every write is fine.
+type progressWriter struct {
+	ctx      context.Context
+	w        io.Writer
+	progress *Progress
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
 	}
-	return []byte(strings.ToUpper(string(data)))
+	n, err := p.w.Write(b)
+	p.progress.Bytes += int64(n)
+	activity.RecordHeartbeat(p.ctx, *p.progress)
+	return n, err
 }
 
-func saveToTmpFile(data []byte) (f *os.File, err error) {
-	tmpFile, err := os.CreateTemp("", "temporal_sample")
-	if err != nil {
-		return nil, err
+// progressReader records the position of the reader with a heartbeat. It is an io.Seeker as the
+// S3 client may read the body twice, once to sign the request.
+type progressReader struct {
+	ctx      context.Context
+	r        io.ReadSeeker
+	progress Progress
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
 	}
-	_, err = tmpFile.Write(data)
-	if err != nil {
-		_ = os.Remove(tmpFile.Name())
-		return nil, err
+	n, err := p.r.Read(b)
+	p.progress.Bytes += int64(n)
+	activity.RecordHeartbeat(p.ctx, p.progress)
+	return n, err
+}
+
+func (p *progressReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := p.r.Seek(offset, whence)
+	if err == nil {
+		p.progress.Bytes = pos
 	}
+	return pos, err
+}
 
-	return tmpFile, nil
+func transcodeData(r io.Reader, w io.Writer) error {
+	// dummy file processor, just do upper case for the data. Only ASCII letters are changed, so that
+	// an offset in the processed file is the same in the downloaded one, to resume processing.
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		for i, c := range buf[:n] {
+			if c >= 'a' && c <= 'z' {
+				buf[i] = c - 'a' + 'A'
+			}
+		}
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
 }
@@ -0,0 +1,226 @@
+package fileprocessing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// BlobStore stores the files to process and the processed files.
+type BlobStore interface {
+	// Size returns the size of a blob in bytes.
+	Size(ctx context.Context, blobID string) (int64, error)
+	// Download writes the content of a blob to w, starting at offset so that an
+	// interrupted download can be resumed.
+	Download(ctx context.Context, blobID string, offset int64, w io.Writer) error
+	// Upload stores size bytes read from r as a blob. The blob is only visible
+	// once the upload completed, so an interrupted upload starts over.
+	Upload(ctx context.Context, blobID string, r io.ReadSeeker, size int64) error
+}
+
+// LocalBlobStore is a BlobStore keeping the blobs as files of a local directory.
+type LocalBlobStore struct {
+	dir string
+}
+
+// NewLocalBlobStore creates a LocalBlobStore in dir, creating the directory if needed.
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed creating blob directory: %w", err)
+	}
+	return &LocalBlobStore{dir: dir}, nil
+}
+
+func (b *LocalBlobStore) path(blobID string) (string, error) {
+	if blobID == "" || blobID != filepath.Base(blobID) || blobID == "." || blobID == ".." {
+		return "", fmt.Errorf("invalid blob ID %q", blobID)
+	}
+	return filepath.Join(b.dir, blobID), nil
+}
+
+// Size returns the size of the file of a blob.
+func (b *LocalBlobStore) Size(ctx context.Context, blobID string) (int64, error) {
+	name, err := b.path(blobID)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Download copies the file of a blob to w, starting at offset.
+func (b *LocalBlobStore) Download(ctx context.Context, blobID string, offset int64, w io.Writer) error {
+	name, err := b.path(blobID)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Upload writes a temporary file in the directory and renames it to the file
+// of the blob once complete.
+func (b *LocalBlobStore) Upload(ctx context.Context, blobID string, r io.ReadSeeker, size int64) error {
+	name, err := b.path(blobID)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(b.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	_, err = io.CopyN(tmpFile, r, size)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), name)
+}
+
+// S3BlobStoreOptions are options for NewS3BlobStore. The defaults point at the
+// mock S3 server of the external-storage sample, see external-storage/s3-mock.
+type S3BlobStoreOptions struct {
+	// Endpoint of the S3-compatible service. Default http://localhost:5000.
+	// Set to "aws" to use the default AWS endpoints.
+	Endpoint string
+	// Bucket holding the blobs. It must exist. Default temporal-payloads.
+	Bucket string
+	// Prefix of the keys of the blobs. Default fileprocessing/.
+	Prefix string
+	// Static credentials. When empty, the default AWS credential chain is used
+	// unless the endpoint is the default one, which takes test/test.
+	AccessKey, SecretKey string
+	// Default us-east-1.
+	Region string
+}
+
+// S3BlobStore is a BlobStore keeping the blobs as objects of an S3 bucket.
+type S3BlobStore struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3BlobStore creates an S3BlobStore for the given options.
+func NewS3BlobStore(ctx context.Context, options S3BlobStoreOptions) (*S3BlobStore, error) {
+	if options.Endpoint == "" {
+		options.Endpoint = "http://localhost:5000"
+		if options.AccessKey == "" {
+			options.AccessKey, options.SecretKey = "test", "test"
+		}
+	}
+	if options.Bucket == "" {
+		options.Bucket = "temporal-payloads"
+	}
+	if options.Prefix == "" {
+		options.Prefix = "fileprocessing/"
+	}
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+	loadOptions := []func(*config.LoadOptions) error{config.WithRegion(options.Region)}
+	if options.AccessKey != "" {
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(options.AccessKey, options.SecretKey, "")))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if options.Endpoint != "aws" {
+			// Path-style addressing avoids resolving bucket.host for local servers
+			o.BaseEndpoint = aws.String(options.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3BlobStore{client: client, bucket: options.Bucket, prefix: options.Prefix}, nil
+}
+
+func (b *S3BlobStore) key(blobID string) *string {
+	return aws.String(path.Join(b.prefix, blobID))
+}
+
+// Size returns the content length of the object of a blob.
+func (b *S3BlobStore) Size(ctx context.Context, blobID string) (int64, error) {
+	head, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(b.bucket), Key: b.key(blobID)})
+	if err != nil {
+		return 0, fmt.Errorf("head object: %w", err)
+	}
+	return aws.ToInt64(head.ContentLength), nil
+}
+
+// Download streams the object of a blob to w, requesting the range starting at
+// offset.
+func (b *S3BlobStore) Download(ctx context.Context, blobID string, offset int64, w io.Writer) error {
+	input := &s3.GetObjectInput{Bucket: aws.String(b.bucket), Key: b.key(blobID)}
+	var optFns []func(*s3.Options)
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+		// Some S3-compatible servers return the checksum of the whole object
+		// with a range, which cannot be validated
+		optFns = append(optFns, func(o *s3.Options) {
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		})
+	}
+	out, err := b.client.GetObject(ctx, input, optFns...)
+	if err != nil {
+		return fmt.Errorf("get object: %w", err)
+	}
+	defer func() { _ = out.Body.Close() }()
+	if _, err := io.Copy(w, out.Body); err != nil {
+		return fmt.Errorf("read object: %w", err)
+	}
+	return nil
+}
+
+// Upload puts the object of a blob. S3 only creates the object once the
+// request completed.
+func (b *S3BlobStore) Upload(ctx context.Context, blobID string, r io.ReadSeeker, size int64) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(b.bucket),
+		Key:           b.key(blobID),
+		Body:          r,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	return nil
+}
+
+// NewBlobStore creates the BlobStore shared by the worker and starter of this
+// sample: "local" keeps the blobs in dir, "s3" in the bucket of the mock S3
+// server of the external-storage sample.
+func NewBlobStore(ctx context.Context, kind, dir string) (BlobStore, error) {
+	switch kind {
+	case "local":
+		return NewLocalBlobStore(dir)
+	case "s3":
+		return NewS3BlobStore(ctx, S3BlobStoreOptions{})
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", kind)
+	}
+}
@@ -0,0 +1,99 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func newS3BlobStore(t *testing.T) *S3BlobStore {
+	backend := s3mem.New()
+	require.NoError(t, backend.CreateBucket("fileprocessing"))
+	server := httptest.NewServer(gofakes3.New(backend).Server())
+	t.Cleanup(server.Close)
+
+	store, err := NewS3BlobStore(context.Background(), S3BlobStoreOptions{
+		Endpoint:  server.URL,
+		Bucket:    "fileprocessing",
+		AccessKey: "test",
+		SecretKey: "test",
+	})
+	require.NoError(t, err)
+	return store
+}
+
+func TestBlobStores(t *testing.T) {
+	local, err := NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	stores := map[string]BlobStore{"local": local, "s3": newS3BlobStore(t)}
+
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, store.Upload(ctx, "blob", bytes.NewReader(data), int64(len(data))))
+
+			size, err := store.Size(ctx, "blob")
+			require.NoError(t, err)
+			require.Equal(t, int64(len(data)), size)
+
+			var buf bytes.Buffer
+			require.NoError(t, store.Download(ctx, "blob", 0, &buf))
+			require.Equal(t, data, buf.Bytes())
+			buf.Reset()
+			require.NoError(t, store.Download(ctx, "blob", 12345, &buf))
+			require.Equal(t, data[12345:], buf.Bytes())
+
+			_, err = store.Size(ctx, "missing")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestActivities_ResumeFromCheckpoint(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	data := bytes.Repeat([]byte("some content\n"), 10000)
+	require.NoError(t, store.Upload(context.Background(), "file1", bytes.NewReader(data), int64(len(data))))
+
+	var testSuite testsuite.WorkflowTestSuite
+	env := testSuite.NewTestActivityEnvironment()
+	a := &Activities{BlobStore: store}
+	env.RegisterActivity(a)
+
+	// A partial download holding bytes written after the last heartbeat, which are dropped
+	partial := filepath.Join(t.TempDir(), "partial")
+	require.NoError(t, os.WriteFile(partial, append(data[:1000:1000], "garbage"...), 0o644))
+	val, err := env.ExecuteActivity(a.DownloadFileActivity, "file1", Progress{FileName: partial, Bytes: 1000})
+	require.NoError(t, err)
+	var download Progress
+	require.NoError(t, val.Get(&download))
+	require.Equal(t, Progress{FileName: partial, Bytes: int64(len(data)), Size: int64(len(data))}, download)
+	downloaded, err := os.ReadFile(partial)
+	require.NoError(t, err)
+	require.Equal(t, data, downloaded)
+
+	// The partial file of the checkpoint is gone, processing starts over
+	val, err = env.ExecuteActivity(a.ProcessFileActivity, download.FileName, Progress{FileName: "gone", Bytes: 1000})
+	require.NoError(t, err)
+	var process Progress
+	require.NoError(t, val.Get(&process))
+	require.NotEqual(t, "gone", process.FileName)
+	require.Equal(t, int64(len(data)), process.Bytes)
+	require.NoFileExists(t, download.FileName)
+
+	_, err = env.ExecuteActivity(a.UploadFileActivity, process.FileName, ProcessedBlobID("file1"))
+	require.NoError(t, err)
+	require.NoFileExists(t, process.FileName)
+	var buf bytes.Buffer
+	require.NoError(t, store.Download(context.Background(), ProcessedBlobID("file1"), 0, &buf))
+	require.Equal(t, bytes.ToUpper(data), buf.Bytes())
+}
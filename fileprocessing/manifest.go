@@ -0,0 +1,146 @@
+package fileprocessing
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// ProgressQuery is the query returning the ManifestProgress of ManifestProcessingWorkflow.
+const ProgressQuery = "progress"
+
+// Manifest is the input of ManifestProcessingWorkflow.
+type Manifest struct {
+	// IDs of the files to process. Required.
+	FileIDs []string
+	// Number of files processed at the same time, each in its own session. Default 4.
+	Concurrency int
+	// Number of attempts of a file, each on a different host when possible. Default 4.
+	MaxAttempts int
+}
+
+// FileState is the state of a file of a manifest.
+type FileState string
+
+const (
+	FilePending   FileState = "pending"
+	FileRunning   FileState = "running"
+	FileSucceeded FileState = "succeeded"
+	FileFailed    FileState = "failed"
+)
+
+// FileResult is the result of a file of a manifest.
+type FileResult struct {
+	FileID string
+	State  FileState
+	// Blob the processed file was uploaded to, once succeeded
+	ProcessedBlobID string
+	// Host of the last attempt
+	Host     string
+	Attempts int
+	// Error of the last attempt
+	Error string
+}
+
+// ManifestProgress is returned by the progress query of ManifestProcessingWorkflow.
+type ManifestProgress struct {
+	Total     int
+	Pending   int
+	Running   int
+	Succeeded int
+	Failed    int
+	Files     []FileResult
+}
+
+// ManifestProcessingWorkflow processes the files of a manifest, spreading them across the session
+// workers. Each file is processed like in SampleFileProcessingWorkflow, and retried on another host
+// when its session fails. The workflow returns the result of every file, it does not fail when some
+// of the files do.
+func ManifestProcessingWorkflow(ctx workflow.Context, manifest Manifest) ([]FileResult, error) {
+	logger := workflow.GetLogger(ctx)
+	if manifest.Concurrency <= 0 {
+		manifest.Concurrency = 4
+	}
+	if manifest.MaxAttempts <= 0 {
+		manifest.MaxAttempts = 4
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+
+	results := make([]FileResult, len(manifest.FileIDs))
+	for i, fileID := range manifest.FileIDs {
+		results[i] = FileResult{FileID: fileID, State: FilePending}
+	}
+	err := workflow.SetQueryHandler(ctx, ProgressQuery, func() (ManifestProgress, error) {
+		progress := ManifestProgress{Total: len(results), Files: results}
+		for _, result := range results {
+			switch result.State {
+			case FilePending:
+				progress.Pending++
+			case FileRunning:
+				progress.Running++
+			case FileSucceeded:
+				progress.Succeeded++
+			case FileFailed:
+				progress.Failed++
+			}
+		}
+		return progress, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every file acquires the semaphore for its whole processing, which caps the number of
+	// sessions of the workflow
+	sem := workflow.NewSemaphore(ctx, int64(manifest.Concurrency))
+	wg := workflow.NewWaitGroup(ctx)
+	for i := range results {
+		result := &results[i]
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return results, err
+		}
+		wg.Add(1)
+		workflow.Go(ctx, func(ctx workflow.Context) {
+			defer wg.Done()
+			defer sem.Release(1)
+			processManifestFile(ctx, result, manifest.MaxAttempts)
+		})
+	}
+	wg.Wait(ctx)
+
+	var failed int
+	for _, result := range results {
+		if result.State == FileFailed {
+			failed++
+		}
+	}
+	logger.Info(fmt.Sprintf("Processed %d files, %d failed.", len(results), failed))
+	return results, nil
+}
+
+// processManifestFile processes a file, excluding the hosts of the failed attempts from the next
+// ones, and updates its result.
+func processManifestFile(ctx workflow.Context, result *FileResult, maxAttempts int) {
+	logger := workflow.GetLogger(ctx)
+	var checkpoints fileCheckpoints
+	var failedHosts []string
+	for result.Attempts < maxAttempts {
+		result.Attempts++
+		result.State = FileRunning
+		host, err := processFile(ctx, result.FileID, &checkpoints, failedHosts)
+		result.Host = host
+		if err == nil {
+			result.State = FileSucceeded
+			result.ProcessedBlobID = ProcessedBlobID(result.FileID)
+			result.Error = ""
+			return
+		}
+
+		logger.Warn("File processing failed.", "FileID", result.FileID, "Host", host, "Attempt", result.Attempts, "Error", err)
+		result.Error = err.Error()
+		if host != "" {
+			failedHosts = append(failedHosts, host)
+		}
+	}
+	result.State = FileFailed
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"flag"
 	"log"
+	"os"
+	"path/filepath"
 
 	"github.com/pborman/uuid"
 	"go.temporal.io/sdk/client"
@@ -11,6 +15,36 @@ import (
 )
 
 func main() {
+	var store, dir, fileName string
+	var files, concurrency int
+	flag.StringVar(&store, "store", "local", "Blob store, one of [local, s3]")
+	flag.StringVar(&dir, "dir", filepath.Join(os.TempDir(), "fileprocessing-blobs"), "Directory of the local blob store")
+	flag.StringVar(&fileName, "file", "", "File to process, some generated content by default")
+	flag.IntVar(&files, "files", 0, "Number of files of a manifest to process, 0 processes a single file")
+	flag.IntVar(&concurrency, "concurrency", 4, "Number of files of the manifest processed at the same time")
+	flag.Parse()
+
+	blobStore, err := fileprocessing.NewBlobStore(context.Background(), store, dir)
+	if err != nil {
+		log.Fatalln("Unable to create blob store", err)
+	}
+	// Upload the files to process to the blob store
+	var fileIDs []string
+	for i := 0; i < max(files, 1); i++ {
+		fileID := uuid.New()
+		data := bytes.Repeat([]byte("dummy content for fileID:"+fileID+"\n"), 100000)
+		if fileName != "" {
+			if data, err = os.ReadFile(fileName); err != nil {
+				log.Fatalln("Unable to read file", err)
+			}
+		}
+		err = blobStore.Upload(context.Background(), fileID, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			log.Fatalln("Unable to upload file", err)
+		}
+		fileIDs = append(fileIDs, fileID)
+	}
+
 	// The client is a heavyweight object that should be created once per process.
 	c, err := client.Dial(client.Options{
 		HostPort: client.DefaultHostPort,
@@ -20,15 +54,37 @@ func main() {
 	}
 	defer c.Close()
 
-	fileID := uuid.New()
+	if files == 0 {
+		workflowOptions := client.StartWorkflowOptions{
+			ID:        "fileprocessing_" + fileIDs[0],
+			TaskQueue: "fileprocessing",
+		}
+
+		we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, fileprocessing.SampleFileProcessingWorkflow, fileIDs[0])
+		if err != nil {
+			log.Fatalln("Unable to execute workflow", err)
+		}
+		log.Println("Started workflow", "WorkflowID", we.GetID(), "RunID", we.GetRunID())
+		log.Println("The processed file will be uploaded to blob", fileprocessing.ProcessedBlobID(fileIDs[0]))
+		return
+	}
+
 	workflowOptions := client.StartWorkflowOptions{
-		ID:        "fileprocessing_" + fileID,
+		ID:        "fileprocessing_manifest_" + uuid.New(),
 		TaskQueue: "fileprocessing",
 	}
-
-	we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, fileprocessing.SampleFileProcessingWorkflow, fileID)
+	manifest := fileprocessing.Manifest{FileIDs: fileIDs, Concurrency: concurrency}
+	we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, fileprocessing.ManifestProcessingWorkflow, manifest)
 	if err != nil {
 		log.Fatalln("Unable to execute workflow", err)
 	}
 	log.Println("Started workflow", "WorkflowID", we.GetID(), "RunID", we.GetRunID())
+
+	var results []fileprocessing.FileResult
+	if err := we.Get(context.Background(), &results); err != nil {
+		log.Fatalln("Workflow failed", err)
+	}
+	for _, result := range results {
+		log.Println("File", result.FileID, "State", result.State, "Host", result.Host, "Attempts", result.Attempts, "Error", result.Error)
+	}
 }
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"os"
+	"path/filepath"
 
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
@@ -10,6 +14,18 @@ import (
 )
 
 func main() {
+	var store, dir string
+	var sessions int
+	flag.StringVar(&store, "store", "local", "Blob store, one of [local, s3]")
+	flag.StringVar(&dir, "dir", filepath.Join(os.TempDir(), "fileprocessing-blobs"), "Directory of the local blob store")
+	flag.IntVar(&sessions, "sessions", 2, "Maximum number of sessions run by this worker at the same time")
+	flag.Parse()
+
+	blobStore, err := fileprocessing.NewBlobStore(context.Background(), store, dir)
+	if err != nil {
+		log.Fatalln("Unable to create blob store", err)
+	}
+
 	// The client and worker are heavyweight objects that should be created once per process.
 	c, err := client.Dial(client.Options{
 		HostPort: client.DefaultHostPort,
@@ -21,11 +37,14 @@ func main() {
 
 	workerOptions := worker.Options{
 		EnableSessionWorker: true, // Important for a worker to participate in the session
+		// A low limit spreads the files of a manifest across the workers
+		MaxConcurrentSessionExecutionSize: sessions,
 	}
 	w := worker.New(c, "fileprocessing", workerOptions)
 
 	w.RegisterWorkflow(fileprocessing.SampleFileProcessingWorkflow)
-	w.RegisterActivity(&fileprocessing.Activities{BlobStore: &fileprocessing.BlobStore{}})
+	w.RegisterWorkflow(fileprocessing.ManifestProcessingWorkflow)
+	w.RegisterActivity(&fileprocessing.Activities{BlobStore: blobStore})
 
 	err = w.Run(worker.InterruptCh())
 	if err != nil {
@@ -1,6 +1,8 @@
 package fileprocessing
 
 import (
+	"errors"
+	"slices"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
@@ -8,25 +10,29 @@ import (
 	"go.temporal.io/sdk/workflow"
 )
 
+// activityOptions are the options of the activities that run in the session.
+var activityOptions = workflow.ActivityOptions{
+	StartToCloseTimeout: time.Minute,
+	HeartbeatTimeout:    2 * time.Second, // such a short timeout to make sample fail over very fast
+	// A heartbeat timeout means that the session worker is gone, so an activity is not retried
+	// within its session. The whole sequence is retried in a new session instead, resuming each
+	// file from the last heartbeat of the activity.
+	RetryPolicy: &temporal.RetryPolicy{
+		MaximumAttempts: 1,
+	},
+}
+
 // SampleFileProcessingWorkflow workflow definition
 func SampleFileProcessingWorkflow(ctx workflow.Context, fileName string) (err error) {
-	ao := workflow.ActivityOptions{
-		StartToCloseTimeout: time.Minute,
-		HeartbeatTimeout:    2 * time.Second, // such a short timeout to make sample fail over very fast
-		RetryPolicy: &temporal.RetryPolicy{
-			InitialInterval:    time.Second,
-			BackoffCoefficient: 2.0,
-			MaximumInterval:    time.Minute,
-		},
-	}
-	ctx = workflow.WithActivityOptions(ctx, ao)
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
 	// Retry the whole sequence from the first activity on any error
 	// to retry it on a different host. In a real application it might be reasonable to
 	// retry individual activities as well as the whole sequence discriminating between different types of errors.
 	// See the retryactivity sample for a more sophisticated retry implementation.
+	var checkpoints fileCheckpoints
 	for i := 1; i < 5; i++ {
-		err = processFile(ctx, fileName)
+		_, err = processFile(ctx, fileName, &checkpoints, nil)
 		if err == nil {
 			break
 		}
@@ -39,30 +45,75 @@ func SampleFileProcessingWorkflow(ctx workflow.Context, fileName string) (err er
 	return err
 }
 
-func processFile(ctx workflow.Context, fileName string) (err error) {
-	so := &workflow.SessionOptions{
-		CreationTimeout:  time.Minute,
-		ExecutionTimeout: time.Minute,
-	}
-	sessionCtx, err := workflow.CreateSession(ctx, so)
+// fileCheckpoints keeps the progress of the files of the activities across the attempts of processFile.
+type fileCheckpoints struct {
+	download Progress
+	process  Progress
+}
+
+// processFile runs the activities of a file in a session on a host other than the excluded ones,
+// if possible, and returns the host.
+func processFile(ctx workflow.Context, fileName string, checkpoints *fileCheckpoints, excludedHosts []string) (host string, err error) {
+	sessionCtx, err := createSession(ctx, excludedHosts)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer workflow.CompleteSession(sessionCtx)
+	host = workflow.GetSessionInfo(sessionCtx).HostName
 
-	var downloadedName string
 	var a *Activities
-	err = workflow.ExecuteActivity(sessionCtx, a.DownloadFileActivity, fileName).Get(sessionCtx, &downloadedName)
+	err = workflow.ExecuteActivity(sessionCtx, a.DownloadFileActivity, fileName, checkpoints.download).Get(sessionCtx, &checkpoints.download)
 	if err != nil {
-		return err
+		checkpoints.download = lastHeartbeat(err, checkpoints.download)
+		return host, err
 	}
 
-	var processedFileName string
-	err = workflow.ExecuteActivity(sessionCtx, a.ProcessFileActivity, downloadedName).Get(sessionCtx, &processedFileName)
+	err = workflow.ExecuteActivity(sessionCtx, a.ProcessFileActivity, checkpoints.download.FileName, checkpoints.process).Get(sessionCtx, &checkpoints.process)
 	if err != nil {
-		return err
+		checkpoints.process = lastHeartbeat(err, checkpoints.process)
+		return host, err
 	}
 
-	err = workflow.ExecuteActivity(sessionCtx, a.UploadFileActivity, processedFileName).Get(sessionCtx, nil)
-	return err
+	err = workflow.ExecuteActivity(sessionCtx, a.UploadFileActivity, checkpoints.process.FileName, ProcessedBlobID(fileName)).Get(sessionCtx, nil)
+	return host, err
+}
+
+// createSession creates a session, completing and creating it again while it lands on an excluded
+// host. After a few sessions on excluded hosts, the last one is kept since those hosts may be the
+// only ones left.
+func createSession(ctx workflow.Context, excludedHosts []string) (workflow.Context, error) {
+	so := &workflow.SessionOptions{
+		CreationTimeout:  time.Minute,
+		ExecutionTimeout: time.Minute,
+	}
+	for i := 1; ; i++ {
+		sessionCtx, err := workflow.CreateSession(ctx, so)
+		if err != nil {
+			return nil, err
+		}
+		host := workflow.GetSessionInfo(sessionCtx).HostName
+		if i >= 3 || !slices.Contains(excludedHosts, host) {
+			return sessionCtx, nil
+		}
+		workflow.GetLogger(ctx).Info("Session created on an excluded host, creating another one.", "Host", host)
+		workflow.CompleteSession(sessionCtx)
+	}
+}
+
+// ProcessedBlobID returns the ID of the blob the processed file is uploaded to.
+func ProcessedBlobID(fileID string) string {
+	return fileID + ".processed"
+}
+
+// lastHeartbeat returns the Progress recorded by the last heartbeat of an activity that timed out,
+// or checkpoint when there is none.
+func lastHeartbeat(err error, checkpoint Progress) Progress {
+	var timeoutErr *temporal.TimeoutError
+	if errors.As(err, &timeoutErr) && timeoutErr.HasLastHeartbeatDetails() {
+		var progress Progress
+		if timeoutErr.LastHeartbeatDetails(&progress) == nil {
+			return progress
+		}
+	}
+	return checkpoint
 }
@@ -1,9 +1,14 @@
 package fileprocessing
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/worker"
 
 	"github.com/stretchr/testify/suite"
@@ -26,9 +31,9 @@ func (s *UnitTestSuite) Test_SampleFileProcessingWorkflow() {
 	})
 	var a *Activities
 
-	env.OnActivity(a.DownloadFileActivity, mock.Anything, "file1").Return("file2", nil)
-	env.OnActivity(a.ProcessFileActivity, mock.Anything, "file2").Return("file3", nil)
-	env.OnActivity(a.UploadFileActivity, mock.Anything, "file3").Return(nil)
+	env.OnActivity(a.DownloadFileActivity, mock.Anything, "file1", Progress{}).Return(Progress{FileName: "file2"}, nil)
+	env.OnActivity(a.ProcessFileActivity, mock.Anything, "file2", Progress{}).Return(Progress{FileName: "file3"}, nil)
+	env.OnActivity(a.UploadFileActivity, mock.Anything, "file3", "file1.processed").Return(nil)
 
 	env.RegisterActivity(a)
 
@@ -39,3 +44,89 @@ func (s *UnitTestSuite) Test_SampleFileProcessingWorkflow() {
 
 	env.AssertExpectations(s.T())
 }
+
+func (s *UnitTestSuite) Test_SampleFileProcessingWorkflow_ResumesFromHeartbeat() {
+	env := s.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{
+		EnableSessionWorker: true, // Important for a worker to participate in the session
+	})
+	var a *Activities
+
+	// The session worker stops while processing the file, the next attempt resumes from the last heartbeat
+	checkpoint := Progress{FileName: "file3", Bytes: 100, Size: 200}
+	env.OnActivity(a.DownloadFileActivity, mock.Anything, "file1", Progress{}).Return(Progress{FileName: "file2", Bytes: 200, Size: 200}, nil).Once()
+	env.OnActivity(a.ProcessFileActivity, mock.Anything, "file2", Progress{}).Return(Progress{}, temporal.NewHeartbeatTimeoutError(checkpoint)).Once()
+	env.OnActivity(a.DownloadFileActivity, mock.Anything, "file1", Progress{FileName: "file2", Bytes: 200, Size: 200}).Return(Progress{FileName: "file2", Bytes: 200, Size: 200}, nil).Once()
+	env.OnActivity(a.ProcessFileActivity, mock.Anything, "file2", checkpoint).Return(Progress{FileName: "file3", Bytes: 200, Size: 200}, nil).Once()
+	env.OnActivity(a.UploadFileActivity, mock.Anything, "file3", "file1.processed").Return(nil).Once()
+
+	env.RegisterActivity(a)
+
+	env.ExecuteWorkflow(SampleFileProcessingWorkflow, "file1")
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) Test_ManifestProcessingWorkflow() {
+	env := s.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{
+		EnableSessionWorker: true, // Important for a worker to participate in the session
+	})
+	var a *Activities
+
+	// Keep track of the files downloaded at the same time
+	var mu sync.Mutex
+	var running, maxRunning int
+	download := func(fileID string) func(context.Context, string, Progress) (Progress, error) {
+		return func(context.Context, string, Progress) (Progress, error) {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return Progress{FileName: fileID + ".download"}, nil
+		}
+	}
+	// file2 is uploaded on the second attempt, file4 always fails
+	env.OnActivity(a.UploadFileActivity, mock.Anything, "file2.tmp", "file2.processed").Return(errors.New("upload failed")).Once()
+	env.OnActivity(a.DownloadFileActivity, mock.Anything, "file4", Progress{}).Return(Progress{}, errors.New("no such file"))
+	for _, fileID := range []string{"file1", "file2", "file3"} {
+		env.OnActivity(a.DownloadFileActivity, mock.Anything, fileID, mock.Anything).Return(download(fileID))
+		env.OnActivity(a.ProcessFileActivity, mock.Anything, fileID+".download", mock.Anything).Return(Progress{FileName: fileID + ".tmp"}, nil)
+		env.OnActivity(a.UploadFileActivity, mock.Anything, fileID+".tmp", fileID+".processed").Return(nil)
+	}
+
+	env.RegisterActivity(a)
+
+	env.ExecuteWorkflow(ManifestProcessingWorkflow, Manifest{
+		FileIDs:     []string{"file1", "file2", "file3", "file4"},
+		Concurrency: 2,
+		MaxAttempts: 3,
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var results []FileResult
+	s.NoError(env.GetWorkflowResult(&results))
+	s.Len(results, 4)
+	s.Equal(FileResult{FileID: "file1", State: FileSucceeded, ProcessedBlobID: "file1.processed", Host: results[0].Host, Attempts: 1}, results[0])
+	s.Equal(FileSucceeded, results[1].State)
+	s.Equal(2, results[1].Attempts)
+	s.Empty(results[1].Error)
+	s.Equal(FileFailed, results[3].State)
+	s.Equal(3, results[3].Attempts)
+	s.Contains(results[3].Error, "no such file")
+	s.LessOrEqual(maxRunning, 2)
+
+	val, err := env.QueryWorkflow(ProgressQuery)
+	s.NoError(err)
+	var progress ManifestProgress
+	s.NoError(val.Get(&progress))
+	s.Equal(ManifestProgress{Total: 4, Succeeded: 3, Failed: 1, Files: results}, progress)
+}
@@ -0,0 +1,123 @@
+package mutex
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// LockModeExclusive is held by a single workflow at a time.
+	LockModeExclusive LockMode = "exclusive"
+	// LockModeShared can be held by any number of workflows at the same time, as long as no workflow holds the
+	// lock exclusively.
+	LockModeShared LockMode = "shared"
+)
+
+type (
+	// LockMode is either LockModeExclusive or LockModeShared.
+	LockMode string
+
+	// LockRequest is sent to MutexWorkflow with the RequestLockSignalName signal.
+	LockRequest struct {
+		WorkflowID string
+		// Mode defaults to LockModeExclusive.
+		Mode LockMode
+		// UnlockTimeout is how long the lock is held at most before MutexWorkflow releases it. Defaults to the
+		// unlockTimeout MutexWorkflow was started with.
+		UnlockTimeout time.Duration
+		// AcquireTimeout is how long the request waits in the queue. When it expires the requester receives an
+		// empty release lock channel name. Zero waits forever.
+		AcquireTimeout time.Duration
+		// RequestedAt is set by MutexWorkflow when it receives the request.
+		RequestedAt time.Time
+	}
+
+	// LockHolder is a workflow that holds the lock.
+	LockHolder struct {
+		WorkflowID string
+		Mode       LockMode
+		// Count is the number of times the workflow acquired the lock without releasing it.
+		Count          int
+		ReleaseChannel string
+		AcquiredAt     time.Time
+		ExpiresAt      time.Time
+	}
+
+	// LockState is returned by the LockStateQueryType query and carried over when MutexWorkflow continues as new.
+	LockState struct {
+		Holders []*LockHolder
+		// Waiters are served in order.
+		Waiters []*LockRequest
+	}
+)
+
+// UnmarshalJSON also accepts the plain sender workflow ID that was sent with RequestLockSignalName before lock
+// requests had options, so workflows that are already waiting keep working.
+func (r *LockRequest) UnmarshalJSON(data []byte) error {
+	var workflowID string
+	if err := json.Unmarshal(data, &workflowID); err == nil {
+		*r = LockRequest{WorkflowID: workflowID}
+		return nil
+	}
+	type lockRequest LockRequest
+	return json.Unmarshal(data, (*lockRequest)(r))
+}
+
+// acquireDeadline returns when the request stops waiting for the lock.
+func (r *LockRequest) acquireDeadline() (time.Time, bool) {
+	if r.AcquireTimeout <= 0 {
+		return time.Time{}, false
+	}
+	return r.RequestedAt.Add(r.AcquireTimeout), true
+}
+
+// holder returns the holder with the given workflow ID, or nil.
+func (s *LockState) holder(workflowID string) *LockHolder {
+	for _, h := range s.Holders {
+		if h.WorkflowID == workflowID {
+			return h
+		}
+	}
+	return nil
+}
+
+// canAcquire reports whether a new holder can acquire the lock in the given mode.
+func (s *LockState) canAcquire(mode LockMode) bool {
+	if len(s.Holders) == 0 {
+		return true
+	}
+	if mode != LockModeShared {
+		return false
+	}
+	for _, h := range s.Holders {
+		if h.Mode != LockModeShared {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *LockState) removeHolder(holder *LockHolder) {
+	for i, h := range s.Holders {
+		if h == holder {
+			s.Holders = append(s.Holders[:i], s.Holders[i+1:]...)
+			return
+		}
+	}
+}
+
+// nextDeadline returns the earliest time a holder expires or a waiter stops waiting.
+func (s *LockState) nextDeadline() (time.Time, bool) {
+	var next time.Time
+	for _, h := range s.Holders {
+		if next.IsZero() || h.ExpiresAt.Before(next) {
+			next = h.ExpiresAt
+		}
+	}
+	for _, r := range s.Waiters {
+		if deadline, ok := r.acquireDeadline(); ok && (next.IsZero() || deadline.Before(next)) {
+			next = deadline
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -2,12 +2,16 @@ package mutex
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
 	"go.temporal.io/sdk/workflow"
@@ -18,10 +22,23 @@ const (
 	AcquireLockSignalName = "acquire-lock-event"
 	// RequestLockSignalName channel name for request lock
 	RequestLockSignalName = "request-lock-event"
+	// LockStateQueryType returns the LockState of a MutexWorkflow
+	LockStateQueryType = "lock-state"
 
 	ClientContextKey ContextKey = "Client"
 )
 
+const (
+	// continueAsNewHistoryLength is the history length at which MutexWorkflow continues as new.
+	continueAsNewHistoryLength = 10000
+	// waiterCleanupInterval is how often MutexWorkflow checks whether waiting workflows have closed.
+	waiterCleanupInterval = time.Minute
+)
+
+// ErrLockNotAcquired is returned by Lock and RLock when the mutex workflow refuses the request, which happens when
+// a workflow that holds a shared lock asks for an exclusive one.
+var ErrLockNotAcquired = errors.New("lock not acquired")
+
 type (
 	ContextKey string
 
@@ -41,44 +58,100 @@ func NewMutex(currentWorkflowID string, lockNamespace string) *Mutex {
 	}
 }
 
-// Lock - locks mutex
+// Lock - locks mutex exclusively. A workflow that already holds the lock acquires it again, and has to call every
+// UnlockFunc it got to release it.
 func (s *Mutex) Lock(ctx workflow.Context,
 	resourceID string, unlockTimeout time.Duration) (UnlockFunc, error) {
+	return s.lock(ctx, resourceID, LockRequest{Mode: LockModeExclusive, UnlockTimeout: unlockTimeout})
+}
 
-	activityCtx := workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
-		ScheduleToCloseTimeout: time.Minute,
-		RetryPolicy: &temporal.RetryPolicy{
-			InitialInterval:    time.Second,
-			BackoffCoefficient: 2.0,
-			MaximumInterval:    time.Minute,
-			MaximumAttempts:    5,
-		},
+// RLock - locks mutex shared with other RLock callers. Once a Lock caller waits, later RLock callers wait behind it.
+func (s *Mutex) RLock(ctx workflow.Context,
+	resourceID string, unlockTimeout time.Duration) (UnlockFunc, error) {
+	return s.lock(ctx, resourceID, LockRequest{Mode: LockModeShared, UnlockTimeout: unlockTimeout})
+}
+
+// TryLock - like Lock, but gives up when the lock cannot be acquired within acquireTimeout, in which case it returns
+// false.
+func (s *Mutex) TryLock(ctx workflow.Context,
+	resourceID string, unlockTimeout, acquireTimeout time.Duration) (UnlockFunc, bool, error) {
+	return s.tryLock(ctx, resourceID, LockRequest{
+		Mode:           LockModeExclusive,
+		UnlockTimeout:  unlockTimeout,
+		AcquireTimeout: acquireTimeout,
 	})
+}
+
+// TryRLock - like RLock, but gives up when the lock cannot be acquired within acquireTimeout, in which case it
+// returns false.
+func (s *Mutex) TryRLock(ctx workflow.Context,
+	resourceID string, unlockTimeout, acquireTimeout time.Duration) (UnlockFunc, bool, error) {
+	return s.tryLock(ctx, resourceID, LockRequest{
+		Mode:           LockModeShared,
+		UnlockTimeout:  unlockTimeout,
+		AcquireTimeout: acquireTimeout,
+	})
+}
+
+func (s *Mutex) tryLock(ctx workflow.Context, resourceID string, request LockRequest) (UnlockFunc, bool, error) {
+	unlockFunc, err := s.lock(ctx, resourceID, request)
+	if errors.Is(err, ErrLockNotAcquired) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return unlockFunc, true, nil
+}
+
+func (s *Mutex) lock(ctx workflow.Context, resourceID string, request LockRequest) (UnlockFunc, error) {
+	request.WorkflowID = s.currentWorkflowID
 
 	var releaseLockChannelName string
 	var execution workflow.Execution
-	err := workflow.ExecuteLocalActivity(activityCtx,
+	err := workflow.ExecuteLocalActivity(withLocalActivityOptions(ctx),
 		SignalWithStartMutexWorkflowActivity, s.lockNamespace,
-		resourceID, s.currentWorkflowID, unlockTimeout).Get(ctx, &execution)
+		resourceID, request).Get(ctx, &execution)
 	if err != nil {
 		return nil, err
 	}
 	workflow.GetSignalChannel(ctx, AcquireLockSignalName).
 		Receive(ctx, &releaseLockChannelName)
+	// The mutex workflow sends an empty channel name when the request timed out or was refused.
+	if releaseLockChannelName == "" {
+		return nil, ErrLockNotAcquired
+	}
 
 	unlockFunc := func() error {
-		return workflow.SignalExternalWorkflow(ctx, execution.ID, execution.RunID,
+		// An empty run ID targets the current run, which changes when MutexWorkflow continues as new.
+		return workflow.SignalExternalWorkflow(ctx, execution.ID, "",
 			releaseLockChannelName, "releaseLock").Get(ctx, nil)
 	}
 	return unlockFunc, nil
 }
 
-// MutexWorkflow used for locking a resource
+func withLocalActivityOptions(ctx workflow.Context) workflow.Context {
+	return workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+		ScheduleToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	})
+}
+
+// MutexWorkflow used for locking a resource. Lock requests are served in the order they arrive: shared requests at
+// the head of the queue are granted together, an exclusive request waits until all holders have released the lock.
+// A holder that does not release the lock within its unlock timeout loses it. The workflow completes once the lock
+// is free and nobody waits, and continues as new with its state when the history grows large.
 func MutexWorkflow(
 	ctx workflow.Context,
 	namespace string,
 	resourceID string,
 	unlockTimeout time.Duration,
+	state *LockState,
 ) error {
 	currentWorkflowID := workflow.GetInfo(ctx).WorkflowExecution.ID
 	if currentWorkflowID == "default-test-workflow-id" {
@@ -87,42 +160,250 @@ func MutexWorkflow(
 	}
 	logger := workflow.GetLogger(ctx)
 	logger.Info("started", "currentWorkflowID", currentWorkflowID)
-	var ack string
+	if state == nil {
+		state = &LockState{}
+	}
+	err := workflow.SetQueryHandler(ctx, LockStateQueryType, func() (*LockState, error) {
+		return state, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m := &lockManager{ctx: ctx, logger: logger, state: state, unlockTimeout: unlockTimeout}
+	if len(state.Waiters) > 0 {
+		m.nextCleanup = workflow.Now(ctx).Add(waiterCleanupInterval)
+	}
 	requestLockCh := workflow.GetSignalChannel(ctx, RequestLockSignalName)
 	for {
-		var senderWorkflowID string
-		if !requestLockCh.ReceiveAsync(&senderWorkflowID) {
+		m.receiveRequests(requestLockCh)
+		m.expire()
+		if !m.nextCleanup.IsZero() && !workflow.Now(ctx).Before(m.nextCleanup) {
+			m.removeClosedWaiters()
+		}
+		m.grantWaiters()
+		if len(state.Holders) == 0 && len(state.Waiters) == 0 {
 			logger.Info("no more signals")
-			break
+			return nil
+		}
+
+		info := workflow.GetInfo(ctx)
+		if info.GetContinueAsNewSuggested() || info.GetCurrentHistoryLength() >= continueAsNewHistoryLength {
+			// Signals that were not received yet would be lost.
+			m.receiveReleases()
+			m.receiveRequests(requestLockCh)
+			logger.Info("continuing as new", "holders", len(state.Holders), "waiters", len(state.Waiters))
+			return workflow.NewContinueAsNewError(ctx, MutexWorkflow, namespace, resourceID, unlockTimeout, state)
+		}
+		m.wait(requestLockCh)
+	}
+}
+
+// lockManager holds the state of a running MutexWorkflow.
+type lockManager struct {
+	ctx           workflow.Context
+	logger        log.Logger
+	state         *LockState
+	unlockTimeout time.Duration
+	// nextCleanup is when to check whether waiting workflows have closed, zero while nobody waits.
+	nextCleanup time.Time
+}
+
+// wait blocks until a lock request or release arrives, or a holder or waiter times out.
+func (m *lockManager) wait(requestLockCh workflow.ReceiveChannel) {
+	timerCtx, cancelTimer := workflow.WithCancel(m.ctx)
+	defer cancelTimer()
+
+	selector := workflow.NewSelector(m.ctx)
+	selector.AddReceive(requestLockCh, func(c workflow.ReceiveChannel, more bool) {
+		m.receiveRequests(c)
+	})
+	for _, holder := range m.state.Holders {
+		holder := holder
+		selector.AddReceive(workflow.GetSignalChannel(m.ctx, holder.ReleaseChannel), func(c workflow.ReceiveChannel, more bool) {
+			var ack string
+			c.Receive(m.ctx, &ack)
+			m.release(holder)
+		})
+	}
+	next, ok := m.state.nextDeadline()
+	if !m.nextCleanup.IsZero() && (!ok || m.nextCleanup.Before(next)) {
+		next, ok = m.nextCleanup, true
+	}
+	if ok {
+		selector.AddFuture(workflow.NewTimer(timerCtx, next.Sub(workflow.Now(m.ctx))), func(f workflow.Future) {})
+	}
+	selector.Select(m.ctx)
+}
+
+// receiveRequests handles all lock requests that have arrived.
+func (m *lockManager) receiveRequests(requestLockCh workflow.ReceiveChannel) {
+	for {
+		var request LockRequest
+		if !requestLockCh.ReceiveAsync(&request) {
+			return
 		}
+		m.logger.Info("lock requested", "senderWorkflowID", request.WorkflowID, "mode", request.Mode)
+		if request.Mode == "" {
+			request.Mode = LockModeExclusive
+		}
+		if request.UnlockTimeout <= 0 {
+			request.UnlockTimeout = m.unlockTimeout
+		}
+		request.RequestedAt = workflow.Now(m.ctx)
+		if holder := m.state.holder(request.WorkflowID); holder != nil {
+			m.reacquire(holder, &request)
+			continue
+		}
+		m.state.Waiters = append(m.state.Waiters, &request)
+		if m.nextCleanup.IsZero() {
+			m.nextCleanup = workflow.Now(m.ctx).Add(waiterCleanupInterval)
+		}
+	}
+}
+
+// receiveReleases handles the releases that have arrived without blocking.
+func (m *lockManager) receiveReleases() {
+	for _, holder := range append([]*LockHolder(nil), m.state.Holders...) {
+		var ack string
+		for holder.Count > 0 && workflow.GetSignalChannel(m.ctx, holder.ReleaseChannel).ReceiveAsync(&ack) {
+			m.release(holder)
+		}
+	}
+}
+
+// grantWaiters grants the lock to the waiters at the head of the queue for as long as the lock allows it.
+func (m *lockManager) grantWaiters() {
+	for len(m.state.Waiters) > 0 {
+		request := m.state.Waiters[0]
+		holder := m.state.holder(request.WorkflowID)
+		if holder == nil && !m.state.canAcquire(request.Mode) {
+			return
+		}
+		m.removeWaiter(request)
+		if holder != nil {
+			m.reacquire(holder, request)
+			continue
+		}
+
 		var releaseLockChannelName string
-		_ = workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
-			return generateUnlockChannelName(senderWorkflowID)
+		_ = workflow.SideEffect(m.ctx, func(ctx workflow.Context) interface{} {
+			return generateUnlockChannelName(request.WorkflowID)
 		}).Get(&releaseLockChannelName)
-		logger.Info("generated release lock channel name", "releaseLockChannelName", releaseLockChannelName)
+		m.logger.Info("generated release lock channel name", "releaseLockChannelName", releaseLockChannelName)
 		// Send release lock channel name back to a senderWorkflowID, so that it can
 		// release the lock using release lock channel name
-		err := workflow.SignalExternalWorkflow(ctx, senderWorkflowID, "",
-			AcquireLockSignalName, releaseLockChannelName).Get(ctx, nil)
-		if err != nil {
-			// .Get(ctx, nil) blocks until the signal is sent.
-			// If the senderWorkflowID is closed (terminated/canceled/timeouted/completed/etc), this would return error.
-			// In this case we release the lock immediately instead of failing the mutex workflow.
-			// Mutex workflow failing would lead to all workflows that have sent requestLock will be waiting.
-			logger.Info("SignalExternalWorkflow error", "Error", err)
+		if err := m.signal(request.WorkflowID, releaseLockChannelName); err != nil {
 			continue
 		}
-		logger.Info("signaled external workflow")
-		selector := workflow.NewSelector(ctx)
-		selector.AddFuture(workflow.NewTimer(ctx, unlockTimeout), func(f workflow.Future) {
-			logger.Info("unlockTimeout exceeded")
-		})
-		selector.AddReceive(workflow.GetSignalChannel(ctx, releaseLockChannelName), func(c workflow.ReceiveChannel, more bool) {
-			c.Receive(ctx, &ack)
-			logger.Info("release signal received")
+		now := workflow.Now(m.ctx)
+		m.state.Holders = append(m.state.Holders, &LockHolder{
+			WorkflowID:     request.WorkflowID,
+			Mode:           request.Mode,
+			Count:          1,
+			ReleaseChannel: releaseLockChannelName,
+			AcquiredAt:     now,
+			ExpiresAt:      now.Add(request.UnlockTimeout),
 		})
-		selector.Select(ctx)
 	}
+}
+
+// reacquire grants the lock again to a workflow that already holds it. Upgrading a shared lock to an exclusive one
+// is refused because two workflows doing it at the same time would wait for each other forever.
+func (m *lockManager) reacquire(holder *LockHolder, request *LockRequest) {
+	if holder.Mode == LockModeShared && request.Mode == LockModeExclusive {
+		m.logger.Info("lock upgrade refused", "senderWorkflowID", request.WorkflowID)
+		_ = m.signal(request.WorkflowID, "")
+		return
+	}
+	if err := m.signal(request.WorkflowID, holder.ReleaseChannel); err != nil {
+		m.state.removeHolder(holder)
+		return
+	}
+	holder.Count++
+	if expiresAt := request.RequestedAt.Add(request.UnlockTimeout); expiresAt.After(holder.ExpiresAt) {
+		holder.ExpiresAt = expiresAt
+	}
+}
+
+func (m *lockManager) release(holder *LockHolder) {
+	m.logger.Info("release signal received", "holderWorkflowID", holder.WorkflowID)
+	holder.Count--
+	if holder.Count <= 0 {
+		m.state.removeHolder(holder)
+	}
+}
+
+// expire releases the locks whose unlock timeout has passed and gives up the requests whose acquire timeout has
+// passed.
+func (m *lockManager) expire() {
+	now := workflow.Now(m.ctx)
+	for _, holder := range append([]*LockHolder(nil), m.state.Holders...) {
+		if !holder.ExpiresAt.After(now) {
+			m.logger.Info("unlockTimeout exceeded", "holderWorkflowID", holder.WorkflowID)
+			m.state.removeHolder(holder)
+		}
+	}
+	for _, request := range append([]*LockRequest(nil), m.state.Waiters...) {
+		if deadline, ok := request.acquireDeadline(); ok && !deadline.After(now) {
+			m.logger.Info("acquireTimeout exceeded", "senderWorkflowID", request.WorkflowID)
+			m.removeWaiter(request)
+			_ = m.signal(request.WorkflowID, "")
+		}
+	}
+}
+
+// removeClosedWaiters removes the waiters whose workflows have closed, so the lock is not granted to them.
+func (m *lockManager) removeClosedWaiters() {
+	m.nextCleanup = time.Time{}
+	workflowIDs := make([]string, len(m.state.Waiters))
+	for i, request := range m.state.Waiters {
+		workflowIDs[i] = request.WorkflowID
+	}
+	var closed []string
+	err := workflow.ExecuteLocalActivity(withLocalActivityOptions(m.ctx),
+		FindClosedWorkflowsActivity, workflowIDs).Get(m.ctx, &closed)
+	if err != nil {
+		m.logger.Info("FindClosedWorkflowsActivity error", "Error", err)
+	}
+	for _, workflowID := range closed {
+		for _, request := range append([]*LockRequest(nil), m.state.Waiters...) {
+			if request.WorkflowID == workflowID {
+				m.logger.Info("removed closed waiter", "senderWorkflowID", workflowID)
+				m.removeWaiter(request)
+			}
+		}
+	}
+	if len(m.state.Waiters) > 0 {
+		m.nextCleanup = workflow.Now(m.ctx).Add(waiterCleanupInterval)
+	}
+}
+
+func (m *lockManager) removeWaiter(request *LockRequest) {
+	for i, r := range m.state.Waiters {
+		if r == request {
+			m.state.Waiters = append(m.state.Waiters[:i], m.state.Waiters[i+1:]...)
+			break
+		}
+	}
+	if len(m.state.Waiters) == 0 {
+		m.nextCleanup = time.Time{}
+	}
+}
+
+// signal sends the release lock channel name to a requester, or an empty name if it did not get the lock.
+func (m *lockManager) signal(workflowID, releaseLockChannelName string) error {
+	err := workflow.SignalExternalWorkflow(m.ctx, workflowID, "",
+		AcquireLockSignalName, releaseLockChannelName).Get(m.ctx, nil)
+	if err != nil {
+		// .Get(ctx, nil) blocks until the signal is sent.
+		// If the senderWorkflowID is closed (terminated/canceled/timeouted/completed/etc), this would return error.
+		// In this case we release the lock immediately instead of failing the mutex workflow.
+		// Mutex workflow failing would lead to all workflows that have sent requestLock will be waiting.
+		m.logger.Info("SignalExternalWorkflow error", "Error", err)
+		return err
+	}
+	m.logger.Info("signaled external workflow", "senderWorkflowID", workflowID)
 	return nil
 }
 
@@ -131,8 +412,7 @@ func SignalWithStartMutexWorkflowActivity(
 	ctx context.Context,
 	namespace string,
 	resourceID string,
-	senderWorkflowID string,
-	unlockTimeout time.Duration,
+	request LockRequest,
 ) (*workflow.Execution, error) {
 
 	c := ctx.Value(ClientContextKey).(client.Client)
@@ -153,8 +433,8 @@ func SignalWithStartMutexWorkflowActivity(
 		},
 	}
 	wr, err := c.SignalWithStartWorkflow(
-		ctx, workflowID, RequestLockSignalName, senderWorkflowID,
-		workflowOptions, MutexWorkflow, namespace, resourceID, unlockTimeout)
+		ctx, workflowID, RequestLockSignalName, request,
+		workflowOptions, MutexWorkflow, namespace, resourceID, request.UnlockTimeout, (*LockState)(nil))
 
 	if err != nil {
 		activity.GetLogger(ctx).Error("Unable to signal with start workflow", "Error", err)
@@ -168,6 +448,27 @@ func SignalWithStartMutexWorkflowActivity(
 	}, nil
 }
 
+// FindClosedWorkflowsActivity returns the workflow IDs whose workflows are no longer running.
+func FindClosedWorkflowsActivity(ctx context.Context, workflowIDs []string) ([]string, error) {
+	c := ctx.Value(ClientContextKey).(client.Client)
+	var closed []string
+	for _, workflowID := range workflowIDs {
+		resp, err := c.DescribeWorkflowExecution(ctx, workflowID, "")
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			closed = append(closed, workflowID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.GetWorkflowExecutionInfo().GetStatus() != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+			closed = append(closed, workflowID)
+		}
+	}
+	return closed, nil
+}
+
 // generateUnlockChannelName generates release lock channel name
 func generateUnlockChannelName(senderWorkflowID string) string {
 	return fmt.Sprintf("unlock-event-%s", senderWorkflowID)
@@ -177,13 +478,13 @@ func generateUnlockChannelName(senderWorkflowID string) string {
 func MockMutexLock(env *testsuite.TestWorkflowEnvironment, resourceID string, mockError error) {
 	execution := &workflow.Execution{ID: "mockID", RunID: "mockRunID"}
 	env.OnActivity(SignalWithStartMutexWorkflowActivity,
-		mock.Anything, mock.Anything, resourceID, mock.Anything, mock.Anything).
+		mock.Anything, mock.Anything, resourceID, mock.Anything).
 		Return(execution, mockError)
 	env.RegisterDelayedCallback(func() {
 		env.SignalWorkflow(AcquireLockSignalName, "mockReleaseLockChannelName")
 	}, time.Millisecond*0)
 	if mockError == nil {
-		env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, execution.RunID,
+		env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, "",
 			mock.Anything, mock.Anything).Return(nil)
 	}
 }
@@ -8,9 +8,11 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.temporal.io/sdk/converter"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
 	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 )
 
 type UnitTestSuite struct {
@@ -75,6 +77,7 @@ func (s *UnitTestSuite) Test_MutexWorkflow_Success() {
 		mockNamespace,
 		mockResourceID,
 		mockUnlockTimeout,
+		(*LockState)(nil),
 	)
 
 	s.True(s.env.IsWorkflowCompleted())
@@ -97,8 +100,232 @@ func (s *UnitTestSuite) Test_MutexWorkflow_TimeoutSuccess() {
 		mockNamespace,
 		mockResourceID,
 		mockUnlockTimeout,
+		(*LockState)(nil),
 	)
 
 	s.True(s.env.IsWorkflowCompleted())
 	s.NoError(s.env.GetWorkflowError())
 }
+
+func (s *UnitTestSuite) Test_MutexWorkflow_Reentrant() {
+	mockSenderWorkflowID := "mockSenderWorkflowID"
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: mockSenderWorkflowID})
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: mockSenderWorkflowID})
+	}, time.Millisecond*0)
+	s.env.RegisterDelayedCallback(func() {
+		s.Equal(2, s.queryLockState().Holders[0].Count)
+		s.env.SignalWorkflow("unlock-event-mockSenderWorkflowID", "releaseLock")
+	}, time.Second)
+	s.env.RegisterDelayedCallback(func() {
+		state := s.queryLockState()
+		s.Len(state.Holders, 1)
+		s.Equal(1, state.Holders[0].Count)
+		s.env.SignalWorkflow("unlock-event-mockSenderWorkflowID", "releaseLock")
+	}, time.Second*2)
+	s.env.OnSignalExternalWorkflow(mock.Anything, mockSenderWorkflowID, "",
+		AcquireLockSignalName, "unlock-event-mockSenderWorkflowID").Return(nil).Twice()
+
+	s.env.ExecuteWorkflow(MutexWorkflow, "mockNamespace", "mockResourceID", 10*time.Minute, (*LockState)(nil))
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) Test_MutexWorkflow_SharedLocks() {
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "reader1", Mode: LockModeShared})
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "writer"})
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "reader2", Mode: LockModeShared})
+	}, time.Millisecond*0)
+	s.env.RegisterDelayedCallback(func() {
+		// reader2 waits behind the writer.
+		state := s.queryLockState()
+		s.Len(state.Holders, 1)
+		s.Equal("reader1", state.Holders[0].WorkflowID)
+		s.Len(state.Waiters, 2)
+		s.Equal("writer", state.Waiters[0].WorkflowID)
+		s.env.SignalWorkflow("unlock-event-reader1", "releaseLock")
+	}, time.Second)
+	s.env.RegisterDelayedCallback(func() {
+		state := s.queryLockState()
+		s.Len(state.Holders, 1)
+		s.Equal(LockModeExclusive, state.Holders[0].Mode)
+		s.env.SignalWorkflow("unlock-event-writer", "releaseLock")
+	}, time.Second*2)
+	s.env.RegisterDelayedCallback(func() {
+		state := s.queryLockState()
+		s.Len(state.Holders, 1)
+		s.Equal("reader2", state.Holders[0].WorkflowID)
+		s.Empty(state.Waiters)
+		s.env.SignalWorkflow("unlock-event-reader2", "releaseLock")
+	}, time.Second*3)
+	s.env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, "",
+		AcquireLockSignalName, mock.Anything).Return(nil).Times(3)
+
+	s.env.ExecuteWorkflow(MutexWorkflow, "mockNamespace", "mockResourceID", 10*time.Minute, (*LockState)(nil))
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) Test_MutexWorkflow_SharedHoldersTogether() {
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "reader1", Mode: LockModeShared})
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "reader2", Mode: LockModeShared})
+	}, time.Millisecond*0)
+	s.env.RegisterDelayedCallback(func() {
+		s.Len(s.queryLockState().Holders, 2)
+	}, time.Second)
+	s.env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, "",
+		AcquireLockSignalName, mock.Anything).Return(nil).Twice()
+
+	s.env.ExecuteWorkflow(MutexWorkflow, "mockNamespace", "mockResourceID", time.Minute, (*LockState)(nil))
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) Test_MutexWorkflow_AcquireTimeout() {
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "holder"})
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "waiter", AcquireTimeout: time.Second})
+	}, time.Millisecond*0)
+	s.env.RegisterDelayedCallback(func() {
+		s.Empty(s.queryLockState().Waiters)
+		s.env.SignalWorkflow("unlock-event-holder", "releaseLock")
+	}, time.Second*2)
+	s.env.OnSignalExternalWorkflow(mock.Anything, "holder", "",
+		AcquireLockSignalName, "unlock-event-holder").Return(nil).Once()
+	s.env.OnSignalExternalWorkflow(mock.Anything, "waiter", "",
+		AcquireLockSignalName, "").Return(nil).Once()
+
+	s.env.ExecuteWorkflow(MutexWorkflow, "mockNamespace", "mockResourceID", 10*time.Minute, (*LockState)(nil))
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) Test_MutexWorkflow_RemovesClosedWaiters() {
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "holder"})
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "closed"})
+	}, time.Millisecond*0)
+	s.env.RegisterDelayedCallback(func() {
+		s.Empty(s.queryLockState().Waiters)
+		s.env.SignalWorkflow("unlock-event-holder", "releaseLock")
+	}, waiterCleanupInterval+time.Second)
+	s.env.OnActivity(FindClosedWorkflowsActivity, mock.Anything, []string{"closed"}).
+		Return([]string{"closed"}, nil).Once()
+	s.env.OnSignalExternalWorkflow(mock.Anything, "holder", "",
+		AcquireLockSignalName, "unlock-event-holder").Return(nil).Once()
+
+	s.env.ExecuteWorkflow(MutexWorkflow, "mockNamespace", "mockResourceID", 10*time.Minute, (*LockState)(nil))
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) Test_MutexWorkflow_ContinueAsNew() {
+	s.env.SetCurrentHistoryLength(continueAsNewHistoryLength)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "holder"})
+		s.env.SignalWorkflow(RequestLockSignalName, LockRequest{WorkflowID: "waiter"})
+	}, time.Millisecond*0)
+	s.env.OnSignalExternalWorkflow(mock.Anything, "holder", "",
+		AcquireLockSignalName, "unlock-event-holder").Return(nil).Once()
+
+	s.env.ExecuteWorkflow(MutexWorkflow, "mockNamespace", "mockResourceID", 10*time.Minute, (*LockState)(nil))
+
+	s.True(s.env.IsWorkflowCompleted())
+	var continueAsNewErr *workflow.ContinueAsNewError
+	s.True(errors.As(s.env.GetWorkflowError(), &continueAsNewErr))
+	var state *LockState
+	s.NoError(converter.GetDefaultDataConverter().FromPayload(continueAsNewErr.Input.Payloads[3], &state))
+	s.Len(state.Holders, 1)
+	s.Equal("holder", state.Holders[0].WorkflowID)
+	s.Len(state.Waiters, 1)
+	s.Equal("waiter", state.Waiters[0].WorkflowID)
+}
+
+func (s *UnitTestSuite) Test_MutexWorkflow_ContinuedAsNewRemovesClosedWaiters() {
+	state := &LockState{
+		Holders: []*LockHolder{{WorkflowID: "holder", Mode: LockModeExclusive, Count: 1,
+			ReleaseChannel: "unlock-event-holder", ExpiresAt: s.env.Now().Add(10 * time.Minute)}},
+		Waiters: []*LockRequest{{WorkflowID: "closed", Mode: LockModeExclusive}},
+	}
+	s.env.RegisterDelayedCallback(func() {
+		s.Empty(s.queryLockState().Waiters)
+		s.env.SignalWorkflow("unlock-event-holder", "releaseLock")
+	}, waiterCleanupInterval+time.Second)
+	s.env.OnActivity(FindClosedWorkflowsActivity, mock.Anything, []string{"closed"}).
+		Return([]string{"closed"}, nil).Once()
+
+	s.env.ExecuteWorkflow(MutexWorkflow, "mockNamespace", "mockResourceID", 10*time.Minute, state)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) Test_Workflow_UnlockAfterContinueAsNew() {
+	env := s.NewTestWorkflowEnvironment()
+	env.OnActivity(SignalWithStartMutexWorkflowActivity, mock.Anything, mock.Anything, "mockResourceID", mock.Anything).
+		Return(&workflow.Execution{ID: "mockID", RunID: "firstRunID"}, nil)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(AcquireLockSignalName, "unlock-event-default-test-workflow-id")
+	}, time.Millisecond*0)
+	// The mutex workflow continues as new while the lock is held, the release goes to the current run.
+	env.OnSignalExternalWorkflow(mock.Anything, "mockID", "",
+		"unlock-event-default-test-workflow-id", "releaseLock").Return(nil).Once()
+
+	env.ExecuteWorkflow(func(ctx workflow.Context) error {
+		unlockFunc, err := NewMutex("default-test-workflow-id", "TestUseCase").
+			Lock(ctx, "mockResourceID", time.Minute)
+		if err != nil {
+			return err
+		}
+		return unlockFunc()
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) Test_Workflow_TryLockNotAcquired() {
+	env := s.NewTestWorkflowEnvironment()
+	env.OnActivity(SignalWithStartMutexWorkflowActivity, mock.Anything, mock.Anything, "mockResourceID",
+		LockRequest{WorkflowID: "default-test-workflow-id", Mode: LockModeExclusive,
+			UnlockTimeout: time.Minute, AcquireTimeout: time.Second}).
+		Return(&workflow.Execution{ID: "mockID", RunID: "mockRunID"}, nil)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(AcquireLockSignalName, "")
+	}, time.Millisecond*0)
+
+	env.ExecuteWorkflow(func(ctx workflow.Context) (bool, error) {
+		_, ok, err := NewMutex("default-test-workflow-id", "TestUseCase").
+			TryLock(ctx, "mockResourceID", time.Minute, time.Second)
+		return ok, err
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var ok bool
+	s.NoError(env.GetWorkflowResult(&ok))
+	s.False(ok)
+	env.AssertExpectations(s.T())
+}
+
+func (s *UnitTestSuite) queryLockState() *LockState {
+	result, err := s.env.QueryWorkflow(LockStateQueryType)
+	s.NoError(err)
+	var state *LockState
+	s.NoError(result.Get(&state))
+	return state
+}
@@ -25,6 +25,7 @@ func main() {
 	})
 
 	w.RegisterActivity(mutex.SignalWithStartMutexWorkflowActivity)
+	w.RegisterActivity(mutex.FindClosedWorkflowsActivity)
 	w.RegisterWorkflow(mutex.MutexWorkflow)
 	w.RegisterWorkflow(mutex.SampleWorkflowWithMutex)
 
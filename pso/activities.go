@@ -12,6 +12,7 @@ import (
 const (
 	InitParticleActivityName   = "initParticleActivityName"
 	UpdateParticleActivityName = "updateParticleActivityName"
+	EvalRastriginActivityName  = "evalRastriginActivityName"
 )
 
 func InitParticleActivity(ctx context.Context, swarm Swarm) (Particle, error) {
@@ -34,3 +35,9 @@ func UpdateParticleActivity(ctx context.Context, swarm Swarm, particleIdx int) (
 
 	return *particle, nil
 }
+
+// EvalRastriginActivity evaluates the Rastrigin function at a location. It is an
+// example of an objective function evaluated by an activity, see RegisterFunction.
+func EvalRastriginActivity(ctx context.Context, vec []float64) (float64, error) {
+	return EvalRastrigin(vec), nil
+}
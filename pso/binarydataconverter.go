@@ -0,0 +1,149 @@
+package pso
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	// EncodingGob is the encoding marker of the payloads encoded by the binary data converter.
+	EncodingGob = "binary/gob"
+	// EncodingRaw is the encoding marker of the payloads encoded by the JSON data converter.
+	EncodingRaw = "raw"
+)
+
+// swarmBinary is the gob representation of a Swarm. Particles are stored by value since gob
+// cannot encode nil elements of a slice, which a swarm being initialized has.
+type swarmBinary struct {
+	Settings  SwarmSettings
+	Gbest     Position
+	Particles []Particle
+}
+
+// binaryDataConverter implements converter.DataConverter using gob for Swarm, Particle, Position
+// and WorkflowResult, which is much more compact than JSON for the float arrays of large swarms.
+// Every other value is left to the JSON data converter. Payloads without the EncodingGob marker
+// are decoded as JSON, so that workflows started with the JSON data converter can still be
+// replayed.
+// WARNING: Make sure all struct members are public (Capital letter) otherwise serialization does not work!
+type binaryDataConverter struct {
+	json jsonDataConverter
+}
+
+// NewBinaryDataConverter creates a binary data converter
+func NewBinaryDataConverter() converter.DataConverter {
+	return &binaryDataConverter{}
+}
+
+func (dc *binaryDataConverter) ToPayloads(value ...interface{}) (*commonpb.Payloads, error) {
+	payloads := &commonpb.Payloads{}
+	for _, obj := range value {
+		payload, err := dc.ToPayload(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		payloads.Payloads = append(payloads.Payloads, payload)
+	}
+
+	return payloads, nil
+}
+
+func (dc *binaryDataConverter) ToPayload(value interface{}) (*commonpb.Payload, error) {
+	var encoded interface{}
+	switch t := value.(type) {
+	case Swarm:
+		s := swarmBinary{Particles: make([]Particle, len(t.Particles))}
+		if t.Settings != nil {
+			s.Settings = *t.Settings
+		}
+		if t.Gbest != nil {
+			s.Gbest = *t.Gbest
+		}
+		for i, particle := range t.Particles {
+			if particle != nil {
+				s.Particles[i] = *particle
+			}
+		}
+		encoded = s
+	case Particle, Position, WorkflowResult:
+		encoded = t
+	default:
+		return dc.json.ToPayload(value)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(encoded); err != nil {
+		return nil, fmt.Errorf(
+			"unable to encode argument: %T, with error: %w", value, err)
+	}
+
+	payload := &commonpb.Payload{
+		Metadata: map[string][]byte{
+			"encoding": []byte(EncodingGob),
+		},
+		Data: buf.Bytes(),
+	}
+
+	return payload, nil
+}
+
+func (dc *binaryDataConverter) FromPayloads(payloads *commonpb.Payloads, valuePtrs ...interface{}) error {
+	if payloads == nil {
+		return nil
+	}
+	for i, payload := range payloads.Payloads {
+		err := dc.FromPayload(payload, valuePtrs[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dc *binaryDataConverter) FromPayload(payload *commonpb.Payload, valuePtr interface{}) error {
+	if string(payload.GetMetadata()["encoding"]) != EncodingGob {
+		return dc.json.FromPayload(payload, valuePtr)
+	}
+
+	var err error
+	dec := gob.NewDecoder(bytes.NewReader(payload.GetData()))
+	switch t := valuePtr.(type) {
+	case *Swarm:
+		var s swarmBinary
+		err = dec.Decode(&s)
+		t.Settings = &s.Settings
+		t.Settings.function = FunctionFactory(t.Settings.FunctionName)
+		t.Gbest = &s.Gbest
+		t.Particles = make([]*Particle, len(s.Particles))
+		for i := range s.Particles {
+			t.Particles[i] = &s.Particles[i]
+		}
+	default:
+		err = dec.Decode(valuePtr)
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"unable to decode argument: %T, with error: %v", valuePtr, err)
+	}
+	return nil
+}
+
+func (dc *binaryDataConverter) ToString(payload *commonpb.Payload) string {
+	if string(payload.GetMetadata()["encoding"]) != EncodingGob {
+		return string(payload.GetData())
+	}
+	return fmt.Sprintf("%s payload of %d bytes", EncodingGob, len(payload.GetData()))
+}
+
+func (dc *binaryDataConverter) ToStrings(payloads *commonpb.Payloads) []string {
+	var result []string
+	for _, payload := range payloads.GetPayloads() {
+		result = append(result, dc.ToString(payload))
+	}
+	return result
+}
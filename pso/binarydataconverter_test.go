@@ -0,0 +1,114 @@
+package pso
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+)
+
+// newLargeSwarm creates a swarm optimizing the sphere function in dim dimensions.
+func newLargeSwarm(dim int) Swarm {
+	settings := PSODefaultSettings("sphere")
+	settings.function.dim = dim
+	settings.Size = CalculateSwarmSize(dim, pso_max_size)
+	swarm := Swarm{Settings: settings, Gbest: NewPosition(dim)}
+	swarm.Gbest.Fitness = 1e20
+	for i := 0; i < settings.Size; i++ {
+		particle := NewParticle(&swarm)
+		particle.UpdateFitness(&swarm)
+		swarm.Particles = append(swarm.Particles, particle)
+	}
+	swarm.updateBest()
+	return swarm
+}
+
+func Test_BinaryDataConverter_RoundTrip(t *testing.T) {
+	dc := NewBinaryDataConverter()
+	swarm := newLargeSwarm(20)
+	// A swarm being initialized has no particles yet
+	swarm.Particles[1] = nil
+
+	payload, err := dc.ToPayload(swarm)
+	require.NoError(t, err)
+	require.Equal(t, EncodingGob, string(payload.Metadata["encoding"]))
+	var decoded Swarm
+	require.NoError(t, dc.FromPayload(payload, &decoded))
+	require.Equal(t, swarm.Settings.FunctionName, decoded.Settings.FunctionName)
+	require.Equal(t, swarm.Settings.Size, decoded.Settings.Size)
+	require.Equal(t, Sphere.dim, decoded.Settings.function.dim)
+	require.Equal(t, swarm.Gbest, decoded.Gbest)
+	require.Len(t, decoded.Particles, len(swarm.Particles))
+	require.Equal(t, swarm.Particles[0], decoded.Particles[0])
+	require.Equal(t, &Particle{}, decoded.Particles[1])
+
+	payloads, err := dc.ToPayloads(WorkflowResult{"done", true}, *swarm.Gbest, 42, []float64{1, 2})
+	require.NoError(t, err)
+	var result WorkflowResult
+	var position Position
+	var step int
+	var location []float64
+	require.NoError(t, dc.FromPayloads(payloads, &result, &position, &step, &location))
+	require.Equal(t, WorkflowResult{"done", true}, result)
+	require.Equal(t, *swarm.Gbest, position)
+	require.Equal(t, 42, step)
+	require.Equal(t, []float64{1, 2}, location)
+	// Other values are left to JSON
+	require.Equal(t, EncodingRaw, string(payloads.Payloads[2].Metadata["encoding"]))
+}
+
+func Test_BinaryDataConverter_DecodesJSON(t *testing.T) {
+	swarm := newLargeSwarm(3)
+	payloads, err := NewJSONDataConverter().ToPayloads(swarm, WorkflowResult{"done", true}, 42)
+	require.NoError(t, err)
+
+	var decoded Swarm
+	var result WorkflowResult
+	var step int
+	require.NoError(t, NewBinaryDataConverter().FromPayloads(payloads, &decoded, &result, &step))
+	require.Equal(t, swarm.Gbest, decoded.Gbest)
+	require.Equal(t, swarm.Particles, decoded.Particles)
+	require.Equal(t, WorkflowResult{"done", true}, result)
+	require.Equal(t, 42, step)
+}
+
+func Test_BinaryDataConverter_Size(t *testing.T) {
+	for _, dim := range []int{3, 100, 1000} {
+		swarm := newLargeSwarm(dim)
+		jsonPayload, err := NewJSONDataConverter().ToPayload(swarm)
+		require.NoError(t, err)
+		binaryPayload, err := NewBinaryDataConverter().ToPayload(swarm)
+		require.NoError(t, err)
+
+		jsonSize, binarySize := len(jsonPayload.Data), len(binaryPayload.Data)
+		t.Logf("dim %d: json %d bytes, binary %d bytes (%.0f%%)", dim, jsonSize, binarySize, 100*float64(binarySize)/float64(jsonSize))
+		if dim >= 100 {
+			require.Less(t, binarySize, jsonSize*2/3)
+		}
+	}
+}
+
+func benchmarkDataConverter(b *testing.B, dc converter.DataConverter, dim int) {
+	swarm := newLargeSwarm(dim)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payload, err := dc.ToPayload(swarm)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var decoded Swarm
+		if err := dc.FromPayload(payload, &decoded); err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(payload.Data)))
+	}
+}
+
+func Benchmark_JSONDataConverter(b *testing.B) {
+	benchmarkDataConverter(b, NewJSONDataConverter(), 1000)
+}
+
+func Benchmark_BinaryDataConverter(b *testing.B) {
+	benchmarkDataConverter(b, NewBinaryDataConverter(), 1000)
+}
@@ -74,7 +74,7 @@ func (dc *jsonDataConverter) ToPayload(value interface{}) (*commonpb.Payload, er
 
 	payload := &commonpb.Payload{
 		Metadata: map[string][]byte{
-			"encoding": []byte("raw"),
+			"encoding": []byte(EncodingRaw),
 		},
 		Data: buf.Bytes(),
 	}
@@ -1,6 +1,11 @@
 package pso
 
-import "math"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
 
 type ObjectiveFunction struct {
 	name     string                      // name of the function
@@ -9,6 +14,86 @@ type ObjectiveFunction struct {
 	xHi      float64                     // higher range limit
 	Goal     float64                     // optimization goal (error threshold)
 	Evaluate func(vec []float64) float64 // the objective function
+	// name of the activity evaluating the function when Evaluate is nil
+	evaluateActivity string
+}
+
+// FunctionOptions describe an objective function for RegisterFunction.
+type FunctionOptions struct {
+	// Problem dimensionality. Required.
+	Dim int
+	// Range of each coordinate. Required.
+	XLo, XHi float64
+	// Optimization goal (error threshold). Default 1e-5.
+	Goal float64
+	// Evaluate computes the fitness of a location within the particle
+	// activities. Either Evaluate or EvaluateActivity is required.
+	Evaluate func(vec []float64) float64
+	// EvaluateActivity is the name of an activity that takes a location
+	// ([]float64) and returns its fitness (float64). The workflow calls it
+	// after each particle activity, for functions that are expensive or can
+	// only be evaluated on some workers.
+	EvaluateActivity string
+}
+
+var (
+	functionsLock sync.RWMutex
+	functions     = map[string]ObjectiveFunction{}
+)
+
+func init() {
+	for _, function := range []ObjectiveFunction{Sphere, Rosenbrock, Griewank} {
+		functions[function.name] = function
+	}
+}
+
+// RegisterFunction registers an objective function by name, so that workflows
+// can optimize it. Register the same functions on all workers, before they
+// start, since the swarm settings only carry the name of the function.
+func RegisterFunction(name string, options FunctionOptions) error {
+	if name == "" {
+		return errors.New("function name required")
+	} else if options.Dim <= 0 {
+		return fmt.Errorf("function %s: dimension must be positive", name)
+	} else if options.XLo >= options.XHi {
+		return fmt.Errorf("function %s: XLo must be lower than XHi", name)
+	} else if (options.Evaluate == nil) == (options.EvaluateActivity == "") {
+		return fmt.Errorf("function %s: either Evaluate or EvaluateActivity required", name)
+	}
+	if options.Goal == 0 {
+		options.Goal = 1e-5
+	}
+
+	functionsLock.Lock()
+	defer functionsLock.Unlock()
+	if _, ok := functions[name]; ok {
+		return fmt.Errorf("function %s already registered", name)
+	}
+	functions[name] = ObjectiveFunction{
+		name:             name,
+		dim:              options.Dim,
+		xLo:              options.XLo,
+		xHi:              options.XHi,
+		Goal:             options.Goal,
+		Evaluate:         options.Evaluate,
+		evaluateActivity: options.EvaluateActivity,
+	}
+	return nil
+}
+
+// unregisterFunction removes a function registered with RegisterFunction.
+func unregisterFunction(name string) {
+	functionsLock.Lock()
+	defer functionsLock.Unlock()
+	delete(functions, name)
+}
+
+// LookupFunction returns the objective function registered with the name.
+func LookupFunction(name string) (ObjectiveFunction, bool) {
+	functionsLock.RLock()
+	defer functionsLock.RUnlock()
+	function, ok := functions[name]
+	return function, ok
 }
 
 var Sphere = ObjectiveFunction{
@@ -65,3 +150,13 @@ func EvalGriewank(vec []float64) float64 {
 	}
 	return sum/4000.0 - prod + 1.0
 }
+
+// EvalRastrigin is not registered by default, the worker registers it to be
+// evaluated by EvalRastriginActivity.
+func EvalRastrigin(vec []float64) float64 {
+	var sum = 10.0 * float64(len(vec))
+	for i := 0; i < len(vec); i++ {
+		sum += math.Pow(vec[i], 2.0) - 10.0*math.Cos(2.0*math.Pi*vec[i])
+	}
+	return sum
+}
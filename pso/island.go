@@ -0,0 +1,215 @@
+package pso
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pborman/uuid"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// MigrantSignal carries the global best Position of an island to the next one.
+	MigrantSignal = "migrant"
+	// IslandProgressSignal carries the IslandStatus of an island to the parent workflow.
+	IslandProgressSignal = "island-progress"
+)
+
+// IslandParams are the parameters of PSOIslandWorkflow.
+type IslandParams struct {
+	FunctionName string
+	// Number of swarms run in parallel. Default 4.
+	Islands int
+	// Steps between two migrations of the global best to the next island. Default 5.
+	MigrationInterval int
+}
+
+// IslandConfig tells an island where to send its migrants.
+type IslandConfig struct {
+	Index int
+	// Workflow ID of the next island of the ring.
+	Next              string
+	MigrationInterval int
+}
+
+// IslandStatus is the progress of an island, returned by the "child" query of PSOIslandWorkflow.
+type IslandStatus struct {
+	Island     int
+	WorkflowID string
+	Step       int
+	Fitness    float64
+	Converged  bool
+	Done       bool
+}
+
+// PSOIslandWorkflow runs several swarms in parallel as child workflows, the islands. Each island
+// sends its global best to the next island of a ring every MigrationInterval steps, where it
+// replaces the global best if it is better. The workflow completes once an island reaches the goal,
+// cancelling the others, or once all of them gave up.
+func PSOIslandWorkflow(ctx workflow.Context, params IslandParams) (string, error) {
+	logger := workflow.GetLogger(ctx)
+	if _, ok := LookupFunction(params.FunctionName); !ok {
+		return "", fmt.Errorf("unknown function %q", params.FunctionName)
+	}
+	if params.Islands <= 0 {
+		params.Islands = 4
+	}
+	if params.MigrationInterval <= 0 {
+		params.MigrationInterval = 5
+	}
+	logger.Info(fmt.Sprintf("Optimizing function %s on %d islands", params.FunctionName, params.Islands))
+
+	// Set activity options
+	ctx = workflow.WithActivityOptions(ctx, ActivityOptions)
+
+	// Setup query handler for query type "child", which reports the progress of every island
+	statuses := make([]IslandStatus, params.Islands)
+	err := workflow.SetQueryHandler(ctx, "child", func() ([]IslandStatus, error) {
+		return statuses, nil
+	})
+	if err != nil {
+		msg := "SetQueryHandler failed: " + err.Error()
+		logger.Error(msg)
+		return msg, err
+	}
+
+	// The islands need to know the IDs of each other upfront to form the ring
+	var prefix string
+	err = workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
+		return "PSO_Island_" + uuid.New()
+	}).Get(&prefix)
+	if err != nil {
+		return "", err
+	}
+	for i := range statuses {
+		statuses[i] = IslandStatus{Island: i, WorkflowID: fmt.Sprintf("%s_%d", prefix, i)}
+	}
+
+	childCtx, cancelIslands := workflow.WithCancel(ctx)
+	defer cancelIslands()
+	selector := workflow.NewSelector(ctx)
+	winner := -1
+	var winnerMsg string
+	for i := range statuses {
+		island := i
+		swarm, err := NewSwarm(ctx, PSODefaultSettings(params.FunctionName))
+		if err != nil {
+			msg := "Optimization failed. " + err.Error()
+			logger.Error(msg)
+			return msg, err
+		}
+		cwo := workflow.ChildWorkflowOptions{
+			WorkflowID:          statuses[island].WorkflowID,
+			WorkflowRunTimeout:  time.Minute,
+			WorkflowTaskTimeout: time.Minute,
+		}
+		config := IslandConfig{
+			Index:             island,
+			Next:              statuses[(island+1)%params.Islands].WorkflowID,
+			MigrationInterval: params.MigrationInterval,
+		}
+		future := workflow.ExecuteChildWorkflow(workflow.WithChildOptions(childCtx, cwo), PSOIslandChildWorkflow, *swarm, 1, config)
+		selector.AddFuture(future, func(f workflow.Future) {
+			statuses[island].Done = true
+			var result WorkflowResult
+			if err := f.Get(ctx, &result); err != nil {
+				logger.Info(fmt.Sprintf("Island #%d stopped. %s", island, err.Error()))
+				return
+			}
+			if result.Success {
+				statuses[island].Converged = true
+				if winner < 0 {
+					winner, winnerMsg = island, result.Msg
+				}
+			}
+		})
+	}
+	selector.AddReceive(workflow.GetSignalChannel(ctx, IslandProgressSignal), func(c workflow.ReceiveChannel, more bool) {
+		var status IslandStatus
+		c.Receive(ctx, &status)
+		if status.Island >= 0 && status.Island < len(statuses) && !statuses[status.Island].Done {
+			statuses[status.Island].Step = status.Step
+			statuses[status.Island].Fitness = status.Fitness
+		}
+	})
+
+	// Wait for an island to converge, then cancel the others and wait for them to stop
+	for running := params.Islands; running > 0; {
+		selector.Select(ctx)
+		running = 0
+		for _, status := range statuses {
+			if !status.Done {
+				running++
+			}
+		}
+		if winner >= 0 {
+			cancelIslands()
+		}
+	}
+
+	if winner >= 0 {
+		msg := fmt.Sprintf("Optimization was successful on island #%d. %s", winner, winnerMsg)
+		logger.Info(msg)
+		return msg, nil
+	}
+	msg := fmt.Sprintf("Unable to reach goal on any of %d islands", params.Islands)
+	logger.Info(msg)
+	return msg, nil
+}
+
+// PSOIslandChildWorkflow runs the swarm of an island, exchanging its global best with the other
+// islands and reporting its progress to the parent workflow every MigrationInterval steps.
+func PSOIslandChildWorkflow(ctx workflow.Context, swarm Swarm, startingStep int, island IslandConfig) (WorkflowResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Island workflow execution started.", "Island", island.Index)
+
+	// Set activity options
+	ctx = workflow.WithActivityOptions(ctx, ActivityOptions)
+
+	migrants := workflow.GetSignalChannel(ctx, MigrantSignal)
+	parent := workflow.GetInfo(ctx).ParentWorkflowExecution
+	result, err := swarm.run(ctx, startingStep, func(ctx workflow.Context, step int) {
+		// Drain the migrants at every step, so that none is left behind on continue-as-new
+		var migrant Position
+		for migrants.ReceiveAsync(&migrant) {
+			if migrant.IsBetterThan(swarm.Gbest) {
+				swarm.Gbest = migrant.Copy()
+			}
+		}
+		if step%island.MigrationInterval != 0 {
+			return
+		}
+
+		// The next island may have completed already, so failures are only logged
+		err := workflow.SignalExternalWorkflow(ctx, island.Next, "", MigrantSignal, *swarm.Gbest).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Failed sending migrant", "Next", island.Next, "Error", err)
+		}
+		if parent != nil {
+			status := IslandStatus{Island: island.Index, Step: step, Fitness: swarm.Gbest.Fitness}
+			err := workflow.SignalExternalWorkflow(ctx, parent.ID, "", IslandProgressSignal, status).Get(ctx, nil)
+			if err != nil {
+				logger.Warn("Failed reporting progress", "Error", err)
+			}
+		}
+	})
+	if err != nil {
+		if err.Error() == ContinueAsNewStr {
+			return WorkflowResult{"NewContinueAsNewError", false}, workflow.NewContinueAsNewError(ctx, PSOIslandChildWorkflow, swarm, result.Step+1, island)
+		}
+
+		msg := "Error in swarm loop: " + err.Error()
+		logger.Error(msg)
+		return WorkflowResult{msg, false}, errors.New("error in swarm loop")
+	}
+	if result.Position.Fitness < swarm.Settings.function.Goal {
+		msg := fmt.Sprintf("Goal was reached @ step %d (fitness=%.2e)", result.Step, result.Position.Fitness)
+		logger.Info(msg)
+		return WorkflowResult{msg, true}, nil
+	}
+
+	msg := fmt.Sprintf("Goal was not reached after %d steps (fitness=%.2e)", result.Step, result.Position.Fitness)
+	logger.Info(msg)
+	return WorkflowResult{msg, false}, nil
+}
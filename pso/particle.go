@@ -47,8 +47,19 @@ func (particle *Particle) UpdateLocation(swarm *Swarm) {
 	}
 }
 
+// UpdateFitness evaluates the objective function at the position of the
+// particle. Functions evaluated by an activity are left to the workflow, which
+// calls SetFitness with the result of the activity.
 func (particle *Particle) UpdateFitness(swarm *Swarm) {
-	particle.Position.Fitness = swarm.Settings.function.Evaluate(particle.Position.Location)
+	if swarm.Settings.function.Evaluate == nil {
+		return
+	}
+	particle.SetFitness(swarm.Settings.function.Evaluate(particle.Position.Location))
+}
+
+// SetFitness sets the fitness of the position of the particle and updates its best position.
+func (particle *Particle) SetFitness(fitness float64) {
+	particle.Position.Fitness = fitness
 
 	if particle.Position.IsBetterThan(particle.Pbest) {
 		particle.Pbest = particle.Position.Copy()
@@ -20,7 +20,7 @@ func main() {
 	// The client is a heavyweight object that should be created once per process.
 	c, err := client.Dial(client.Options{
 		HostPort:      client.DefaultHostPort,
-		DataConverter: pso.NewJSONDataConverter(),
+		DataConverter: pso.NewBinaryDataConverter(),
 	})
 	if err != nil {
 		log.Fatalln("Unable to create client", err)
@@ -29,16 +29,10 @@ type SwarmSettings struct {
 	Inertia float64 // current inertia weight value
 }
 
+// FunctionFactory returns the registered objective function with the name, or
+// the zero ObjectiveFunction if there is none.
 func FunctionFactory(functionName string) ObjectiveFunction {
-	var function ObjectiveFunction
-	switch functionName {
-	case "sphere":
-		function = Sphere
-	case "rosenbrock":
-		function = Rosenbrock
-	case "griewank":
-		function = Griewank
-	}
+	function, _ := LookupFunction(functionName)
 	return function
 }
 
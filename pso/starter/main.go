@@ -13,13 +13,16 @@ import (
 
 func main() {
 	var functionName string
-	flag.StringVar(&functionName, "f", "sphere", "One of [sphere, rosenbrock, griewank]")
+	var params pso.IslandParams
+	flag.StringVar(&functionName, "f", "sphere", "One of [sphere, rosenbrock, griewank, rastrigin]")
+	flag.IntVar(&params.Islands, "islands", 0, "Number of swarms run in parallel, 0 runs a single swarm")
+	flag.IntVar(&params.MigrationInterval, "migrate", 5, "Steps between migrations of the global best between islands")
 	flag.Parse()
 
 	// The client is a heavyweight object that should be created once per process.
 	c, err := client.Dial(client.Options{
 		HostPort:      client.DefaultHostPort,
-		DataConverter: pso.NewJSONDataConverter(),
+		DataConverter: pso.NewBinaryDataConverter(),
 	})
 	if err != nil {
 		log.Fatalln("Unable to create client", err)
@@ -31,7 +34,13 @@ func main() {
 		TaskQueue: "pso",
 	}
 
-	we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, pso.PSOWorkflow, functionName)
+	var we client.WorkflowRun
+	if params.Islands > 0 {
+		params.FunctionName = functionName
+		we, err = c.ExecuteWorkflow(context.Background(), workflowOptions, pso.PSOIslandWorkflow, params)
+	} else {
+		we, err = c.ExecuteWorkflow(context.Background(), workflowOptions, pso.PSOWorkflow, functionName)
+	}
 	if err != nil {
 		log.Fatalln("Unable to execute workflow", err)
 	}
@@ -34,6 +34,9 @@ func NewSwarm(ctx workflow.Context, settings *SwarmSettings) (*Swarm, error) {
 		workflow.Go(ctx, func(ctx workflow.Context) {
 			var particle Particle
 			err := workflow.ExecuteActivity(ctx, InitParticleActivityName, swarm).Get(ctx, &particle)
+			if err == nil {
+				err = swarm.evaluate(ctx, &particle)
+			}
 			if err == nil {
 				swarm.Particles[particleIdx] = &particle
 			}
@@ -66,7 +69,28 @@ func (swarm *Swarm) updateBest() {
 	}
 }
 
+// evaluate sets the fitness of the particle with the evaluation activity of the
+// objective function, if it has one. Otherwise the particle activities already did.
+func (swarm *Swarm) evaluate(ctx workflow.Context, particle *Particle) error {
+	activityName := swarm.Settings.function.evaluateActivity
+	if activityName == "" {
+		return nil
+	}
+	var fitness float64
+	if err := workflow.ExecuteActivity(ctx, activityName, particle.Position.Location).Get(ctx, &fitness); err != nil {
+		return err
+	}
+	particle.SetFitness(fitness)
+	return nil
+}
+
 func (swarm *Swarm) Run(ctx workflow.Context, step int) (ParticleResult, error) {
+	return swarm.run(ctx, step, nil)
+}
+
+// run is Run with an optional afterStep function, called once the swarm best is
+// updated at each step, which may change the swarm best in turn.
+func (swarm *Swarm) run(ctx workflow.Context, step int, afterStep func(ctx workflow.Context, step int)) (ParticleResult, error) {
 	logger := workflow.GetLogger(ctx)
 
 	// Setup query handler for query type "iteration"
@@ -89,6 +113,9 @@ func (swarm *Swarm) Run(ctx workflow.Context, step int) (ParticleResult, error)
 			workflow.Go(ctx, func(ctx workflow.Context) {
 				var particle Particle
 				err := workflow.ExecuteActivity(ctx, UpdateParticleActivityName, *swarm, particleIdx).Get(ctx, &particle)
+				if err == nil {
+					err = swarm.evaluate(ctx, &particle)
+				}
 				if err == nil {
 					swarm.Particles[particleIdx] = &particle
 				}
@@ -114,6 +141,9 @@ func (swarm *Swarm) Run(ctx workflow.Context, step int) (ParticleResult, error)
 		logger.Debug("Iteration Update Swarm Best", "step", step)
 
 		swarm.updateBest()
+		if afterStep != nil {
+			afterStep(ctx, step)
+		}
 
 		// Check if the goal has reached then stop early
 		if swarm.Gbest.Fitness < swarm.Settings.function.Goal {
@@ -11,10 +11,21 @@ import (
 )
 
 func main() {
+	// Objective functions are looked up by name, so every worker registers the same ones
+	err := pso.RegisterFunction("rastrigin", pso.FunctionOptions{
+		Dim:              3,
+		XLo:              -5.12,
+		XHi:              5.12,
+		EvaluateActivity: pso.EvalRastriginActivityName,
+	})
+	if err != nil {
+		log.Fatalln("Unable to register function", err)
+	}
+
 	// The client and worker are heavyweight objects that should be created once per process.
 	c, err := client.Dial(client.Options{
 		HostPort:      client.DefaultHostPort,
-		DataConverter: pso.NewJSONDataConverter(),
+		DataConverter: pso.NewBinaryDataConverter(),
 	})
 	if err != nil {
 		log.Fatalln("Unable to create client", err)
@@ -27,9 +38,12 @@ func main() {
 
 	w.RegisterWorkflow(pso.PSOWorkflow)
 	w.RegisterWorkflow(pso.PSOChildWorkflow)
+	w.RegisterWorkflow(pso.PSOIslandWorkflow)
+	w.RegisterWorkflow(pso.PSOIslandChildWorkflow)
 
 	w.RegisterActivityWithOptions(pso.InitParticleActivity, activity.RegisterOptions{Name: pso.InitParticleActivityName})
 	w.RegisterActivityWithOptions(pso.UpdateParticleActivity, activity.RegisterOptions{Name: pso.UpdateParticleActivityName})
+	w.RegisterActivityWithOptions(pso.EvalRastriginActivity, activity.RegisterOptions{Name: pso.EvalRastriginActivityName})
 
 	err = w.Run(worker.InterruptCh())
 	if err != nil {
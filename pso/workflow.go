@@ -32,6 +32,9 @@ const ContinueAsNewStr = "CONTINUEASNEW"
 // PSOWorkflow workflow definition
 func PSOWorkflow(ctx workflow.Context, functionName string) (string, error) {
 	logger := workflow.GetLogger(ctx)
+	if _, ok := LookupFunction(functionName); !ok {
+		return "", fmt.Errorf("unknown function %q", functionName)
+	}
 	logger.Info(fmt.Sprintf("Optimizing function %s", functionName))
 
 	// Set activity options
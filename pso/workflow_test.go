@@ -29,7 +29,7 @@ func Test_Workflow(t *testing.T) {
 
 	var activityCalled []string
 
-	var dataConverter = NewJSONDataConverter()
+	var dataConverter = NewBinaryDataConverter()
 	env.SetDataConverter(dataConverter)
 
 	// env.SetWorkflowTimeout(5 * time.Minute)
@@ -72,3 +72,90 @@ func queryAndVerify(t *testing.T, env *testsuite.TestWorkflowEnvironment, query
 	require.NoError(t, err)
 	require.Equal(t, expectedState, state)
 }
+
+func newTestEnv() *testsuite.TestWorkflowEnvironment {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.SetDataConverter(NewBinaryDataConverter())
+	env.RegisterActivityWithOptions(
+		InitParticleActivity,
+		activity.RegisterOptions{Name: InitParticleActivityName},
+	)
+	env.RegisterActivityWithOptions(
+		UpdateParticleActivity,
+		activity.RegisterOptions{Name: UpdateParticleActivityName},
+	)
+	return env
+}
+
+func Test_ActivityObjectiveFunction(t *testing.T) {
+	env := newTestEnv()
+	env.RegisterWorkflow(PSOChildWorkflow)
+
+	// The sphere function, evaluated by an activity instead of the particle activities
+	const evalActivityName = "evalSphereActivity"
+	require.NoError(t, RegisterFunction("sphere-activity", FunctionOptions{
+		Dim:              3,
+		XLo:              -100,
+		XHi:              100,
+		EvaluateActivity: evalActivityName,
+	}))
+	t.Cleanup(func() { unregisterFunction("sphere-activity") })
+	require.Error(t, RegisterFunction("sphere-activity", FunctionOptions{Dim: 3, XLo: -1, XHi: 1, Evaluate: EvalSphere}))
+	evaluations := 0
+	env.RegisterActivityWithOptions(func(ctx context.Context, vec []float64) (float64, error) {
+		evaluations++
+		return EvalSphere(vec), nil
+	}, activity.RegisterOptions{Name: evalActivityName})
+
+	env.ExecuteWorkflow(PSOWorkflow, "sphere-activity")
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result string
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.True(t, strings.HasPrefix(result, "Optimization was successful"), result)
+	require.Positive(t, evaluations)
+}
+
+func Test_UnknownFunction(t *testing.T) {
+	env := newTestEnv()
+	env.ExecuteWorkflow(PSOWorkflow, "unknown")
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), `unknown function "unknown"`)
+}
+
+func Test_IslandWorkflow(t *testing.T) {
+	env := newTestEnv()
+	env.RegisterWorkflow(PSOIslandChildWorkflow)
+
+	env.ExecuteWorkflow(PSOIslandWorkflow, IslandParams{FunctionName: "sphere", Islands: 3, MigrationInterval: 1})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result string
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.True(t, strings.HasPrefix(result, "Optimization was successful on island #"), result)
+
+	// The child query reports the convergence of the islands
+	value, err := env.QueryWorkflow("child")
+	require.NoError(t, err)
+	var statuses []IslandStatus
+	require.NoError(t, value.Get(&statuses))
+	require.Len(t, statuses, 3)
+	converged, reported := 0, 0
+	for _, status := range statuses {
+		require.True(t, status.Done)
+		require.NotEmpty(t, status.WorkflowID)
+		if status.Converged {
+			converged++
+		}
+		if status.Step > 0 {
+			reported++
+		}
+	}
+	require.Positive(t, converged)
+	// The islands signal their progress at every step
+	require.Positive(t, reported)
+}
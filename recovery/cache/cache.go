@@ -1,33 +1,50 @@
 package cache
 
-import "time"
+import (
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
 
 // A Cache is a generalized interface to a cache.  See cache.LRU for a specific
-// implementation (bounded cache with LRU eviction)
-type Cache interface {
-	// Get retrieves an element based on a key, returning nil if the element
+// implementation (bounded cache with LRU eviction) and cache.NewSharded for
+// one that spreads the keys over several of them to reduce lock contention.
+// Implementations are safe for concurrent use.
+type Cache[K comparable, V any] interface {
+	// Get retrieves an element based on a key, returning false if the element
 	// does not exist
-	Get(key string) interface{}
+	Get(key K) (V, bool)
 
-	// Put adds an element to the cache, returning the previous element
-	Put(key string, value interface{}) interface{}
+	// Put adds an element to the cache, returning the previous element if there was one
+	Put(key K, value V) (V, bool)
 
-	// PutIfNotExist puts a value associated with a given key if it does not exist
-	PutIfNotExist(key string, value interface{}) (interface{}, error)
+	// PutIfNotExist puts a value associated with a given key if it does not exist,
+	// returning the value that is in the cache afterwards
+	PutIfNotExist(key K, value V) (V, error)
 
 	// Delete deletes an element in the cache
-	Delete(key string)
+	Delete(key K)
 
 	// Release decrements the ref count of a pinned element. If the ref count
 	// drops to 0, the element can be evicted from the cache.
-	Release(key string)
+	Release(key K)
 
 	// Size returns the number of entries currently stored in the Cache
 	Size() int
 }
 
+// Names of the counters reported to Options.MetricsHandler.
+const (
+	// MetricHits counts Get calls that found the key
+	MetricHits = "cache_hits"
+	// MetricMisses counts Get calls that didn't find the key or found an expired entry
+	MetricMisses = "cache_misses"
+	// MetricEvictions counts entries removed because they expired or the cache was full
+	MetricEvictions = "cache_evictions"
+)
+
 // Options control the behavior of the cache
-type Options struct {
+type Options[V any] struct {
 	// TTL controls the time-to-live for a given cache entry.  Cache entries that
 	// are older than the TTL will not be returned
 	TTL time.Duration
@@ -40,11 +57,15 @@ type Options struct {
 
 	// RemovedFunc is an optional function called when an element
 	// is scheduled for deletion
-	RemovedFunc RemovedFunc
+	RemovedFunc RemovedFunc[V]
+
+	// MetricsHandler is an optional handler that counts hits, misses and evictions.
+	// Use MetricsHandler.WithTags to tell several caches apart.
+	MetricsHandler client.MetricsHandler
 }
 
 // RemovedFunc is a type for notifying applications when an item is
 // scheduled for removal from the Cache. If f is a function with the
-// appropriate signature and i is the interface{} scheduled for
-// deletion, Cache calls go f(i)
-type RemovedFunc func(interface{})
+// appropriate signature and v is the value scheduled for
+// deletion, Cache calls go f(v)
+type RemovedFunc[V any] func(V)
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"go.temporal.io/sdk/client"
 )
 
 var (
@@ -13,57 +15,69 @@ var (
 )
 
 // lru is a concurrent fixed size cache that evicts elements in lru order
-type lru struct {
-	mut      sync.Mutex
-	byAccess *list.List
-	byKey    map[string]*list.Element
-	maxSize  int
-	ttl      time.Duration
-	pin      bool
-	rmFunc   RemovedFunc
+type lru[K comparable, V any] struct {
+	mut       sync.Mutex
+	byAccess  *list.List
+	byKey     map[K]*list.Element
+	maxSize   int
+	ttl       time.Duration
+	pin       bool
+	rmFunc    RemovedFunc[V]
+	hits      client.MetricsCounter
+	misses    client.MetricsCounter
+	evictions client.MetricsCounter
 }
 
 // New creates a new cache with the given options
-func New(maxSize int, opts *Options) Cache {
+func New[K comparable, V any](maxSize int, opts *Options[V]) Cache[K, V] {
 	if opts == nil {
-		opts = &Options{}
+		opts = &Options[V]{}
+	}
+	metricsHandler := opts.MetricsHandler
+	if metricsHandler == nil {
+		metricsHandler = client.MetricsNopHandler
 	}
 
-	return &lru{
-		byAccess: list.New(),
-		byKey:    make(map[string]*list.Element, opts.InitialCapacity),
-		ttl:      opts.TTL,
-		maxSize:  maxSize,
-		pin:      opts.Pin,
-		rmFunc:   opts.RemovedFunc,
+	return &lru[K, V]{
+		byAccess:  list.New(),
+		byKey:     make(map[K]*list.Element, opts.InitialCapacity),
+		ttl:       opts.TTL,
+		maxSize:   maxSize,
+		pin:       opts.Pin,
+		rmFunc:    opts.RemovedFunc,
+		hits:      metricsHandler.Counter(MetricHits),
+		misses:    metricsHandler.Counter(MetricMisses),
+		evictions: metricsHandler.Counter(MetricEvictions),
 	}
 }
 
 // NewLRU creates a new LRU cache of the given size, setting initial capacity
 // to the max size
-func NewLRU(maxSize int) Cache {
-	return New(maxSize, nil)
+func NewLRU[K comparable, V any](maxSize int) Cache[K, V] {
+	return New[K, V](maxSize, nil)
 }
 
 // NewLRUWithInitialCapacity creates a new LRU cache with an initial capacity
 // and a max size
-func NewLRUWithInitialCapacity(initialCapacity, maxSize int) Cache {
-	return New(maxSize, &Options{
+func NewLRUWithInitialCapacity[K comparable, V any](initialCapacity, maxSize int) Cache[K, V] {
+	return New[K, V](maxSize, &Options[V]{
 		InitialCapacity: initialCapacity,
 	})
 }
 
 // Get retrieves the value stored under the given key
-func (c *lru) Get(key string) interface{} {
+func (c *lru[K, V]) Get(key K) (V, bool) {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
+	var zero V
 	elt := c.byKey[key]
 	if elt == nil {
-		return nil
+		c.misses.Inc(1)
+		return zero, false
 	}
 
-	cacheEntry := elt.Value.(*cacheEntry)
+	cacheEntry := elt.Value.(*cacheEntry[K, V])
 
 	if c.pin {
 		cacheEntry.refCount++
@@ -71,50 +85,54 @@ func (c *lru) Get(key string) interface{} {
 
 	if cacheEntry.refCount == 0 && !cacheEntry.expiration.IsZero() && time.Now().After(cacheEntry.expiration) {
 		// Entry has expired
+		c.misses.Inc(1)
+		c.evictions.Inc(1)
 		if c.rmFunc != nil {
 			go c.rmFunc(cacheEntry.value)
 		}
 		c.byAccess.Remove(elt)
 		delete(c.byKey, cacheEntry.key)
-		return nil
+		return zero, false
 	}
 
+	c.hits.Inc(1)
 	c.byAccess.MoveToFront(elt)
-	return cacheEntry.value
+	return cacheEntry.value, true
 }
 
 // Put puts a new value associated with a given key, returning the existing value (if present)
-func (c *lru) Put(key string, value interface{}) interface{} {
+func (c *lru[K, V]) Put(key K, value V) (V, bool) {
 	if c.pin {
 		panic("Cannot use Put API in Pin mode. Use Delete and PutIfNotExist if necessary")
 	}
-	val, _ := c.putInternal(key, value, true)
-	return val
+	val, existed, _ := c.putInternal(key, value, true)
+	return val, existed
 }
 
 // PutIfNotExist puts a value associated with a given key if it does not exist
-func (c *lru) PutIfNotExist(key string, value interface{}) (interface{}, error) {
-	existing, err := c.putInternal(key, value, false)
+func (c *lru[K, V]) PutIfNotExist(key K, value V) (V, error) {
+	existing, existed, err := c.putInternal(key, value, false)
 	if err != nil {
-		return nil, err
+		var zero V
+		return zero, err
 	}
 
-	if existing == nil {
+	if !existed {
 		// This is a new value
-		return value, err
+		return value, nil
 	}
 
-	return existing, err
+	return existing, nil
 }
 
 // Delete deletes a key, value pair associated with a key
-func (c *lru) Delete(key string) {
+func (c *lru[K, V]) Delete(key K) {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
 	elt := c.byKey[key]
 	if elt != nil {
-		entry := c.byAccess.Remove(elt).(*cacheEntry)
+		entry := c.byAccess.Remove(elt).(*cacheEntry[K, V])
 		if c.rmFunc != nil {
 			go c.rmFunc(entry.value)
 		}
@@ -123,17 +141,23 @@ func (c *lru) Delete(key string) {
 }
 
 // Release decrements the ref count of a pinned element.
-func (c *lru) Release(key string) {
+func (c *lru[K, V]) Release(key K) {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
 	elt := c.byKey[key]
-	cacheEntry := elt.Value.(*cacheEntry)
-	cacheEntry.refCount--
+	if elt == nil {
+		// Deleted while pinned
+		return
+	}
+	cacheEntry := elt.Value.(*cacheEntry[K, V])
+	if cacheEntry.refCount > 0 {
+		cacheEntry.refCount--
+	}
 }
 
 // Size returns the number of entries currently in the lru, useful if cache is not full
-func (c *lru) Size() int {
+func (c *lru[K, V]) Size() int {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
@@ -142,13 +166,14 @@ func (c *lru) Size() int {
 
 // Put puts a new value associated with a given key, returning the existing value (if present)
 // allowUpdate flag is used to control overwrite behavior if the value exists
-func (c *lru) putInternal(key string, value interface{}, allowUpdate bool) (interface{}, error) {
+func (c *lru[K, V]) putInternal(key K, value V, allowUpdate bool) (V, bool, error) {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
+	var zero V
 	elt := c.byKey[key]
 	if elt != nil {
-		entry := elt.Value.(*cacheEntry)
+		entry := elt.Value.(*cacheEntry[K, V])
 		existing := entry.value
 		if allowUpdate {
 			entry.value = value
@@ -160,10 +185,10 @@ func (c *lru) putInternal(key string, value interface{}, allowUpdate bool) (inte
 		if c.pin {
 			entry.refCount++
 		}
-		return existing, nil
+		return existing, true, nil
 	}
 
-	entry := &cacheEntry{
+	entry := &cacheEntry[K, V]{
 		key:   key,
 		value: value,
 	}
@@ -177,17 +202,18 @@ func (c *lru) putInternal(key string, value interface{}, allowUpdate bool) (inte
 	}
 
 	c.byKey[key] = c.byAccess.PushFront(entry)
-	if len(c.byKey) == c.maxSize {
-		oldest := c.byAccess.Back().Value.(*cacheEntry)
+	if len(c.byKey) > c.maxSize {
+		oldest := c.byAccess.Back().Value.(*cacheEntry[K, V])
 
 		if oldest.refCount > 0 {
 			// Cache is full with pinned elements
 			// revert the insert and return
 			c.byAccess.Remove(c.byAccess.Front())
 			delete(c.byKey, key)
-			return nil, ErrCacheFull
+			return zero, false, ErrCacheFull
 		}
 
+		c.evictions.Inc(1)
 		c.byAccess.Remove(c.byAccess.Back())
 		if c.rmFunc != nil {
 			go c.rmFunc(oldest.value)
@@ -195,12 +221,12 @@ func (c *lru) putInternal(key string, value interface{}, allowUpdate bool) (inte
 		delete(c.byKey, oldest.key)
 	}
 
-	return nil, nil
+	return zero, false, nil
 }
 
-type cacheEntry struct {
-	key        string
+type cacheEntry[K comparable, V any] struct {
+	key        K
 	expiration time.Time
-	value      interface{}
+	value      V
 	refCount   int
 }
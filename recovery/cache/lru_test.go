@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+)
+
+func TestLRU_Evicts(t *testing.T) {
+	metrics := newCountingHandler()
+	c := New[string, int](2, &Options[int]{MetricsHandler: metrics})
+
+	c.Put("a", 1)
+	c.Put("b", 2)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	// b is the least recently used
+	c.Put("c", 3)
+
+	_, ok = c.Get("b")
+	require.False(t, ok)
+	v, ok := c.Get("c")
+	require.True(t, ok)
+	require.Equal(t, 3, v)
+	require.Equal(t, 2, c.Size())
+	require.Equal(t, map[string]int64{MetricHits: 2, MetricMisses: 1, MetricEvictions: 1}, metrics.counts())
+}
+
+func TestLRU_TTL(t *testing.T) {
+	metrics := newCountingHandler()
+	c := New[string, int](2, &Options[int]{TTL: time.Millisecond, MetricsHandler: metrics})
+
+	c.Put("a", 1)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := c.Get("a")
+	require.False(t, ok)
+	require.Equal(t, 0, c.Size())
+	require.Equal(t, map[string]int64{MetricMisses: 1, MetricEvictions: 1}, metrics.counts())
+}
+
+func TestLRU_Pin(t *testing.T) {
+	c := New[string, int](1, &Options[int]{Pin: true})
+
+	v, err := c.PutIfNotExist("a", 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	v, err = c.PutIfNotExist("a", 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	_, err = c.PutIfNotExist("b", 2)
+	require.ErrorIs(t, err, ErrCacheFull)
+
+	c.Release("a")
+	c.Release("a")
+	_, err = c.PutIfNotExist("b", 2)
+	require.NoError(t, err)
+	_, ok := c.Get("a")
+	require.False(t, ok)
+	require.Panics(t, func() { c.Put("c", 3) })
+}
+
+func TestSharded(t *testing.T) {
+	metrics := newCountingHandler()
+	c := NewSharded[int, string](4, 100, &Options[string]{MetricsHandler: metrics})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				key := i*10 + j
+				c.Put(key, fmt.Sprint(key))
+				v, ok := c.Get(key)
+				assert.True(t, ok)
+				assert.Equal(t, fmt.Sprint(key), v)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 40, c.Size())
+	c.Delete(7)
+	_, ok := c.Get(7)
+	require.False(t, ok)
+	require.Equal(t, map[string]int64{MetricHits: 40, MetricMisses: 1}, metrics.counts())
+}
+
+func TestSharded_MaxSize(t *testing.T) {
+	c := NewSharded[int, string](3, 10, nil).(*sharded[int, string])
+
+	var sizes []int
+	for _, shard := range c.shards {
+		sizes = append(sizes, shard.(*lru[int, string]).maxSize)
+	}
+	require.Equal(t, []int{4, 3, 3}, sizes)
+}
+
+// countingHandler is a client.MetricsHandler that sums up the counters.
+type countingHandler struct {
+	mut    sync.Mutex
+	values map[string]int64
+}
+
+func newCountingHandler() *countingHandler {
+	return &countingHandler{values: map[string]int64{}}
+}
+
+func (h *countingHandler) WithTags(map[string]string) client.MetricsHandler {
+	return h
+}
+
+func (h *countingHandler) Counter(name string) client.MetricsCounter {
+	return counterFunc(func(delta int64) {
+		h.mut.Lock()
+		defer h.mut.Unlock()
+		h.values[name] += delta
+	})
+}
+
+func (h *countingHandler) Gauge(string) client.MetricsGauge {
+	return client.MetricsNopHandler.Gauge("")
+}
+
+func (h *countingHandler) Timer(string) client.MetricsTimer {
+	return client.MetricsNopHandler.Timer("")
+}
+
+func (h *countingHandler) counts() map[string]int64 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	counts := map[string]int64{}
+	for name, value := range h.values {
+		if value != 0 {
+			counts[name] = value
+		}
+	}
+	return counts
+}
+
+type counterFunc func(int64)
+
+func (f counterFunc) Inc(delta int64) { f(delta) }
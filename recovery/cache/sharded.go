@@ -0,0 +1,74 @@
+package cache
+
+import "hash/maphash"
+
+// sharded spreads the keys over several lru caches, each with its own lock
+type sharded[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []Cache[K, V]
+}
+
+// NewSharded creates a cache of the given max size that is split into the given number of
+// LRU caches. The first maxSize % shards shards hold one more entry than the others, so the
+// sizes add up to maxSize. Keys are assigned to shards by hash, so eviction is in lru order
+// within a shard only. The options apply to every shard.
+func NewSharded[K comparable, V any](shards, maxSize int, opts *Options[V]) Cache[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+	shardOpts := Options[V]{}
+	if opts != nil {
+		shardOpts = *opts
+	}
+	shardOpts.InitialCapacity = (shardOpts.InitialCapacity + shards - 1) / shards
+	c := &sharded[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]Cache[K, V], shards),
+	}
+	for i := range c.shards {
+		shardSize := maxSize / shards
+		if i < maxSize%shards {
+			shardSize++
+		}
+		c.shards[i] = New[K, V](shardSize, &shardOpts)
+	}
+	return c
+}
+
+func (c *sharded[K, V]) shard(key K) Cache[K, V] {
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Get retrieves the value stored under the given key
+func (c *sharded[K, V]) Get(key K) (V, bool) {
+	return c.shard(key).Get(key)
+}
+
+// Put puts a new value associated with a given key, returning the existing value (if present)
+func (c *sharded[K, V]) Put(key K, value V) (V, bool) {
+	return c.shard(key).Put(key, value)
+}
+
+// PutIfNotExist puts a value associated with a given key if it does not exist
+func (c *sharded[K, V]) PutIfNotExist(key K, value V) (V, error) {
+	return c.shard(key).PutIfNotExist(key, value)
+}
+
+// Delete deletes a key, value pair associated with a key
+func (c *sharded[K, V]) Delete(key K) {
+	c.shard(key).Delete(key)
+}
+
+// Release decrements the ref count of a pinned element.
+func (c *sharded[K, V]) Release(key K) {
+	c.shard(key).Release(key)
+}
+
+// Size returns the number of entries currently in all shards
+func (c *sharded[K, V]) Size() int {
+	size := 0
+	for _, shard := range c.shards {
+		size += shard.Size()
+	}
+	return size
+}
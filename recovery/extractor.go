@@ -0,0 +1,75 @@
+package recovery
+
+import (
+	"errors"
+	"fmt"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+type (
+	// StateExtractor extracts the parameters to restart an execution with from its history. The first event of the
+	// history is the WorkflowExecutionStarted event. Register one for every workflow type that can be recovered.
+	StateExtractor func(workflowID string, history []*historypb.HistoryEvent) (*RestartParams, error)
+
+	// StateExtractors are the StateExtractor of each workflow type, keyed by workflow type name.
+	// Put them on the activity context with StateExtractorsKey.
+	StateExtractors map[string]StateExtractor
+)
+
+// ErrStateExtractorsNotFound when state extractors are not found on context
+var ErrStateExtractorsNotFound = errors.New("failed to retrieve state extractors from context")
+
+// Register registers the extractor of the workflow type.
+func (e StateExtractors) Register(workflowType string, extractor StateExtractor) {
+	e[workflowType] = extractor
+}
+
+// ReplayStateExtractor restarts the execution with the input of its first run and replays all signals, without
+// decoding the payloads. It suits workflow types that don't need their state fixed up to be restarted.
+func ReplayStateExtractor(workflowID string, history []*historypb.HistoryEvent) (*RestartParams, error) {
+	params, err := restartParamsFromEvent(workflowID, history[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, payload := range history[0].GetWorkflowExecutionStartedEventAttributes().GetInput().GetPayloads() {
+		params.Args = append(params.Args, converter.NewRawValue(payload))
+	}
+
+	for _, event := range history {
+		if event.GetEventType() != enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED {
+			continue
+		}
+		attr := event.GetWorkflowExecutionSignaledEventAttributes()
+		signal := &SignalParams{Name: attr.GetSignalName()}
+		if payloads := attr.GetInput().GetPayloads(); len(payloads) > 0 {
+			signal.Data = converter.NewRawValue(payloads[0])
+		}
+		params.Signals = append(params.Signals, signal)
+	}
+
+	return params, nil
+}
+
+// restartParamsFromEvent returns the RestartParams to start a new run of the same workflow type on the same task
+// queue as the WorkflowExecutionStarted event, without any arguments or signals.
+func restartParamsFromEvent(workflowID string, event *historypb.HistoryEvent) (*RestartParams, error) {
+	if event.GetEventType() != enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED {
+		return nil, fmt.Errorf("unexpected first event type %v", event.GetEventType())
+	}
+
+	attr := event.GetWorkflowExecutionStartedEventAttributes()
+	return &RestartParams{
+		Options: client.StartWorkflowOptions{
+			ID:                  workflowID,
+			TaskQueue:           attr.TaskQueue.GetName(),
+			WorkflowTaskTimeout: attr.GetWorkflowTaskTimeout().AsDuration(),
+			// RetryPolicy: attr.RetryPolicy,
+		},
+		WorkflowType: attr.GetWorkflowType().GetName(),
+	}, nil
+}
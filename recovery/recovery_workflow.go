@@ -3,19 +3,19 @@ package recovery
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/pborman/uuid"
 	commonpb "go.temporal.io/api/common/v1"
 	enumspb "go.temporal.io/api/enums/v1"
-	filterpb "go.temporal.io/api/filter/v1"
 	historypb "go.temporal.io/api/history/v1"
 	"go.temporal.io/api/workflowservice/v1"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"golang.org/x/time/rate"
 
 	"github.com/temporalio/samples-go/recovery/cache"
 )
@@ -23,31 +23,116 @@ import (
 type (
 	// Params is the input parameters to RecoveryWorkflow
 	Params struct {
-		ID          string
-		Type        string
+		ID string
+		// Type selects the open executions of the workflow type when Query is empty.
+		Type string
+		// Query is a visibility query that selects the executions to recover, for example
+		// "WorkflowType = 'TripWorkflow' AND ExecutionStatus = 'Running'".
+		// Restarted executions start new runs, which must not be listed again on later pages.
+		Query string
+		// Concurrency is the number of activities recovering the executions of a page in parallel.
 		Concurrency int
+		// PageSize is the number of executions listed at a time. Defaults to 100.
+		PageSize int
+		// DryRun only reports the executions that would be restarted.
+		DryRun bool
+		// RestartsPerSecond limits the restarts of all activities together. Zero means no limit.
+		RestartsPerSecond float64
+		// Checkpoint is the progress made by the previous runs, set on continue-as-new.
+		Checkpoint *Checkpoint
+	}
+
+	// Checkpoint is the progress of a recovery, carried over on continue-as-new.
+	Checkpoint struct {
+		// NextPageToken of the next page of executions to recover.
+		NextPageToken []byte
+		Result        RecoveryResult
+	}
+
+	// RecoveryResult is the result of RecoverWorkflow
+	RecoveryResult struct {
+		// Restarted is the number of restarted executions, or in dry-run mode the number of executions that would be
+		// restarted.
+		Restarted int
+		// Executions reports the executions that would be restarted in dry-run mode, up to maxReportedExecutions.
+		Executions []ExecutionReport
+		// Failed is the number of executions that could not be recovered. Recover them again with a query that
+		// selects them once the cause is fixed.
+		Failed int
+		// Failures reports the executions that could not be recovered, up to maxReportedExecutions.
+		Failures []ExecutionFailure
+	}
+
+	// ExecutionReport describes an execution that would be restarted.
+	ExecutionReport struct {
+		WorkflowID   string
+		RunID        string
+		WorkflowType string
+		// Running is set when the current run would be terminated before the restart.
+		Running bool
+		// Signals is the number of signals that would be replayed to the new run.
+		Signals int
+	}
+
+	// ExecutionFailure describes an execution that could not be recovered.
+	ExecutionFailure struct {
+		WorkflowID string
+		RunID      string
+		Error      string
+	}
+
+	// ListOpenExecutionsParams are the parameters of listOpenExecutions activity
+	ListOpenExecutionsParams struct {
+		Query         string
+		PageSize      int
+		NextPageToken []byte
 	}
 
 	// ListOpenExecutionsResult is the result returned from listOpenExecutions activity
 	ListOpenExecutionsResult struct {
-		ID     string
-		Count  int
-		HostID string
+		ID            string
+		Count         int
+		HostID        string
+		NextPageToken []byte
 	}
 
-	// RestartParams are parameters extracted from StartWorkflowExecution history event
+	// RecoverExecutionsParams are the parameters of RecoverExecutions activity
+	RecoverExecutionsParams struct {
+		// Key of the executions returned by listOpenExecutions activity
+		Key        string
+		StartIndex int
+		BatchSize  int
+		DryRun     bool
+		// RestartsPerSecond limits the restarts of this activity. Zero means no limit.
+		RestartsPerSecond float64
+	}
+
+	// RecoverExecutionsProgress is recorded as heartbeat by RecoverExecutions activity
+	RecoverExecutionsProgress struct {
+		FinishedIndex int
+		Result        RecoveryResult
+	}
+
+	// RestartParams are parameters extracted from history by a StateExtractor
 	RestartParams struct {
-		Options client.StartWorkflowOptions
-		State   UserState
+		Options      client.StartWorkflowOptions
+		WorkflowType string
+		Args         []interface{}
+		// Signals are sent to the new run in order.
+		Signals []*SignalParams
 	}
 
 	// SignalParams are the parameters extracted from SignalWorkflowExecution history event
 	SignalParams struct {
 		Name string
-		Data TripEvent
+		Data interface{}
 	}
 )
 
+// ExecutionsCache caches the executions listed by listOpenExecutions activity by key.
+// Put one on the activity context with WorkflowExecutionCacheKey.
+type ExecutionsCache = cache.Cache[string, []*commonpb.WorkflowExecution]
+
 // ClientKey is the key for lookup
 type ClientKey int
 
@@ -56,6 +141,16 @@ const (
 	TemporalClientKey ClientKey = iota
 	// WorkflowExecutionCacheKey for retrieving executions cache from context
 	WorkflowExecutionCacheKey
+	// StateExtractorsKey for retrieving StateExtractors from context
+	StateExtractorsKey
+)
+
+const (
+	defaultPageSize = 100
+	// pagesPerRun is the number of pages recovered before continue-as-new.
+	pagesPerRun = 10
+	// maxReportedExecutions limits the size of the dry-run report.
+	maxReportedExecutions = 1000
 )
 
 // HostID - Use a new uuid just for demo so we can run 2 host specific activity workers on same machine.
@@ -67,24 +162,79 @@ var (
 	ErrClientNotFound = errors.New("failed to retrieve client from context")
 	// ErrExecutionCacheNotFound when executions cache is not found on context
 	ErrExecutionCacheNotFound = errors.New("failed to retrieve cache from context")
+	// ErrExecutionsNotCached when the executions listed by listOpenExecutions activity are not in the cache
+	ErrExecutionsNotCached = errors.New("listed executions not found in cache")
 )
 
-// RecoverWorkflow is the workflow implementation to recover TripWorkflow executions
-func RecoverWorkflow(ctx workflow.Context, params Params) error {
+// RecoverWorkflow restarts the executions selected by a visibility query and replays their signals to the new runs.
+// The restart parameters are extracted from the history by the StateExtractor registered for the workflow type.
+// Executions are recovered a page at a time, and the progress is carried over on continue-as-new.
+func RecoverWorkflow(ctx workflow.Context, params Params) (*RecoveryResult, error) {
 	logger := workflow.GetLogger(ctx)
-	logger.Info("Recover workflow started.")
+	logger.Info("Recover workflow started.", "DryRun", params.DryRun)
 
-	ao := workflow.ActivityOptions{
-		StartToCloseTimeout: 10 * time.Minute,
-		HeartbeatTimeout:    30 * time.Second,
+	query := params.Query
+	if query == "" {
+		if params.Type == "" {
+			return nil, temporal.NewApplicationError("either Query or Type is required", "invalidInput")
+		}
+		query = fmt.Sprintf("WorkflowType = '%s' AND ExecutionStatus = 'Running'", params.Type)
+	}
+	pageSize := defaultPageSize
+	if params.PageSize > 0 {
+		pageSize = params.PageSize
+	}
+	checkpoint := Checkpoint{}
+	if params.Checkpoint != nil {
+		checkpoint = *params.Checkpoint
 	}
-	ctx = workflow.WithActivityOptions(ctx, ao)
 
-	var result ListOpenExecutionsResult
-	err := workflow.ExecuteActivity(ctx, ListOpenExecutions, params.Type).Get(ctx, &result)
-	if err != nil {
-		logger.Error("Failed to list open workflow executions.", "Error", err)
-		return err
+	for pages := 0; ; pages++ {
+		if pages >= pagesPerRun || workflow.GetInfo(ctx).GetContinueAsNewSuggested() {
+			logger.Info("Continuing as new.", "Restarted", checkpoint.Result.Restarted)
+			params.Checkpoint = &checkpoint
+			return nil, workflow.NewContinueAsNewError(ctx, RecoverWorkflow, params)
+		}
+
+		ao := workflow.ActivityOptions{
+			StartToCloseTimeout: 10 * time.Minute,
+			HeartbeatTimeout:    30 * time.Second,
+		}
+		listCtx := workflow.WithActivityOptions(ctx, ao)
+
+		var result ListOpenExecutionsResult
+		err := workflow.ExecuteActivity(listCtx, ListOpenExecutions, ListOpenExecutionsParams{
+			Query:         query,
+			PageSize:      pageSize,
+			NextPageToken: checkpoint.NextPageToken,
+		}).Get(ctx, &result)
+		if err != nil {
+			logger.Error("Failed to list open workflow executions.", "Error", err)
+			return nil, err
+		}
+
+		// The checkpoint only moves past the page once all its executions were recovered or reported as failed.
+		if err := recoverPage(ctx, params, result, &checkpoint.Result); err != nil {
+			return nil, err
+		}
+
+		checkpoint.NextPageToken = result.NextPageToken
+		if len(checkpoint.NextPageToken) == 0 {
+			break
+		}
+	}
+
+	logger.Info("Workflow completed.", "Restarted", checkpoint.Result.Restarted, "Failed", checkpoint.Result.Failed)
+
+	return &checkpoint.Result, nil
+}
+
+// recoverPage recovers the executions listed by listOpenExecutions activity with up to params.Concurrency activities
+// and adds their results to total. It returns the error of an activity that failed all its attempts.
+func recoverPage(ctx workflow.Context, params Params, page ListOpenExecutionsResult, total *RecoveryResult) error {
+	logger := workflow.GetLogger(ctx)
+	if page.Count == 0 {
+		return nil
 	}
 
 	concurrency := 1
@@ -92,12 +242,12 @@ func RecoverWorkflow(ctx workflow.Context, params Params) error {
 		concurrency = params.Concurrency
 	}
 
-	if result.Count < concurrency {
-		concurrency = result.Count
+	if page.Count < concurrency {
+		concurrency = page.Count
 	}
 
-	batchSize := result.Count / concurrency
-	if result.Count%concurrency != 0 {
+	batchSize := page.Count / concurrency
+	if page.Count%concurrency != 0 {
 		batchSize++
 	}
 
@@ -110,7 +260,7 @@ func RecoverWorkflow(ctx workflow.Context, params Params) error {
 		MaximumInterval:    10 * time.Second,
 		MaximumAttempts:    100,
 	}
-	ao = workflow.ActivityOptions{
+	ao := workflow.ActivityOptions{
 		StartToCloseTimeout: expiration,
 		HeartbeatTimeout:    30 * time.Second,
 		RetryPolicy:         retryPolicy,
@@ -118,15 +268,27 @@ func RecoverWorkflow(ctx workflow.Context, params Params) error {
 	ctx = workflow.WithActivityOptions(ctx, ao)
 
 	doneCh := workflow.NewChannel(ctx)
+	var recoverErr error
 	for i := 0; i < concurrency; i++ {
-		startIndex := i * batchSize
+		activityParams := RecoverExecutionsParams{
+			Key:               page.ID,
+			StartIndex:        i * batchSize,
+			BatchSize:         batchSize,
+			DryRun:            params.DryRun,
+			RestartsPerSecond: params.RestartsPerSecond / float64(concurrency),
+		}
 
 		workflow.Go(ctx, func(ctx workflow.Context) {
-			err = workflow.ExecuteActivity(ctx, RecoverExecutions, result.ID, startIndex, batchSize).Get(ctx, nil)
+			var result RecoveryResult
+			err := workflow.ExecuteActivity(ctx, RecoverExecutions, activityParams).Get(ctx, &result)
 			if err != nil {
-				logger.Error("Recover executions failed.", "StartIndex", startIndex, "Error", err)
+				logger.Error("Recover executions failed.", "StartIndex", activityParams.StartIndex, "Error", err)
+				if recoverErr == nil {
+					recoverErr = err
+				}
 			} else {
-				logger.Info("Recover executions completed.", "StartIndex", startIndex)
+				logger.Info("Recover executions completed.", "StartIndex", activityParams.StartIndex)
+				total.add(result)
 			}
 
 			doneCh.Send(ctx, "done")
@@ -136,17 +298,31 @@ func RecoverWorkflow(ctx workflow.Context, params Params) error {
 	for i := 0; i < concurrency; i++ {
 		doneCh.Receive(ctx, nil)
 	}
+	return recoverErr
+}
 
-	logger.Info("Workflow completed.", "Result", result.Count)
-
-	return nil
+func (r *RecoveryResult) add(other RecoveryResult) {
+	r.Restarted += other.Restarted
+	for _, execution := range other.Executions {
+		if len(r.Executions) >= maxReportedExecutions {
+			break
+		}
+		r.Executions = append(r.Executions, execution)
+	}
+	r.Failed += other.Failed
+	for _, failure := range other.Failures {
+		if len(r.Failures) >= maxReportedExecutions {
+			break
+		}
+		r.Failures = append(r.Failures, failure)
+	}
 }
 
-func ListOpenExecutions(ctx context.Context, workflowType string) (*ListOpenExecutionsResult, error) {
+func ListOpenExecutions(ctx context.Context, params ListOpenExecutionsParams) (*ListOpenExecutionsResult, error) {
 	key := uuid.New()
 	logger := activity.GetLogger(ctx)
-	logger.Info("List all open executions of type.",
-		"WorkflowType", workflowType,
+	logger.Info("List a page of open executions.",
+		"Query", params.Query,
 		"HostID", HostID)
 
 	c, err := getClientFromContext(ctx)
@@ -154,118 +330,183 @@ func ListOpenExecutions(ctx context.Context, workflowType string) (*ListOpenExec
 		return nil, err
 	}
 
-	executionsCache := ctx.Value(WorkflowExecutionCacheKey).(cache.Cache)
-	if executionsCache == nil {
+	executionsCache, ok := ctx.Value(WorkflowExecutionCacheKey).(ExecutionsCache)
+	if !ok {
 		logger.Error("Could not retrieve cache from context.")
 		return nil, ErrExecutionCacheNotFound
 	}
 
-	openExecutions, err := getAllExecutionsOfType(ctx, c, workflowType)
+	resp, err := c.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+		Namespace:     client.DefaultNamespace,
+		PageSize:      int32(params.PageSize),
+		NextPageToken: params.NextPageToken,
+		Query:         params.Query,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	var openExecutions []*commonpb.WorkflowExecution
+	for _, r := range resp.Executions {
+		openExecutions = append(openExecutions, r.Execution)
+	}
+
 	executionsCache.Put(key, openExecutions)
 	return &ListOpenExecutionsResult{
-		ID:     key,
-		Count:  len(openExecutions),
-		HostID: HostID,
+		ID:            key,
+		Count:         len(openExecutions),
+		HostID:        HostID,
+		NextPageToken: resp.NextPageToken,
 	}, nil
 }
 
-func RecoverExecutions(ctx context.Context, key string, startIndex, batchSize int) error {
+func RecoverExecutions(ctx context.Context, params RecoverExecutionsParams) (*RecoveryResult, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Starting execution recovery.",
 		"HostID", HostID,
-		"Key", key,
-		"StartIndex", startIndex,
-		"BatchSize", batchSize)
+		"Key", params.Key,
+		"StartIndex", params.StartIndex,
+		"BatchSize", params.BatchSize,
+		"DryRun", params.DryRun)
 
-	executionsCache := ctx.Value(WorkflowExecutionCacheKey).(cache.Cache)
-	if executionsCache == nil {
+	executionsCache, ok := ctx.Value(WorkflowExecutionCacheKey).(ExecutionsCache)
+	if !ok {
 		logger.Error("Could not retrieve cache from context.")
-		return ErrExecutionCacheNotFound
+		return nil, ErrExecutionCacheNotFound
 	}
 
-	openExecutions := executionsCache.Get(key).([]*commonpb.WorkflowExecution)
-	endIndex := startIndex + batchSize
+	openExecutions, ok := executionsCache.Get(params.Key)
+	if !ok {
+		// Evicted, or listed by another host
+		logger.Error("Executions not found in cache.", "Key", params.Key)
+		return nil, ErrExecutionsNotCached
+	}
+	startIndex := params.StartIndex
+	endIndex := params.StartIndex + params.BatchSize
 
 	// Check if this activity has previous heartbeat to retrieve progress from it
+	var progress RecoverExecutionsProgress
 	if activity.HasHeartbeatDetails(ctx) {
-		var finishedIndex int
-		if err := activity.GetHeartbeatDetails(ctx, &finishedIndex); err == nil {
+		if err := activity.GetHeartbeatDetails(ctx, &progress); err == nil {
 			// we have finished progress
-			startIndex = finishedIndex + 1 // start from next one.
+			startIndex = progress.FinishedIndex + 1 // start from next one.
 		}
 	}
 
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if params.RestartsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(params.RestartsPerSecond), 1)
+	}
+
 	for index := startIndex; index < endIndex && index < len(openExecutions); index++ {
 		execution := openExecutions[index]
-		if err := recoverSingleExecution(ctx, execution.GetWorkflowId()); err != nil {
+		report, err := recoverSingleExecution(ctx, execution, params.DryRun, limiter)
+		if err != nil && ctx.Err() != nil {
+			// Timed out or cancelled, the next attempt continues from the last heartbeat.
+			return nil, err
+		} else if err != nil {
+			// A single execution does not stop the recovery of the others, it is reported instead.
 			logger.Error("Failed to recover execution.",
 				"WorkflowID", execution.GetWorkflowId(),
 				"Error", err)
-			return err
+			progress.Result.add(RecoveryResult{Failed: 1, Failures: []ExecutionFailure{{
+				WorkflowID: execution.GetWorkflowId(),
+				RunID:      execution.GetRunId(),
+				Error:      err.Error(),
+			}}})
+		} else if report != nil {
+			progress.Result.Restarted++
+			if params.DryRun {
+				progress.Result.add(RecoveryResult{Executions: []ExecutionReport{*report}})
+			}
 		}
 
 		// Record a heartbeat after each recovery of execution
-		activity.RecordHeartbeat(ctx, index)
+		progress.FinishedIndex = index
+		activity.RecordHeartbeat(ctx, progress)
 	}
 
-	return nil
+	return &progress.Result, nil
 }
 
-func recoverSingleExecution(ctx context.Context, workflowID string) error {
+// recoverSingleExecution restarts the execution and returns its report, or nil when there is nothing to recover.
+// In dry-run mode it only returns the report.
+func recoverSingleExecution(ctx context.Context, listed *commonpb.WorkflowExecution, dryRun bool, limiter *rate.Limiter) (*ExecutionReport, error) {
 	logger := activity.GetLogger(ctx)
 	c, err := getClientFromContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	extractors, ok := ctx.Value(StateExtractorsKey).(StateExtractors)
+	if !ok {
+		logger.Error("Could not retrieve state extractors from context.")
+		return nil, ErrStateExtractorsNotFound
 	}
 
+	// Recovers the current run, which may have continued as new since it was listed
+	workflowID := listed.GetWorkflowId()
 	execution := &commonpb.WorkflowExecution{
 		WorkflowId: workflowID,
 	}
 	history, err := getHistory(ctx, execution)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(history) == 0 {
 		// Nothing to recover
-		return nil
+		return nil, nil
 	}
 
 	firstEvent := history[0]
 	lastEvent := history[len(history)-1]
 
-	// Extract information from StartWorkflowExecution parameters so we can start a new run
-	params, err := extractStateFromEvent(workflowID, firstEvent)
-	if err != nil {
-		return err
+	workflowType := firstEvent.GetWorkflowExecutionStartedEventAttributes().GetWorkflowType().GetName()
+	extractor, ok := extractors[workflowType]
+	if !ok {
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("no state extractor registered for workflow type %q", workflowType), "unknownWorkflowType", nil)
 	}
 
-	// Parse the entire history and extract all signals so they can be replayed back to new run
-	signals, err := extractSignals(history)
+	// Extract the parameters to start a new run and the signals to replay to it from the history
+	params, err := extractor(workflowID, history)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	report := &ExecutionReport{
+		WorkflowID:   workflowID,
+		RunID:        listed.GetRunId(),
+		WorkflowType: params.WorkflowType,
+		Running:      !isExecutionCompleted(lastEvent),
+		Signals:      len(params.Signals),
+	}
+	if dryRun {
+		logger.Info("Would restart workflow.", "WorkflowID", workflowID, "WorkflowType", params.WorkflowType)
+		return report, nil
+	}
+
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, err
 	}
 
 	// First terminate existing run if already running
-	if !isExecutionCompleted(lastEvent) {
+	if report.Running {
 		err := c.TerminateWorkflow(ctx, execution.GetWorkflowId(), execution.GetRunId(), "Recover", nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// Start new execution run
-	newRun, err := c.ExecuteWorkflow(ctx, params.Options, "TripWorkflow", params.State)
+	newRun, err := c.ExecuteWorkflow(ctx, params.Options, params.WorkflowType, params.Args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// re-inject all signals to new run
-	for _, s := range signals {
+	for _, s := range params.Signals {
 		_ = c.SignalWorkflow(ctx, execution.GetWorkflowId(), newRun.GetRunID(), s.Name, s.Data)
 	}
 
@@ -273,54 +514,7 @@ func recoverSingleExecution(ctx context.Context, workflowID string) error {
 		"WorkflowID", execution.GetWorkflowId(),
 		"NewRunID", newRun.GetRunID())
 
-	return nil
-}
-
-func extractStateFromEvent(workflowID string, event *historypb.HistoryEvent) (*RestartParams, error) {
-	switch event.GetEventType() {
-	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED:
-		attr := event.GetWorkflowExecutionStartedEventAttributes()
-		state, err := deserializeUserState(attr.GetInput())
-		if err != nil {
-			// Corrupted Workflow Execution State
-			return nil, err
-		}
-		return &RestartParams{
-			Options: client.StartWorkflowOptions{
-				ID:                  workflowID,
-				TaskQueue:           attr.TaskQueue.GetName(),
-				WorkflowTaskTimeout: attr.GetWorkflowTaskTimeout().AsDuration(),
-				// RetryPolicy: attr.RetryPolicy,
-			},
-			State: state,
-		}, nil
-	default:
-		return nil, errors.New("unknown event type")
-	}
-}
-
-func extractSignals(events []*historypb.HistoryEvent) ([]*SignalParams, error) {
-	var signals []*SignalParams
-	for _, event := range events {
-		if event.GetEventType() == enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED {
-			attr := event.GetWorkflowExecutionSignaledEventAttributes()
-			if attr.GetSignalName() == TripSignalName && attr.GetInput() != nil {
-				signalData, err := deserializeTripEvent(attr.GetInput())
-				if err != nil {
-					// Corrupted Signal Payload
-					return nil, err
-				}
-
-				signal := &SignalParams{
-					Name: attr.GetSignalName(),
-					Data: signalData,
-				}
-				signals = append(signals, signal)
-			}
-		}
-	}
-
-	return signals, nil
+	return report, nil
 }
 
 func isExecutionCompleted(event *historypb.HistoryEvent) bool {
@@ -334,39 +528,6 @@ func isExecutionCompleted(event *historypb.HistoryEvent) bool {
 	}
 }
 
-func getAllExecutionsOfType(ctx context.Context, c client.Client, workflowType string) ([]*commonpb.WorkflowExecution, error) {
-	var openExecutions []*commonpb.WorkflowExecution
-	var nextPageToken []byte
-	for hasMore := true; hasMore; hasMore = len(nextPageToken) > 0 {
-		zeroTime := time.Time{}
-		now := time.Now()
-		resp, err := c.ListOpenWorkflow(ctx, &workflowservice.ListOpenWorkflowExecutionsRequest{
-			Namespace:       client.DefaultNamespace,
-			MaximumPageSize: 10,
-			NextPageToken:   nextPageToken,
-			StartTimeFilter: &filterpb.StartTimeFilter{
-				EarliestTime: timestamppb.New(zeroTime),
-				LatestTime:   timestamppb.New(now),
-			},
-			Filters: &workflowservice.ListOpenWorkflowExecutionsRequest_TypeFilter{TypeFilter: &filterpb.WorkflowTypeFilter{
-				Name: workflowType,
-			}},
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		for _, r := range resp.Executions {
-			openExecutions = append(openExecutions, r.Execution)
-		}
-
-		nextPageToken = resp.NextPageToken
-		activity.RecordHeartbeat(ctx, nextPageToken)
-	}
-
-	return openExecutions, nil
-}
-
 func getHistory(ctx context.Context, execution *commonpb.WorkflowExecution) ([]*historypb.HistoryEvent, error) {
 	c, err := getClientFromContext(ctx)
 	if err != nil {
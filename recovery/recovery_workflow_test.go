@@ -0,0 +1,276 @@
+package recovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	taskqueuepb "go.temporal.io/api/taskqueue/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/temporalio/samples-go/recovery/cache"
+)
+
+func newRecoverWorkflowEnv() *testsuite.TestWorkflowEnvironment {
+	env := (&testsuite.WorkflowTestSuite{}).NewTestWorkflowEnvironment()
+	env.RegisterActivity(ListOpenExecutions)
+	env.RegisterActivity(RecoverExecutions)
+	// The recover activities time out with the workflow execution.
+	env.SetStartWorkflowOptions(client.StartWorkflowOptions{WorkflowExecutionTimeout: time.Hour})
+	return env
+}
+
+func Test_RecoverWorkflow_DryRun(t *testing.T) {
+	env := newRecoverWorkflowEnv()
+	env.OnActivity(ListOpenExecutions, mock.Anything, ListOpenExecutionsParams{
+		Query:    "WorkflowType = 'TripWorkflow' AND ExecutionStatus = 'Running'",
+		PageSize: defaultPageSize,
+	}).Return(&ListOpenExecutionsResult{ID: "page-1", Count: 3}, nil).Once()
+	var batches []RecoverExecutionsParams
+	env.OnActivity(RecoverExecutions, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, params RecoverExecutionsParams) (*RecoveryResult, error) {
+			batches = append(batches, params)
+			result := &RecoveryResult{}
+			for i := params.StartIndex; i < params.StartIndex+params.BatchSize && i < 3; i++ {
+				result.Restarted++
+				result.Executions = append(result.Executions, ExecutionReport{WorkflowType: "TripWorkflow"})
+			}
+			return result, nil
+		})
+
+	env.ExecuteWorkflow(RecoverWorkflow, Params{Type: "TripWorkflow", Concurrency: 2, DryRun: true})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result RecoveryResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, 3, result.Restarted)
+	require.Len(t, result.Executions, 3)
+	require.Len(t, batches, 2)
+	for _, batch := range batches {
+		require.True(t, batch.DryRun)
+		require.Equal(t, "page-1", batch.Key)
+		require.Equal(t, 2, batch.BatchSize)
+	}
+	env.AssertExpectations(t)
+}
+
+func Test_RecoverWorkflow_ContinueAsNew(t *testing.T) {
+	env := newRecoverWorkflowEnv()
+	var tokens []string
+	env.OnActivity(ListOpenExecutions, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, params ListOpenExecutionsParams) (*ListOpenExecutionsResult, error) {
+			tokens = append(tokens, string(params.NextPageToken))
+			return &ListOpenExecutionsResult{ID: "page", Count: 1, NextPageToken: []byte("next")}, nil
+		})
+	env.OnActivity(RecoverExecutions, mock.Anything, mock.Anything).Return(&RecoveryResult{Restarted: 1}, nil)
+
+	env.ExecuteWorkflow(RecoverWorkflow, Params{
+		Type:       "TripWorkflow",
+		Checkpoint: &Checkpoint{NextPageToken: []byte("start"), Result: RecoveryResult{Restarted: 5}},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	var continueAsNewErr *workflow.ContinueAsNewError
+	require.ErrorAs(t, env.GetWorkflowError(), &continueAsNewErr)
+	var params Params
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayloads(continueAsNewErr.Input, &params))
+	// The run resumed from the checkpoint and carries the progress of its pages over.
+	require.Len(t, tokens, pagesPerRun)
+	require.Equal(t, "start", tokens[0])
+	require.Equal(t, "next", tokens[1])
+	require.Equal(t, "TripWorkflow", params.Type)
+	require.Equal(t, []byte("next"), params.Checkpoint.NextPageToken)
+	require.Equal(t, 5+pagesPerRun, params.Checkpoint.Result.Restarted)
+
+	// The next run completes with the total once the last page is recovered.
+	env = newRecoverWorkflowEnv()
+	env.OnActivity(ListOpenExecutions, mock.Anything, mock.Anything).
+		Return(&ListOpenExecutionsResult{ID: "page", Count: 1}, nil).Once()
+	env.OnActivity(RecoverExecutions, mock.Anything, mock.Anything).Return(&RecoveryResult{Restarted: 1}, nil)
+
+	env.ExecuteWorkflow(RecoverWorkflow, params)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result RecoveryResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, 6+pagesPerRun, result.Restarted)
+}
+
+func Test_RecoverWorkflow_RecoverFails(t *testing.T) {
+	env := newRecoverWorkflowEnv()
+	env.OnActivity(ListOpenExecutions, mock.Anything, mock.Anything).
+		Return(&ListOpenExecutionsResult{ID: "page", Count: 1, NextPageToken: []byte("next")}, nil).Once()
+	env.OnActivity(RecoverExecutions, mock.Anything, mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("evicted", "test", ErrExecutionsNotCached))
+
+	env.ExecuteWorkflow(RecoverWorkflow, Params{Type: "TripWorkflow"})
+
+	// The next page is not listed, the executions of the failed page would be skipped.
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), "evicted")
+	env.AssertExpectations(t)
+}
+
+// historyIterator is a client.HistoryEventIterator over the given events.
+type historyIterator struct {
+	events []*historypb.HistoryEvent
+}
+
+func (it *historyIterator) HasNext() bool {
+	return len(it.events) > 0
+}
+
+func (it *historyIterator) Next() (*historypb.HistoryEvent, error) {
+	event := it.events[0]
+	it.events = it.events[1:]
+	return event, nil
+}
+
+func startedEvent(t *testing.T, workflowType string, input interface{}) *historypb.HistoryEvent {
+	payloads, err := converter.GetDefaultDataConverter().ToPayloads(input)
+	require.NoError(t, err)
+	return &historypb.HistoryEvent{
+		EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED,
+		Attributes: &historypb.HistoryEvent_WorkflowExecutionStartedEventAttributes{
+			WorkflowExecutionStartedEventAttributes: &historypb.WorkflowExecutionStartedEventAttributes{
+				WorkflowType: &commonpb.WorkflowType{Name: workflowType},
+				TaskQueue:    &taskqueuepb.TaskQueue{Name: "recovery"},
+				Input:        payloads,
+			},
+		},
+	}
+}
+
+func signaledEvent(t *testing.T, name string, input interface{}) *historypb.HistoryEvent {
+	payloads, err := converter.GetDefaultDataConverter().ToPayloads(input)
+	require.NoError(t, err)
+	return &historypb.HistoryEvent{
+		EventType: enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED,
+		Attributes: &historypb.HistoryEvent_WorkflowExecutionSignaledEventAttributes{
+			WorkflowExecutionSignaledEventAttributes: &historypb.WorkflowExecutionSignaledEventAttributes{
+				SignalName: name,
+				Input:      payloads,
+			},
+		},
+	}
+}
+
+// newRecoverExecutionsEnv returns an activity environment whose cache holds the executions under the key "page".
+func newRecoverExecutionsEnv(c client.Client, extractors StateExtractors, executions ...*commonpb.WorkflowExecution) *testsuite.TestActivityEnvironment {
+	executionsCache := cache.NewLRU[string, []*commonpb.WorkflowExecution](10)
+	executionsCache.Put("page", executions)
+	ctx := context.WithValue(context.Background(), TemporalClientKey, c)
+	ctx = context.WithValue(ctx, WorkflowExecutionCacheKey, executionsCache)
+	ctx = context.WithValue(ctx, StateExtractorsKey, extractors)
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.SetWorkerOptions(worker.Options{BackgroundActivityContext: ctx})
+	env.RegisterActivity(RecoverExecutions)
+	return env
+}
+
+func Test_RecoverExecutions_DryRun(t *testing.T) {
+	c := &mocks.Client{}
+	c.On("GetWorkflowHistory", mock.Anything, "trip-1", "", false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT).
+		Return(&historyIterator{events: []*historypb.HistoryEvent{
+			startedEvent(t, "TripWorkflow", UserState{TripCounter: 3}),
+			signaledEvent(t, TripSignalName, TripEvent{ID: "trip", Total: 10}),
+		}})
+	extractors := StateExtractors{}
+	extractors.Register("TripWorkflow", ExtractTripWorkflowState)
+	env := newRecoverExecutionsEnv(c, extractors, &commonpb.WorkflowExecution{WorkflowId: "trip-1", RunId: "run-1"})
+
+	val, err := env.ExecuteActivity(RecoverExecutions, RecoverExecutionsParams{Key: "page", BatchSize: 1, DryRun: true})
+	require.NoError(t, err)
+	var result RecoveryResult
+	require.NoError(t, val.Get(&result))
+	require.Equal(t, RecoveryResult{
+		Restarted: 1,
+		Executions: []ExecutionReport{
+			{WorkflowID: "trip-1", RunID: "run-1", WorkflowType: "TripWorkflow", Running: true, Signals: 1},
+		},
+	}, result)
+	// Nothing was terminated or started.
+	c.AssertExpectations(t)
+}
+
+func Test_RecoverExecutions_Extractors(t *testing.T) {
+	c := &mocks.Client{}
+	c.On("GetWorkflowHistory", mock.Anything, "trip-1", "", false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT).
+		Return(&historyIterator{events: []*historypb.HistoryEvent{
+			startedEvent(t, "TripWorkflow", UserState{TripCounter: 3}),
+			signaledEvent(t, TripSignalName, TripEvent{ID: "trip", Total: 10}),
+		}})
+	c.On("GetWorkflowHistory", mock.Anything, "other-1", "", false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT).
+		Return(&historyIterator{events: []*historypb.HistoryEvent{
+			startedEvent(t, "OtherWorkflow", "input"),
+		}})
+	run := &mocks.WorkflowRun{}
+	run.On("GetRunID").Return("run-2")
+	c.On("TerminateWorkflow", mock.Anything, "trip-1", "", "Recover", nil).Return(nil).Once()
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, "TripWorkflow", UserState{TripCounter: 3}).Return(run, nil).Once()
+	c.On("SignalWorkflow", mock.Anything, "trip-1", "run-2", TripSignalName, TripEvent{ID: "trip", Total: 10}).Return(nil).Once()
+
+	// Only TripWorkflow has a registered extractor, OtherWorkflow cannot be recovered.
+	extractors := StateExtractors{}
+	extractors.Register("TripWorkflow", ExtractTripWorkflowState)
+	env := newRecoverExecutionsEnv(c, extractors,
+		&commonpb.WorkflowExecution{WorkflowId: "other-1", RunId: "run-1"},
+		&commonpb.WorkflowExecution{WorkflowId: "trip-1", RunId: "run-1"})
+
+	val, err := env.ExecuteActivity(RecoverExecutions, RecoverExecutionsParams{Key: "page", BatchSize: 2})
+	require.NoError(t, err)
+	var result RecoveryResult
+	require.NoError(t, val.Get(&result))
+	require.Equal(t, 1, result.Restarted)
+	require.Equal(t, 1, result.Failed)
+	require.Len(t, result.Failures, 1)
+	require.Equal(t, "other-1", result.Failures[0].WorkflowID)
+	require.Contains(t, result.Failures[0].Error, `no state extractor registered for workflow type "OtherWorkflow"`)
+	c.AssertExpectations(t)
+}
+
+func Test_RecoverExecutions_NotCached(t *testing.T) {
+	env := newRecoverExecutionsEnv(&mocks.Client{}, StateExtractors{})
+
+	_, err := env.ExecuteActivity(RecoverExecutions, RecoverExecutionsParams{Key: "evicted", BatchSize: 1})
+	require.ErrorContains(t, err, ErrExecutionsNotCached.Error())
+}
+
+func Test_ReplayStateExtractor(t *testing.T) {
+	params, err := ReplayStateExtractor("trip-1", []*historypb.HistoryEvent{
+		startedEvent(t, "TripWorkflow", UserState{TripCounter: 3}),
+		signaledEvent(t, TripSignalName, TripEvent{ID: "trip", Total: 10}),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "trip-1", params.Options.ID)
+	require.Equal(t, "recovery", params.Options.TaskQueue)
+	require.Equal(t, "TripWorkflow", params.WorkflowType)
+
+	// The input and signals are replayed without decoding them.
+	require.Len(t, params.Args, 1)
+	var state UserState
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayload(params.Args[0].(converter.RawValue).Payload(), &state))
+	require.Equal(t, UserState{TripCounter: 3}, state)
+	require.Len(t, params.Signals, 1)
+	require.Equal(t, TripSignalName, params.Signals[0].Name)
+	var trip TripEvent
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayload(params.Signals[0].Data.(converter.RawValue).Payload(), &trip))
+	require.Equal(t, TripEvent{ID: "trip", Total: 10}, trip)
+
+	_, err = ReplayStateExtractor("trip-1", []*historypb.HistoryEvent{signaledEvent(t, TripSignalName, nil)})
+	require.ErrorContains(t, err, "unexpected first event type")
+}
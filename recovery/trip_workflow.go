@@ -2,6 +2,8 @@ package recovery
 
 import (
 	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
 	"go.temporal.io/sdk/converter"
 	"go.temporal.io/sdk/workflow"
 )
@@ -60,6 +62,43 @@ func TripWorkflow(ctx workflow.Context, state UserState) error {
 	return workflow.NewContinueAsNewError(ctx, "TripWorkflow", state)
 }
 
+// ExtractTripWorkflowState is the StateExtractor of TripWorkflow. It restarts the execution with the UserState of the
+// current run and replays the TripEvent signals.
+func ExtractTripWorkflowState(workflowID string, history []*historypb.HistoryEvent) (*RestartParams, error) {
+	params, err := restartParamsFromEvent(workflowID, history[0])
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := deserializeUserState(history[0].GetWorkflowExecutionStartedEventAttributes().GetInput())
+	if err != nil {
+		// Corrupted Workflow Execution State
+		return nil, err
+	}
+	params.Args = []interface{}{state}
+
+	// Parse the entire history and extract all signals so they can be replayed back to new run
+	for _, event := range history {
+		if event.GetEventType() == enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED {
+			attr := event.GetWorkflowExecutionSignaledEventAttributes()
+			if attr.GetSignalName() == TripSignalName && attr.GetInput() != nil {
+				signalData, err := deserializeTripEvent(attr.GetInput())
+				if err != nil {
+					// Corrupted Signal Payload
+					return nil, err
+				}
+
+				params.Signals = append(params.Signals, &SignalParams{
+					Name: attr.GetSignalName(),
+					Data: signalData,
+				})
+			}
+		}
+	}
+
+	return params, nil
+}
+
 func deserializeUserState(data *commonpb.Payloads) (UserState, error) {
 	var state UserState
 	if err := converter.GetDefaultDataConverter().FromPayloads(data, &state); err != nil {
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"log"
+	"time"
 
+	commonpb "go.temporal.io/api/common/v1"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
 	"go.temporal.io/sdk/workflow"
@@ -23,7 +25,17 @@ func main() {
 	defer c.Close()
 
 	ctx := context.WithValue(context.Background(), recovery.TemporalClientKey, c)
-	ctx = context.WithValue(ctx, recovery.WorkflowExecutionCacheKey, cache.NewLRU(10))
+	// Listed executions are shared by the recovery activities of this worker, which run concurrently.
+	executionsCache := cache.NewSharded[string, []*commonpb.WorkflowExecution](4, 100, &cache.Options[[]*commonpb.WorkflowExecution]{
+		TTL: time.Hour,
+	})
+	ctx = context.WithValue(ctx, recovery.WorkflowExecutionCacheKey, executionsCache)
+
+	// Every workflow type that can be recovered registers how to restart it from its history.
+	// recovery.ReplayStateExtractor suits workflow types whose input and signals can be replayed as is.
+	extractors := recovery.StateExtractors{}
+	extractors.Register("TripWorkflow", recovery.ExtractTripWorkflowState)
+	ctx = context.WithValue(ctx, recovery.StateExtractorsKey, extractors)
 
 	w := worker.New(c, "recovery", worker.Options{
 		BackgroundActivityContext: ctx,
@@ -0,0 +1,289 @@
+package reqresp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"go.temporal.io/sdk/client"
+)
+
+// Names of the metrics reported to Options.MetricsHandler.
+const (
+	MetricPendingRequests = "reqresp_pending_requests"
+	MetricRequests        = "reqresp_requests"
+	MetricRequestFailures = "reqresp_request_failures"
+	MetricBatches         = "reqresp_batches"
+	MetricRequestLatency  = "reqresp_request_latency"
+)
+
+// DeadlineExceeded is the error of the result of a request that reached the
+// workflow after its deadline.
+const DeadlineExceeded = "deadline exceeded"
+
+// ErrClosed is returned for requests made after Requester.Close.
+var ErrClosed = errors.New("requester closed")
+
+// Envelope is a request with the ID of its result. Workflows receive requests
+// in batches of envelopes.
+type Envelope[Req any] struct {
+	ID      string `json:"id"`
+	Request Req    `json:"request"`
+	// Deadline after which the requester no longer waits for the result, zero
+	// if there is none. Workflows should not handle expired requests.
+	Deadline time.Time `json:"deadline,omitempty"`
+}
+
+// Expired reports whether the deadline of the request is before now. Workflows
+// should pass workflow.Now.
+func (e Envelope[Req]) Expired(now time.Time) bool {
+	return !e.Deadline.IsZero() && now.After(e.Deadline)
+}
+
+// Result is the response to the request with the same ID, or its error.
+type Result[Resp any] struct {
+	ID       string `json:"id"`
+	Response Resp   `json:"response"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Transport delivers batches of requests to a workflow and their results back
+// to the requester.
+type Transport[Req, Resp any] interface {
+	// Start is called once by NewRequester with the function that results are
+	// delivered to. The function is safe for concurrent use.
+	Start(deliver func(Result[Resp])) error
+	// Send delivers the requests to the workflow. The results may be delivered
+	// before Send returns.
+	Send(ctx context.Context, requests []Envelope[Req]) error
+	// Close releases the resources of the transport.
+	Close()
+}
+
+// Options are options for NewRequester.
+type Options struct {
+	// Maximum number of requests sent together. Default 1, which sends every
+	// request on its own.
+	MaxBatchSize int
+	// How long a request waits for others to fill its batch. Default 10ms.
+	// Only used when MaxBatchSize is greater than 1.
+	BatchInterval time.Duration
+	// Deadline of requests whose context has none. Default no deadline.
+	DefaultTimeout time.Duration
+	// How long sending a batch may take. Default 10 seconds. Transports that
+	// wait for the results when sending, like the update transport, wait for
+	// them within this time.
+	SendTimeout time.Duration
+	// Optional handler of the request metrics.
+	MetricsHandler client.MetricsHandler
+}
+
+// Requester sends requests of type Req to a workflow and waits for responses of
+// type Resp, through a pluggable Transport. It should be closed after use.
+type Requester[Req, Resp any] struct {
+	options   Options
+	transport Transport[Req, Resp]
+	// sends are the batches being sent, which Close waits for.
+	sends sync.WaitGroup
+
+	mu sync.Mutex
+	// Channels need buffer of 1 because they are sent to in non-blocking fashion
+	pending    map[string]chan<- outcome[Resp]
+	batch      []Envelope[Req]
+	batchTimer *time.Timer
+	closed     bool
+
+	pendingGauge client.MetricsGauge
+	requests     client.MetricsCounter
+	failures     client.MetricsCounter
+	batches      client.MetricsCounter
+	latency      client.MetricsTimer
+}
+
+type outcome[Resp any] struct {
+	result Result[Resp]
+	err    error
+}
+
+// NewRequester creates a Requester that sends requests through the transport.
+func NewRequester[Req, Resp any](transport Transport[Req, Resp], options Options) (*Requester[Req, Resp], error) {
+	if transport == nil {
+		return nil, fmt.Errorf("transport required")
+	}
+	if options.MaxBatchSize <= 0 {
+		options.MaxBatchSize = 1
+	}
+	if options.BatchInterval == 0 {
+		options.BatchInterval = 10 * time.Millisecond
+	}
+	if options.SendTimeout == 0 {
+		options.SendTimeout = 10 * time.Second
+	}
+	metricsHandler := options.MetricsHandler
+	if metricsHandler == nil {
+		metricsHandler = client.MetricsNopHandler
+	}
+	r := &Requester[Req, Resp]{
+		options:      options,
+		transport:    transport,
+		pending:      map[string]chan<- outcome[Resp]{},
+		pendingGauge: metricsHandler.Gauge(MetricPendingRequests),
+		requests:     metricsHandler.Counter(MetricRequests),
+		failures:     metricsHandler.Counter(MetricRequestFailures),
+		batches:      metricsHandler.Counter(MetricBatches),
+		latency:      metricsHandler.Timer(MetricRequestLatency),
+	}
+	if err := transport.Start(r.deliver); err != nil {
+		return nil, fmt.Errorf("failed starting transport: %w", err)
+	}
+	return r, nil
+}
+
+// Request sends the request and returns its response. The deadline of the
+// context is sent with the request.
+func (r *Requester[Req, Resp]) Request(ctx context.Context, req Req) (resp Resp, err error) {
+	start := time.Now()
+	r.requests.Inc(1)
+	defer func() {
+		r.latency.Record(time.Since(start))
+		if err != nil {
+			r.failures.Inc(1)
+		}
+	}()
+
+	if _, ok := ctx.Deadline(); !ok && r.options.DefaultTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.options.DefaultTimeout)
+		defer cancel()
+	}
+	env := Envelope[Req]{ID: uuid.New(), Request: req}
+	if deadline, ok := ctx.Deadline(); ok {
+		env.Deadline = deadline
+	}
+
+	// Add channel to pending and queue the request
+	respCh := make(chan outcome[Resp], 1)
+	if err := r.enqueue(env, respCh); err != nil {
+		return resp, err
+	}
+	// Remove pending request when done
+	defer r.remove(env.ID)
+
+	select {
+	case <-ctx.Done():
+		return resp, ctx.Err()
+	case out := <-respCh:
+		if out.err != nil {
+			return resp, out.err
+		}
+		if out.result.Error != "" {
+			return resp, fmt.Errorf("request failed: %v", out.result.Error)
+		}
+		return out.result.Response, nil
+	}
+}
+
+// Pending returns the number of requests waiting for their response.
+func (r *Requester[Req, Resp]) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
+
+// Close sends the queued requests, waits up to SendTimeout for the batches being
+// sent and closes the transport. Callers are expected to not make requests
+// after this and to cancel outstanding requests.
+func (r *Requester[Req, Resp]) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	r.flushLocked()
+	r.mu.Unlock()
+
+	r.sends.Wait()
+	r.transport.Close()
+}
+
+func (r *Requester[Req, Resp]) enqueue(env Envelope[Req], respCh chan<- outcome[Resp]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	r.pending[env.ID] = respCh
+	r.pendingGauge.Update(float64(len(r.pending)))
+
+	r.batch = append(r.batch, env)
+	if len(r.batch) >= r.options.MaxBatchSize {
+		r.flushLocked()
+	} else if r.batchTimer == nil {
+		r.batchTimer = time.AfterFunc(r.options.BatchInterval, func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.flushLocked()
+		})
+	}
+	return nil
+}
+
+func (r *Requester[Req, Resp]) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, id)
+	r.pendingGauge.Update(float64(len(r.pending)))
+}
+
+// flushLocked sends the queued requests in the background. r.mu must be held.
+func (r *Requester[Req, Resp]) flushLocked() {
+	if r.batchTimer != nil {
+		r.batchTimer.Stop()
+		r.batchTimer = nil
+	}
+	if len(r.batch) == 0 {
+		return
+	}
+	batch := r.batch
+	r.batch = nil
+	r.batches.Inc(1)
+
+	r.sends.Add(1)
+	go func() {
+		defer r.sends.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), r.options.SendTimeout)
+		defer cancel()
+		if err := r.transport.Send(ctx, batch); err != nil {
+			err = fmt.Errorf("failed sending request: %w", err)
+			for _, env := range batch {
+				r.complete(env.ID, outcome[Resp]{err: err})
+			}
+		}
+	}()
+}
+
+func (r *Requester[Req, Resp]) deliver(result Result[Resp]) {
+	r.complete(result.ID, outcome[Resp]{result: result})
+}
+
+func (r *Requester[Req, Resp]) complete(id string, out outcome[Resp]) {
+	// Get the channel to respond to
+	r.mu.Lock()
+	respCh := r.pending[id]
+	r.mu.Unlock()
+	// We choose not to log or error if a response is not pending because it is
+	// normal behavior for a requester to have closed the context and stop waiting
+	if respCh == nil {
+		return
+	}
+	// Send non-blocking since the channel should have enough room. A result
+	// delivered twice, for example by a retried response activity, is dropped.
+	select {
+	case respCh <- out:
+	default:
+	}
+}
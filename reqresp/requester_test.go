@@ -0,0 +1,177 @@
+package reqresp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/temporalio/samples-go/reqresp"
+)
+
+// fakeTransport uppercases the requests when they are sent, unless they expired
+// or the send was cancelled.
+type fakeTransport struct {
+	deliver func(reqresp.Result[string])
+	hold    chan struct{}
+
+	mu      sync.Mutex
+	batches [][]reqresp.Envelope[string]
+	closed  bool
+	err     error
+}
+
+func (f *fakeTransport) Start(deliver func(reqresp.Result[string])) error {
+	f.deliver = deliver
+	return nil
+}
+
+func (f *fakeTransport) Send(ctx context.Context, requests []reqresp.Envelope[string]) error {
+	f.mu.Lock()
+	f.batches = append(f.batches, requests)
+	err := f.err
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if f.hold != nil {
+		<-f.hold
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, env := range requests {
+		result := reqresp.Result[string]{ID: env.ID, Response: strings.ToUpper(env.Request)}
+		if env.Expired(time.Now()) {
+			result = reqresp.Result[string]{ID: env.ID, Error: reqresp.DeadlineExceeded}
+		}
+		f.deliver(result)
+	}
+	return nil
+}
+
+func (f *fakeTransport) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+func TestRequester_Batching(t *testing.T) {
+	transport := &fakeTransport{}
+	req, err := reqresp.NewRequester[string, string](transport, reqresp.Options{
+		MaxBatchSize:  3,
+		BatchInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for _, input := range []string{"a", "b", "c"} {
+		input := input
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := req.Request(context.Background(), input)
+			require.NoError(t, err)
+			require.Equal(t, strings.ToUpper(input), res)
+		}()
+	}
+	wg.Wait()
+	req.Close()
+
+	// The three requests filled a batch before the interval passed.
+	require.Len(t, transport.batches, 1)
+	require.Len(t, transport.batches[0], 3)
+	require.True(t, transport.closed)
+	_, err = req.Request(context.Background(), "d")
+	require.ErrorIs(t, err, reqresp.ErrClosed)
+}
+
+func TestRequester_CloseSendsPartialBatch(t *testing.T) {
+	transport := &fakeTransport{}
+	req, err := reqresp.NewRequester[string, string](transport, reqresp.Options{
+		MaxBatchSize:  10,
+		BatchInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	var res string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res, err = req.Request(context.Background(), "a")
+	}()
+	require.Eventually(t, func() bool { return req.Pending() == 1 }, time.Second, time.Millisecond)
+	req.Close()
+	<-done
+
+	// The batch was not full, Close sent it before closing the transport.
+	require.NoError(t, err)
+	require.Equal(t, "A", res)
+	require.Len(t, transport.batches, 1)
+	require.True(t, transport.closed)
+}
+
+func TestRequester_BatchInterval(t *testing.T) {
+	transport := &fakeTransport{}
+	req, err := reqresp.NewRequester[string, string](transport, reqresp.Options{
+		MaxBatchSize:  10,
+		BatchInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer req.Close()
+
+	res, err := req.Request(context.Background(), "SoMe VaLuE")
+	require.NoError(t, err)
+	require.Equal(t, "SOME VALUE", res)
+}
+
+func TestRequester_Deadline(t *testing.T) {
+	transport := &fakeTransport{hold: make(chan struct{})}
+	req, err := reqresp.NewRequester[string, string](transport, reqresp.Options{
+		DefaultTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	before := time.Now()
+	_, err = req.Request(context.Background(), "a")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, req.Pending())
+
+	// The deadline of the request was sent along with it.
+	transport.mu.Lock()
+	deadline := transport.batches[0][0].Deadline
+	transport.mu.Unlock()
+	require.WithinDuration(t, before.Add(50*time.Millisecond), deadline, 40*time.Millisecond)
+	close(transport.hold)
+	req.Close()
+}
+
+func TestRequester_SendError(t *testing.T) {
+	transport := &fakeTransport{err: errors.New("unavailable")}
+	req, err := reqresp.NewRequester[string, string](transport, reqresp.Options{})
+	require.NoError(t, err)
+	defer req.Close()
+
+	_, err = req.Request(context.Background(), "a")
+	require.ErrorContains(t, err, "failed sending request: unavailable")
+}
+
+func TestRequester_Pending(t *testing.T) {
+	transport := &fakeTransport{hold: make(chan struct{})}
+	req, err := reqresp.NewRequester[string, string](transport, reqresp.Options{})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = req.Request(context.Background(), "a")
+	}()
+	require.Eventually(t, func() bool { return req.Pending() == 1 }, time.Second, time.Millisecond)
+	close(transport.hold)
+	<-done
+	require.Zero(t, req.Pending())
+	req.Close()
+}
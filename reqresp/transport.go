@@ -0,0 +1,296 @@
+package reqresp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+)
+
+// Names of the workflow handlers the transports send requests to.
+const (
+	// BatchSignalName is the signal that accepts a Batch.
+	BatchSignalName = "requests"
+	// ResultsQueryName is the query that accepts a slice of request IDs and
+	// returns the results available for them.
+	ResultsQueryName = "results"
+	// BatchUpdateName is the update that accepts a slice of envelopes and
+	// returns their results.
+	BatchUpdateName = "requests"
+	// ResponseActivityName is the activity that the activity transport
+	// registers to receive a Result.
+	ResponseActivityName = "reqresp-response"
+)
+
+// Batch is the argument of the batch signal.
+type Batch[Req any] struct {
+	Requests []Envelope[Req] `json:"requests"`
+	// Activity and task queue to send each result to. Only set by the activity
+	// transport, the query transport polls for the results instead.
+	ResponseActivity  string `json:"response_activity,omitempty"`
+	ResponseTaskQueue string `json:"response_task_queue,omitempty"`
+}
+
+// ActivityTransportOptions are options for NewActivityTransport.
+type ActivityTransportOptions struct {
+	// Client to the Temporal server. Required. Not closed on Close.
+	Client client.Client
+	// ID of the workflow listening for the batch signal. Required.
+	TargetWorkflowID string
+
+	// Visible for testing.
+	ExistingWorker interface {
+		RegisterActivityWithOptions(interface{}, activity.RegisterOptions)
+		Start() error
+		Stop()
+	}
+}
+
+// ActivityTransport signals batches of requests to the workflow, which
+// responds by calling an activity on a worker that the transport runs on its
+// own task queue.
+type ActivityTransport[Req, Resp any] struct {
+	options   ActivityTransportOptions
+	taskQueue string
+	deliver   func(Result[Resp])
+}
+
+// NewActivityTransport creates an ActivityTransport for the given options.
+func NewActivityTransport[Req, Resp any](options ActivityTransportOptions) (*ActivityTransport[Req, Resp], error) {
+	if options.Client == nil {
+		return nil, fmt.Errorf("client required")
+	} else if options.TargetWorkflowID == "" {
+		return nil, fmt.Errorf("target workflow required")
+	}
+	t := &ActivityTransport[Req, Resp]{options: options, taskQueue: "requester-" + uuid.New()}
+	if t.options.ExistingWorker == nil {
+		t.options.ExistingWorker = worker.New(options.Client, t.taskQueue, worker.Options{})
+	}
+	return t, nil
+}
+
+// Start registers the response activity and starts the worker.
+func (t *ActivityTransport[Req, Resp]) Start(deliver func(Result[Resp])) error {
+	t.deliver = deliver
+	t.options.ExistingWorker.RegisterActivityWithOptions(t.responseActivity,
+		activity.RegisterOptions{Name: ResponseActivityName})
+	if err := t.options.ExistingWorker.Start(); err != nil {
+		return fmt.Errorf("failed starting worker: %w", err)
+	}
+	return nil
+}
+
+// Send signals the requests to the workflow.
+func (t *ActivityTransport[Req, Resp]) Send(ctx context.Context, requests []Envelope[Req]) error {
+	batch := &Batch[Req]{
+		Requests:          requests,
+		ResponseActivity:  ResponseActivityName,
+		ResponseTaskQueue: t.taskQueue,
+	}
+	if err := t.options.Client.SignalWorkflow(ctx, t.options.TargetWorkflowID, "", BatchSignalName, batch); err != nil {
+		return fmt.Errorf("failed signaling workflow: %w", err)
+	}
+	return nil
+}
+
+// Close stops the worker. Since this stops the response worker, it does a
+// graceful stop for a period.
+func (t *ActivityTransport[Req, Resp]) Close() {
+	t.options.ExistingWorker.Stop()
+}
+
+func (t *ActivityTransport[Req, Resp]) responseActivity(ctx context.Context, result Result[Resp]) error {
+	t.deliver(result)
+	return nil
+}
+
+// QueryTransportOptions are options for NewQueryTransport.
+type QueryTransportOptions struct {
+	// Client to the Temporal server. Required.
+	Client client.Client
+	// ID of the workflow listening for the batch signal. Required.
+	TargetWorkflowID string
+	// Frequency of query for results. Default 300ms.
+	ResponseQueryInterval time.Duration
+	// How long to wait for the results of a batch. Default 4 seconds. The
+	// requests without a result by then fail.
+	ResponseTimeout time.Duration
+}
+
+// QueryTransport signals batches of requests to the workflow and polls for
+// their results with the results query.
+type QueryTransport[Req, Resp any] struct {
+	options QueryTransportOptions
+	deliver func(Result[Resp])
+	// ctx is cancelled on Close to stop polling.
+	ctx    context.Context
+	cancel context.CancelFunc
+	polls  sync.WaitGroup
+}
+
+// NewQueryTransport creates a QueryTransport for the given options.
+func NewQueryTransport[Req, Resp any](options QueryTransportOptions) (*QueryTransport[Req, Resp], error) {
+	if options.Client == nil {
+		return nil, fmt.Errorf("client required")
+	} else if options.TargetWorkflowID == "" {
+		return nil, fmt.Errorf("target workflow required")
+	}
+	if options.ResponseQueryInterval == 0 {
+		options.ResponseQueryInterval = 300 * time.Millisecond
+	}
+	if options.ResponseTimeout == 0 {
+		options.ResponseTimeout = 4 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &QueryTransport[Req, Resp]{options: options, ctx: ctx, cancel: cancel}, nil
+}
+
+// Start keeps the function to deliver the results to.
+func (t *QueryTransport[Req, Resp]) Start(deliver func(Result[Resp])) error {
+	t.deliver = deliver
+	return nil
+}
+
+// Send signals the requests to the workflow and polls for their results in the
+// background, until all of them are delivered or the response timeout passes.
+func (t *QueryTransport[Req, Resp]) Send(ctx context.Context, requests []Envelope[Req]) error {
+	batch := &Batch[Req]{Requests: requests}
+	if err := t.options.Client.SignalWorkflow(ctx, t.options.TargetWorkflowID, "", BatchSignalName, batch); err != nil {
+		return fmt.Errorf("failed signaling workflow: %w", err)
+	}
+	ids := make([]string, len(requests))
+	for i, env := range requests {
+		ids[i] = env.ID
+	}
+	t.polls.Add(1)
+	go func() {
+		defer t.polls.Done()
+		t.poll(ids)
+	}()
+	return nil
+}
+
+func (t *QueryTransport[Req, Resp]) poll(ids []string) {
+	ticker := time.NewTicker(t.options.ResponseQueryInterval)
+	defer ticker.Stop()
+	pollCtx, cancel := context.WithTimeout(t.ctx, t.options.ResponseTimeout)
+	defer cancel()
+
+	for len(ids) > 0 {
+		// Query for results
+		val, err := t.options.Client.QueryWorkflow(pollCtx, t.options.TargetWorkflowID, "", ResultsQueryName, ids)
+
+		// Sometimes an error can happen during continue-as-new of a workflow, so we
+		// do not fail on errors here, we just log them
+		if err != nil {
+			log.Printf("Query workflow failed: %v", err)
+		} else if val != nil && val.HasValue() {
+			var results []Result[Resp]
+			if err := val.Get(&results); err != nil {
+				log.Printf("Failed unmarshalling results: %v", err)
+			}
+			ids = t.deliverResults(ids, results)
+		}
+		if len(ids) == 0 {
+			return
+		}
+
+		// Wait for interval or timeout
+		select {
+		case <-pollCtx.Done():
+			// Fail the requests still waiting, showing the last error if any
+			msg := "timeout waiting for response"
+			if err != nil {
+				msg = fmt.Sprintf("timeout, last error: %v", err)
+			}
+			for _, id := range ids {
+				t.deliver(Result[Resp]{ID: id, Error: msg})
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverResults delivers the results and returns the IDs still waiting for one.
+func (t *QueryTransport[Req, Resp]) deliverResults(ids []string, results []Result[Resp]) []string {
+	delivered := make(map[string]bool, len(results))
+	for _, result := range results {
+		t.deliver(result)
+		delivered[result.ID] = true
+	}
+	remaining := ids[:0]
+	for _, id := range ids {
+		if !delivered[id] {
+			remaining = append(remaining, id)
+		}
+	}
+	return remaining
+}
+
+// Close stops polling.
+func (t *QueryTransport[Req, Resp]) Close() {
+	t.cancel()
+	t.polls.Wait()
+}
+
+// UpdateTransportOptions are options for NewUpdateTransport.
+type UpdateTransportOptions struct {
+	// Client to the Temporal server. Required.
+	Client client.Client
+	// ID of the workflow handling the batch update. Required.
+	TargetWorkflowID string
+}
+
+// UpdateTransport sends each batch of requests as one update and receives
+// their results as the result of the update.
+type UpdateTransport[Req, Resp any] struct {
+	options UpdateTransportOptions
+	deliver func(Result[Resp])
+}
+
+// NewUpdateTransport creates an UpdateTransport for the given options.
+func NewUpdateTransport[Req, Resp any](options UpdateTransportOptions) (*UpdateTransport[Req, Resp], error) {
+	if options.Client == nil {
+		return nil, fmt.Errorf("client required")
+	} else if options.TargetWorkflowID == "" {
+		return nil, fmt.Errorf("target workflow required")
+	}
+	return &UpdateTransport[Req, Resp]{options: options}, nil
+}
+
+// Start keeps the function to deliver the results to.
+func (t *UpdateTransport[Req, Resp]) Start(deliver func(Result[Resp])) error {
+	t.deliver = deliver
+	return nil
+}
+
+// Send updates the workflow with the requests and delivers the results.
+func (t *UpdateTransport[Req, Resp]) Send(ctx context.Context, requests []Envelope[Req]) error {
+	handle, err := t.options.Client.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   t.options.TargetWorkflowID,
+		UpdateName:   BatchUpdateName,
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+		Args:         []interface{}{requests},
+	})
+	if err != nil {
+		return fmt.Errorf("failed updating workflow: %w", err)
+	}
+	var results []Result[Resp]
+	if err := handle.Get(ctx, &results); err != nil {
+		return fmt.Errorf("failed getting update response: %w", err)
+	}
+	for _, result := range results {
+		t.deliver(result)
+	}
+	return nil
+}
+
+// Close does nothing, updates need no resources between requests.
+func (t *UpdateTransport[Req, Resp]) Close() {}
@@ -2,21 +2,17 @@ package reqrespactivity
 
 import (
 	"context"
-	"fmt"
-	"sync"
 
-	"github.com/pborman/uuid"
+	"github.com/temporalio/samples-go/reqresp"
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
-	"go.temporal.io/sdk/worker"
 )
 
 // Requester can request uppercasing of strings and should be closed after use.
+// It is a reqresp.Requester that sends batches of requests by signal and
+// receives the responses with a callback activity.
 type Requester struct {
-	options   RequesterOptions
-	taskQueue string
-	// Channels need buffer of 1 because they are sent to in non-blocking fashion
-	pendingRequests     map[string]chan<- *Response
-	pendingRequestsLock sync.RWMutex
+	requester *reqresp.Requester[string, string]
 }
 
 // RequesterOptions are options for NewRequester.
@@ -25,10 +21,12 @@ type RequesterOptions struct {
 	Client client.Client
 	// ID of the workflow listening for signals to uppercase. Required.
 	TargetWorkflowID string
+	// Batching, deadline and metrics options of the requests.
+	Options reqresp.Options
 
 	// Visible for testing.
 	ExistingWorker interface {
-		RegisterActivity(interface{})
+		RegisterActivityWithOptions(interface{}, activity.RegisterOptions)
 		Start() error
 		Stop()
 	}
@@ -36,95 +34,29 @@ type RequesterOptions struct {
 
 // NewRequester creates a new Requester for the given options.
 func NewRequester(options RequesterOptions) (*Requester, error) {
-	if options.Client == nil {
-		return nil, fmt.Errorf("client required")
-	} else if options.TargetWorkflowID == "" {
-		return nil, fmt.Errorf("target workflow required")
-	}
-
-	// Create requester
-	r := &Requester{
-		options:         options,
-		taskQueue:       "requester-" + uuid.New(),
-		pendingRequests: map[string]chan<- *Response{},
-	}
-	if r.options.ExistingWorker == nil {
-		r.options.ExistingWorker = worker.New(options.Client, r.taskQueue, worker.Options{})
-	}
-
-	// Start worker
-	r.options.ExistingWorker.RegisterActivity(r.responseActivity)
-	if err := r.options.ExistingWorker.Start(); err != nil {
-		return nil, fmt.Errorf("failed starting worker: %w", err)
-	}
-	return r, nil
+	transport, err := reqresp.NewActivityTransport[string, string](reqresp.ActivityTransportOptions{
+		Client:           options.Client,
+		TargetWorkflowID: options.TargetWorkflowID,
+		ExistingWorker:   options.ExistingWorker,
+	})
+	if err != nil {
+		return nil, err
+	}
+	requester, err := reqresp.NewRequester[string, string](transport, options.Options)
+	if err != nil {
+		return nil, err
+	}
+	return &Requester{requester}, nil
 }
 
 // RequestUppercase sends a request and returns a response.
 func (r *Requester) RequestUppercase(ctx context.Context, str string) (string, error) {
-	// Create request and add channel to pending
-	req := &Request{
-		ID:                uuid.New(),
-		Input:             str,
-		ResponseActivity:  "responseActivity",
-		ResponseTaskQueue: r.taskQueue,
-	}
-	respCh := make(chan *Response, 1)
-	r.pendingRequestsLock.Lock()
-	r.pendingRequests[req.ID] = respCh
-	r.pendingRequestsLock.Unlock()
-
-	// Remove pending request when done
-	defer func() {
-		r.pendingRequestsLock.Lock()
-		defer r.pendingRequestsLock.Unlock()
-		delete(r.pendingRequests, req.ID)
-	}()
-
-	// Send request and wait for response
-	if err := r.options.Client.SignalWorkflow(ctx, r.options.TargetWorkflowID, "", "request", req); err != nil {
-		return "", fmt.Errorf("failed signaling workflow: %w", err)
-	}
-	select {
-	case <-ctx.Done():
-		return "", ctx.Err()
-	case resp := <-respCh:
-		if resp.Error != "" {
-			return "", fmt.Errorf("Request failed: %v", resp.Error)
-		}
-		return resp.Output, nil
-	}
+	return r.requester.Request(ctx, str)
 }
 
 // Close stops the internal worker. Since this stops the response worker, it
 // does a graceful stop for a period. Callers are expected to not call requests
 // after this and to cancel outstanding requests.
 func (r *Requester) Close() {
-	// We intentionally don't cancel all pending requests because they may
-	// complete during graceful worker stop. We expect the caller to no longer
-	// call for new requests and cancel their request contexts.
-	r.options.ExistingWorker.Stop()
-}
-
-func (r *Requester) responseActivity(ctx context.Context, resp *Response) error {
-	// Get the channel to respond to
-	r.pendingRequestsLock.RLock()
-	respCh := r.pendingRequests[resp.ID]
-	r.pendingRequestsLock.RUnlock()
-	// We choose not to log or error if a response is not pending because it is
-	// normal behavior for a requester to have closed the context and stop waiting
-	if respCh == nil {
-		return nil
-	}
-
-	// Send non-blocking since the channel should have enough room. Technically
-	// during a situation where this worker was too busy for this activity to
-	// return, the responseActivity could be called again for the same response
-	// during retry from the other side. This will just result in a no-op since
-	// the channel does not have room.
-	select {
-	case respCh <- resp:
-	default:
-	}
-	return nil
+	r.requester.Close()
 }
@@ -7,7 +7,9 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/temporalio/samples-go/reqresp"
 	"github.com/temporalio/samples-go/reqrespactivity"
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/mocks"
 	"go.temporal.io/sdk/testsuite"
 )
@@ -18,15 +20,16 @@ func TestActivityRequester(t *testing.T) {
 
 	// Expect to be signalled
 	c := &mocks.Client{}
-	c.On("SignalWorkflow", mock.Anything, "some-workflow", "", "request", mock.AnythingOfType("*reqrespactivity.Request")).
+	c.On("SignalWorkflow", mock.Anything, "some-workflow", "", reqresp.BatchSignalName, mock.AnythingOfType("*reqresp.Batch[string]")).
 		Once().
 		Return(nil).
 		Run(func(args mock.Arguments) {
 			// Once signalled, we can now execute the activity
-			req := args[len(args)-1].(*reqrespactivity.Request)
-			_, err := env.ExecuteActivity(req.ResponseActivity, &reqrespactivity.Response{
-				ID:     req.ID,
-				Output: strings.ToUpper(req.Input),
+			batch := args[len(args)-1].(*reqresp.Batch[string])
+			require.Len(t, batch.Requests, 1)
+			_, err := env.ExecuteActivity(batch.ResponseActivity, &reqresp.Result[string]{
+				ID:       batch.Requests[0].ID,
+				Response: strings.ToUpper(batch.Requests[0].Request),
 			})
 			require.NoError(t, err)
 		})
@@ -50,6 +53,8 @@ type fakeWorker struct {
 	env *testsuite.TestActivityEnvironment
 }
 
-func (f *fakeWorker) RegisterActivity(a interface{}) { f.env.RegisterActivity(a) }
-func (*fakeWorker) Start() error                     { return nil }
-func (*fakeWorker) Stop()                            {}
+func (f *fakeWorker) RegisterActivityWithOptions(a interface{}, options activity.RegisterOptions) {
+	f.env.RegisterActivityWithOptions(a, options)
+}
+func (*fakeWorker) Start() error { return nil }
+func (*fakeWorker) Stop()        {}
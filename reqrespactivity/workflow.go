@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/temporalio/samples-go/reqresp"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
@@ -12,7 +13,8 @@ import (
 // UppercaseWorkflow is a workflow that accepts requests to uppercase strings
 // via signals and provides responses via a callback response activity.
 //
-// The "request" signal accepts a Request.
+// The "request" signal accepts a Request. The reqresp.BatchSignalName signal
+// accepts a reqresp.Batch of strings, sent by a reqresp.ActivityTransport.
 func UppercaseWorkflow(ctx workflow.Context) error {
 	// Create and run the uppercaser. We choose to use a separate struct for this
 	// to make state management easier.
@@ -51,6 +53,7 @@ type Response struct {
 type uppercaser struct {
 	workflow.Context
 	requestCh                   workflow.ReceiveChannel
+	batchCh                     workflow.ReceiveChannel
 	requestsBeforeContinueAsNew int
 	responseActivityOptions     workflow.ActivityOptions
 }
@@ -70,6 +73,7 @@ func newUppercaser(ctx workflow.Context) (*uppercaser, error) {
 		// workflow counts on the ability to have some "idle" period between
 		// handling requests/responses where it can continue-as-new.
 		requestCh: workflow.GetSignalChannel(ctx, "request"),
+		batchCh:   workflow.GetSignalChannel(ctx, reqresp.BatchSignalName),
 
 		// We'll allow 500 requests before we continue-as-new the workflow. This is
 		// required because the history will grow very large otherwise for an
@@ -110,6 +114,16 @@ func (u *uppercaser) run() error {
 		u.addExecuteActivityFuture(&req, selector)
 	})
 
+	// Listen for batches of requests, which count as one request each
+	selector.AddReceive(u.batchCh, func(c workflow.ReceiveChannel, more bool) {
+		var batch reqresp.Batch[string]
+		c.Receive(u, &batch)
+		requestCount += len(batch.Requests)
+		for _, env := range batch.Requests {
+			u.addExecuteEnvelopeFuture(env, &batch, selector)
+		}
+	})
+
 	// Continually select until there are too many requests and no pending
 	// selects.
 	//
@@ -141,25 +155,45 @@ func (u *uppercaser) addExecuteActivityFuture(req *Request, selector workflow.Se
 			resp.Error = err.Error()
 		}
 
-		// Shallow copy activity options and set the task queue
-		opts := u.responseActivityOptions
-		opts.TaskQueue = req.ResponseTaskQueue
-		actCtx := workflow.WithActivityOptions(u, opts)
-
-		// We need to capture the error of the activity so we can log it. We add
-		// the future to the selector instead of just doing a workflow.Go so that
-		// we make sure the future is drained before continue-as-new occurs.
-		// Otherwise, the future could be lost and the log may not occur.
-		//
-		// Note however that this ties the the workflow to the success/fail of
-		// these response activities. Therefore, if these take longer to be
-		// handled than the gap that may be needed between requests for
-		// continue-as-new, the workflow will never exit.
-		selector.AddFuture(workflow.ExecuteActivity(actCtx, req.ResponseActivity, resp), func(f workflow.Future) {
-			// Just log if there is an error
-			if err := f.Get(actCtx, nil); err != nil {
-				workflow.GetLogger(actCtx).Warn("Failure sending response activity", "error", err)
-			}
-		})
+		u.addResponseFuture(req.ResponseActivity, req.ResponseTaskQueue, resp, selector)
+	})
+}
+
+func (u *uppercaser) addExecuteEnvelopeFuture(env reqresp.Envelope[string], batch *reqresp.Batch[string], selector workflow.Selector) {
+	// Respond right away if the requester no longer waits for the result
+	if env.Expired(workflow.Now(u)) {
+		result := &reqresp.Result[string]{ID: env.ID, Error: reqresp.DeadlineExceeded}
+		u.addResponseFuture(batch.ResponseActivity, batch.ResponseTaskQueue, result, selector)
+		return
+	}
+	selector.AddFuture(workflow.ExecuteActivity(u, UppercaseActivity, env.Request), func(f workflow.Future) {
+		result := &reqresp.Result[string]{ID: env.ID}
+		if err := f.Get(u, &result.Response); err != nil {
+			result.Error = err.Error()
+		}
+		u.addResponseFuture(batch.ResponseActivity, batch.ResponseTaskQueue, result, selector)
+	})
+}
+
+func (u *uppercaser) addResponseFuture(responseActivity, responseTaskQueue string, resp interface{}, selector workflow.Selector) {
+	// Shallow copy activity options and set the task queue
+	opts := u.responseActivityOptions
+	opts.TaskQueue = responseTaskQueue
+	actCtx := workflow.WithActivityOptions(u, opts)
+
+	// We need to capture the error of the activity so we can log it. We add
+	// the future to the selector instead of just doing a workflow.Go so that
+	// we make sure the future is drained before continue-as-new occurs.
+	// Otherwise, the future could be lost and the log may not occur.
+	//
+	// Note however that this ties the the workflow to the success/fail of
+	// these response activities. Therefore, if these take longer to be
+	// handled than the gap that may be needed between requests for
+	// continue-as-new, the workflow will never exit.
+	selector.AddFuture(workflow.ExecuteActivity(actCtx, responseActivity, resp), func(f workflow.Future) {
+		// Just log if there is an error
+		if err := f.Get(actCtx, nil); err != nil {
+			workflow.GetLogger(actCtx).Warn("Failure sending response activity", "error", err)
+		}
 	})
 }
@@ -2,8 +2,10 @@ package reqrespactivity_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/temporalio/samples-go/reqresp"
 	"github.com/temporalio/samples-go/reqrespactivity"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/testsuite"
@@ -42,3 +44,40 @@ func TestUppercaseWorkflow(t *testing.T) {
 	// Confirm activity sent
 	require.Equal(t, []*reqrespactivity.Response{{ID: "request1", Output: "FOO"}}, externalResponses)
 }
+
+func TestUppercaseWorkflow_Batch(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(reqrespactivity.UppercaseWorkflow)
+	env.RegisterActivity(reqrespactivity.UppercaseActivity)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(reqresp.BatchSignalName, &reqresp.Batch[string]{
+			Requests: []reqresp.Envelope[string]{
+				{ID: "request1", Request: "foo"},
+				{ID: "request2", Request: "bar", Deadline: env.Now().Add(time.Minute)},
+				// Expired before it reached the workflow.
+				{ID: "request3", Request: "baz", Deadline: env.Now().Add(-time.Minute)},
+			},
+			ResponseActivity:  "external-activity",
+			ResponseTaskQueue: "external-task-queue",
+		})
+	}, 2)
+
+	var externalResults []*reqresp.Result[string]
+	env.RegisterActivityWithOptions(
+		func(result *reqresp.Result[string]) error {
+			externalResults = append(externalResults, result)
+			return nil
+		},
+		activity.RegisterOptions{Name: "external-activity"},
+	)
+
+	env.ExecuteWorkflow(reqrespactivity.UppercaseWorkflow)
+
+	require.ElementsMatch(t, []*reqresp.Result[string]{
+		{ID: "request1", Response: "FOO"},
+		{ID: "request2", Response: "BAR"},
+		{ID: "request3", Error: reqresp.DeadlineExceeded},
+	}, externalResults)
+}
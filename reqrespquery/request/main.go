@@ -33,6 +33,7 @@ func main() {
 	if err != nil {
 		log.Fatalln("Unable to create requester", err)
 	}
+	defer req.Close()
 
 	// Run until ctrl+c
 	log.Printf("Requesting every second until ctrl+c")
@@ -2,18 +2,17 @@ package reqrespquery
 
 import (
 	"context"
-	"fmt"
-	"log"
 	"time"
 
-	"github.com/pborman/uuid"
+	"github.com/temporalio/samples-go/reqresp"
 	"go.temporal.io/sdk/client"
-	"go.temporal.io/sdk/temporal"
 )
 
-// Requester can request uppercasing of strings.
+// Requester can request uppercasing of strings and should be closed after use.
+// It is a reqresp.Requester that sends batches of requests by signal and polls
+// for the responses with a query.
 type Requester struct {
-	options RequesterOptions
+	requester *reqresp.Requester[string, string]
 }
 
 // RequesterOptions are options for NewRequester.
@@ -26,67 +25,35 @@ type RequesterOptions struct {
 	ResponseQueryInterval time.Duration
 	// How long to wait for response. Default 4 seconds.
 	ResponseTimeout time.Duration
+	// Batching, deadline and metrics options of the requests.
+	Options reqresp.Options
 }
 
 // NewRequester creates a new Requester for the given options.
 func NewRequester(options RequesterOptions) (*Requester, error) {
-	if options.Client == nil {
-		return nil, fmt.Errorf("client required")
-	} else if options.TargetWorkflowID == "" {
-		return nil, fmt.Errorf("target workflow required")
+	transport, err := reqresp.NewQueryTransport[string, string](reqresp.QueryTransportOptions{
+		Client:                options.Client,
+		TargetWorkflowID:      options.TargetWorkflowID,
+		ResponseQueryInterval: options.ResponseQueryInterval,
+		ResponseTimeout:       options.ResponseTimeout,
+	})
+	if err != nil {
+		return nil, err
 	}
-	if options.ResponseQueryInterval == 0 {
-		options.ResponseQueryInterval = 300 * time.Millisecond
+	requester, err := reqresp.NewRequester[string, string](transport, options.Options)
+	if err != nil {
+		return nil, err
 	}
-	if options.ResponseTimeout == 0 {
-		options.ResponseTimeout = 4 * time.Second
-	}
-	return &Requester{options}, nil
+	return &Requester{requester}, nil
 }
 
 // RequestUppercase sends a request and returns a response.
 func (r *Requester) RequestUppercase(ctx context.Context, str string) (string, error) {
-	// Send request and poll on an interval for response
-	req := &Request{ID: uuid.New(), Input: str}
-	if err := r.options.Client.SignalWorkflow(ctx, r.options.TargetWorkflowID, "", "request", req); err != nil {
-		return "", fmt.Errorf("failed signaling workflow: %w", err)
-	}
-
-	t := time.NewTicker(r.options.ResponseQueryInterval)
-	defer t.Stop()
-	pollCtx, cancel := context.WithTimeout(ctx, r.options.ResponseTimeout)
-	defer cancel()
-
-	for {
-		// Query for response
-		val, err := r.options.Client.QueryWorkflow(pollCtx, r.options.TargetWorkflowID, "", "response", req.ID)
-
-		// Sometimes an error can happen during continue-as-new of a workflow, so we
-		// do not fail on errors here, we just log them
-		if err != nil {
-			log.Printf("Query workflow failed: %v", err)
-		} else if val != nil {
-			var resp *Response
-			err = val.Get(&resp)
-			// We continue on ErrNoData
-			if err != nil && err != temporal.ErrNoData {
-				return "", fmt.Errorf("failed unmarshalling response: %w", err)
-			} else if resp != nil && resp.Error != "" {
-				return "", fmt.Errorf("request failed: %v", resp.Error)
-			} else if resp != nil {
-				return resp.Output, nil
-			}
-		}
+	return r.requester.Request(ctx, str)
+}
 
-		// Wait for interval or timeout
-		select {
-		case <-pollCtx.Done():
-			// If we timed out and not the original context, show last error
-			if ctx.Err() == nil && err != nil {
-				return "", fmt.Errorf("timeout, last error: %w", err)
-			}
-			return "", ctx.Err()
-		case <-t.C:
-		}
-	}
+// Close stops polling for responses. Callers are expected to not call requests
+// after this and to cancel outstanding requests.
+func (r *Requester) Close() {
+	r.requester.Close()
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/temporalio/samples-go/reqresp"
 	"github.com/temporalio/samples-go/reqrespquery"
 	"go.temporal.io/sdk/mocks"
 )
@@ -15,30 +16,33 @@ import (
 func TestRequester(t *testing.T) {
 	c := &mocks.Client{}
 	// Handle query requests
-	var queryResponse *reqrespquery.Response
-	var queryResponseLock sync.RWMutex
+	var queryResults []reqresp.Result[string]
+	var queryResultsLock sync.RWMutex
 	queryVal := &mocks.Value{}
-	queryVal.On("Get", mock.AnythingOfType("**reqrespquery.Response")).
+	queryVal.On("HasValue").Maybe().Return(true)
+	queryVal.On("Get", mock.AnythingOfType("*[]reqresp.Result[string]")).
 		Maybe().
 		Return(nil).
 		Run(func(args mock.Arguments) {
-			queryResponseLock.RLock()
-			defer queryResponseLock.RUnlock()
-			*args.Get(0).(**reqrespquery.Response) = queryResponse
+			queryResultsLock.RLock()
+			defer queryResultsLock.RUnlock()
+			*args.Get(0).(*[]reqresp.Result[string]) = queryResults
 		})
-	c.On("QueryWorkflow", mock.Anything, "some-workflow", "", "response", mock.AnythingOfType("string")).
+	c.On("QueryWorkflow", mock.Anything, "some-workflow", "", reqresp.ResultsQueryName, mock.AnythingOfType("[]string")).
 		Maybe().
 		Return(queryVal, nil)
 	// Expect to be signalled
-	c.On("SignalWorkflow", mock.Anything, "some-workflow", "", "request", mock.AnythingOfType("*reqrespquery.Request")).
+	c.On("SignalWorkflow", mock.Anything, "some-workflow", "", reqresp.BatchSignalName, mock.AnythingOfType("*reqresp.Batch[string]")).
 		Once().
 		Return(nil).
 		Run(func(args mock.Arguments) {
 			// Once signalled, we can now respond to the query
-			queryResponseLock.Lock()
-			defer queryResponseLock.Unlock()
-			req := args[len(args)-1].(*reqrespquery.Request)
-			queryResponse = &reqrespquery.Response{ID: req.ID, Output: strings.ToUpper(req.Input)}
+			queryResultsLock.Lock()
+			defer queryResultsLock.Unlock()
+			batch := args[len(args)-1].(*reqresp.Batch[string])
+			for _, env := range batch.Requests {
+				queryResults = append(queryResults, reqresp.Result[string]{ID: env.ID, Response: strings.ToUpper(env.Request)})
+			}
 		})
 
 	// Create requester
@@ -47,6 +51,7 @@ func TestRequester(t *testing.T) {
 		TargetWorkflowID: "some-workflow",
 	})
 	require.NoError(t, err)
+	defer req.Close()
 
 	// Request
 	res, err := req.RequestUppercase(context.Background(), "SoMe VaLuE")
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/temporalio/samples-go/reqresp"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
@@ -16,6 +17,10 @@ import (
 // The "request" signal accepts a Request.
 //
 // The "response" query accepts a request ID.
+//
+// The reqresp.BatchSignalName signal accepts a reqresp.Batch of strings and the
+// reqresp.ResultsQueryName query accepts request IDs, both used by a
+// reqresp.QueryTransport.
 func UppercaseWorkflow(ctx workflow.Context) error {
 	// Create and run the uppercaser. We choose to use a separate struct for this
 	// to make state management easier.
@@ -51,10 +56,12 @@ type Response struct {
 type uppercaser struct {
 	workflow.Context
 	requestCh                   workflow.ReceiveChannel
+	batchCh                     workflow.ReceiveChannel
 	requestsBeforeContinueAsNew int
 	// This maintains responses for the lifetime of the workflow, which should not
 	// be too large before continue-as-new.
 	responses map[string]*Response
+	results   map[string]*reqresp.Result[string]
 }
 
 func newUppercaser(ctx workflow.Context) (*uppercaser, error) {
@@ -72,17 +79,22 @@ func newUppercaser(ctx workflow.Context) (*uppercaser, error) {
 		// workflow counts on the ability to have some "idle" period between
 		// handling requests/responses where it can continue-as-new.
 		requestCh: workflow.GetSignalChannel(ctx, "request"),
+		batchCh:   workflow.GetSignalChannel(ctx, reqresp.BatchSignalName),
 
 		// We'll allow 500 requests before we continue-as-new the workflow. This is
 		// required because the history will grow very large otherwise for an
 		// interminable workflow fielding signal requests and executing activities.
 		requestsBeforeContinueAsNew: 500,
 		responses:                   make(map[string]*Response, 500),
+		results:                     make(map[string]*reqresp.Result[string], 500),
 	}
-	// Set query handler
+	// Set query handlers
 	if err := workflow.SetQueryHandler(ctx, "response", u.queryResponse); err != nil {
 		return nil, fmt.Errorf("failed setting query handler: %w", err)
 	}
+	if err := workflow.SetQueryHandler(ctx, reqresp.ResultsQueryName, u.queryResults); err != nil {
+		return nil, fmt.Errorf("failed setting query handler: %w", err)
+	}
 	return u, nil
 }
 
@@ -105,6 +117,16 @@ func (u *uppercaser) run() error {
 		u.addExecuteActivityFuture(&req, selector)
 	})
 
+	// Listen for batches of requests, which count as one request each
+	selector.AddReceive(u.batchCh, func(c workflow.ReceiveChannel, more bool) {
+		var batch reqresp.Batch[string]
+		c.Receive(u, &batch)
+		requestCount += len(batch.Requests)
+		for _, env := range batch.Requests {
+			u.addExecuteEnvelopeFuture(env, selector)
+		}
+	})
+
 	// Continually select until there are too many requests and no pending
 	// selects.
 	//
@@ -138,6 +160,18 @@ func (u *uppercaser) queryResponse(id string) (*Response, error) {
 	return u.responses[id], nil
 }
 
+// queryResults returns the results available for the given request IDs. Like
+// queryResponse, it leaves them in place.
+func (u *uppercaser) queryResults(ids []string) ([]*reqresp.Result[string], error) {
+	var results []*reqresp.Result[string]
+	for _, id := range ids {
+		if result, ok := u.results[id]; ok {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
 func (u *uppercaser) addExecuteActivityFuture(req *Request, selector workflow.Selector) {
 	// Add future for handling the result
 	selector.AddFuture(workflow.ExecuteActivity(u, UppercaseActivity, req.Input), func(f workflow.Future) {
@@ -148,3 +182,18 @@ func (u *uppercaser) addExecuteActivityFuture(req *Request, selector workflow.Se
 		u.responses[resp.ID] = resp
 	})
 }
+
+func (u *uppercaser) addExecuteEnvelopeFuture(env reqresp.Envelope[string], selector workflow.Selector) {
+	// Do not bother uppercasing if the requester no longer waits for the result
+	if env.Expired(workflow.Now(u)) {
+		u.results[env.ID] = &reqresp.Result[string]{ID: env.ID, Error: reqresp.DeadlineExceeded}
+		return
+	}
+	selector.AddFuture(workflow.ExecuteActivity(u, UppercaseActivity, env.Request), func(f workflow.Future) {
+		result := &reqresp.Result[string]{ID: env.ID}
+		if err := f.Get(u, &result.Response); err != nil {
+			result.Error = err.Error()
+		}
+		u.results[result.ID] = result
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/temporalio/samples-go/reqresp"
 	"github.com/temporalio/samples-go/reqrespquery"
 	"go.temporal.io/sdk/testsuite"
 )
@@ -40,3 +41,39 @@ func TestUppercaseWorkflow(t *testing.T) {
 	require.NoError(t, queryErr)
 	require.Equal(t, &reqrespquery.Response{ID: "request1", Output: "FOO"}, queryResponse)
 }
+
+func TestUppercaseWorkflow_Batch(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(reqrespquery.UppercaseWorkflow)
+	env.RegisterActivity(reqrespquery.UppercaseActivity)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(reqresp.BatchSignalName, &reqresp.Batch[string]{
+			Requests: []reqresp.Envelope[string]{
+				{ID: "request1", Request: "foo"},
+				// Expired before it reached the workflow.
+				{ID: "request2", Request: "bar", Deadline: env.Now().Add(-time.Minute)},
+			},
+		})
+	}, 1)
+
+	var results []*reqresp.Result[string]
+	var queryErr error
+	env.RegisterDelayedCallback(func() {
+		val, err := env.QueryWorkflow(reqresp.ResultsQueryName, []string{"request1", "request2", "unknown"})
+		if err != nil {
+			queryErr = err
+		} else {
+			queryErr = val.Get(&results)
+		}
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(reqrespquery.UppercaseWorkflow)
+
+	require.NoError(t, queryErr)
+	require.Equal(t, []*reqresp.Result[string]{
+		{ID: "request1", Response: "FOO"},
+		{ID: "request2", Error: reqresp.DeadlineExceeded},
+	}, results)
+}
@@ -2,12 +2,12 @@ package main
 
 import (
 	"context"
-	"errors"
 	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/temporalio/samples-go/reqrespupdate"
@@ -34,6 +34,7 @@ func main() {
 	if err != nil {
 		log.Fatalln("Unable to create requester", err)
 	}
+	defer req.Close()
 
 	// Run until ctrl+c
 	log.Printf("Requesting every second until ctrl+c")
@@ -53,9 +54,10 @@ func main() {
 		str := "foo" + strconv.Itoa(i)
 		log.Printf("Requesting %q be uppercased", str)
 		if val, err := req.RequestUppercase(ctx, str); err != nil {
-			log.Printf("  Failed: %v", errors.Unwrap(err))
-			// Backoff so the workflow can continue as new
-			if errors.Unwrap(err).Error() == reqrespupdate.ErrBackoff.Error() {
+			log.Printf("  Failed: %v", err)
+			// Backoff so the workflow can continue as new. The rejection reaches
+			// the requester as a server error with the same message.
+			if strings.Contains(err.Error(), reqrespupdate.ErrBackoff.Error()) {
 				log.Printf("Workflow trying to continue as new, backing off")
 				time.Sleep(2 * time.Second)
 			}
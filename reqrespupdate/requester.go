@@ -2,50 +2,51 @@ package reqrespupdate
 
 import (
 	"context"
-	"fmt"
 
+	"github.com/temporalio/samples-go/reqresp"
 	"go.temporal.io/sdk/client"
 )
 
-// Requester can request uppercasing of strings.
+// Requester can request uppercasing of strings and should be closed after use.
+// It is a reqresp.Requester that sends batches of requests as updates.
 type Requester struct {
-	options RequesterOptions
+	requester *reqresp.Requester[string, string]
 }
 
 // RequesterOptions are options for NewRequester.
 type RequesterOptions struct {
 	// Client to the Temporal server. Required.
 	Client client.Client
-	// ID of the workflow listening for signals to uppercase. Required.
+	// ID of the workflow handling updates to uppercase. Required.
 	TargetWorkflowID string
+	// Batching, deadline and metrics options of the requests.
+	Options reqresp.Options
 }
 
 // NewRequester creates a new Requester for the given options.
 func NewRequester(options RequesterOptions) (*Requester, error) {
-	if options.Client == nil {
-		return nil, fmt.Errorf("client required")
-	} else if options.TargetWorkflowID == "" {
-		return nil, fmt.Errorf("target workflow required")
-	}
-	return &Requester{options}, nil
-}
-
-// RequestUppercase sends a request and returns a response.
-func (r *Requester) RequestUppercase(ctx context.Context, str string) (string, error) {
-	// Send request and poll on an interval for response
-	handle, err := r.options.Client.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
-		WorkflowID:   r.options.TargetWorkflowID,
-		UpdateName:   UpdateHandler,
-		WaitForStage: client.WorkflowUpdateStageCompleted,
-		Args:         []interface{}{Request{Input: str}},
+	transport, err := reqresp.NewUpdateTransport[string, string](reqresp.UpdateTransportOptions{
+		Client:           options.Client,
+		TargetWorkflowID: options.TargetWorkflowID,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed updating workflow: %w", err)
+		return nil, err
 	}
-	var response Response
-	err = handle.Get(ctx, &response)
+	requester, err := reqresp.NewRequester[string, string](transport, options.Options)
 	if err != nil {
-		return "", fmt.Errorf("failed getting update response: %w", err)
+		return nil, err
 	}
-	return response.Output, nil
+	return &Requester{requester}, nil
+}
+
+// RequestUppercase sends a request and returns a response.
+func (r *Requester) RequestUppercase(ctx context.Context, str string) (string, error) {
+	return r.requester.Request(ctx, str)
+}
+
+// Close sends the queued batch of requests and waits up to SendTimeout for the
+// updates being sent. Callers are expected to not make requests after this and
+// to cancel outstanding requests.
+func (r *Requester) Close() {
+	r.requester.Close()
 }
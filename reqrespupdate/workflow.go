@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/temporalio/samples-go/reqresp"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
@@ -20,7 +21,9 @@ var (
 )
 
 // UppercaseWorkflow is a workflow that accepts requests to uppercase strings
-// via updates and provides a response.
+// via updates and provides a response. The reqresp.BatchUpdateName update
+// accepts a batch of reqresp.Envelope of strings, sent by a
+// reqresp.UpdateTransport, and returns their results.
 func UppercaseWorkflow(ctx workflow.Context, rejectUpdateOnPendingContinueAsNew bool) error {
 	// Create and run the uppercaser. We choose to use a separate struct for this
 	// to make state management easier.
@@ -107,6 +110,47 @@ func (u *uppercaser) run(ctx workflow.Context) error {
 		return fmt.Errorf("failed setting updatex handler: %w", err)
 	}
 
+	var batchOptions workflow.UpdateHandlerOptions
+	if u.rejectUpdateOnPendingContinueAsNew {
+		batchOptions.Validator = func(ctx workflow.Context, requests []reqresp.Envelope[string]) error {
+			if requestCount >= u.requestsBeforeContinueAsNew {
+				return ErrBackoff
+			}
+			return nil
+		}
+	}
+	// Set batch update handler, where each request of the batch counts as one
+	err = workflow.SetUpdateHandlerWithOptions(ctx, reqresp.BatchUpdateName, func(ctx workflow.Context, requests []reqresp.Envelope[string]) ([]reqresp.Result[string], error) {
+		requestCount += len(requests)
+		pendingUpdates++
+		defer func() {
+			pendingUpdates--
+		}()
+		// Start an activity for each request that did not expire, then wait for all of them
+		results := make([]reqresp.Result[string], len(requests))
+		futures := make([]workflow.Future, len(requests))
+		for i, env := range requests {
+			results[i].ID = env.ID
+			if env.Expired(workflow.Now(ctx)) {
+				results[i].Error = reqresp.DeadlineExceeded
+				continue
+			}
+			futures[i] = workflow.ExecuteActivity(u, UppercaseActivity, env.Request)
+		}
+		for i, future := range futures {
+			if future == nil {
+				continue
+			}
+			if err := future.Get(ctx, &results[i].Response); err != nil {
+				results[i].Error = err.Error()
+			}
+		}
+		return results, nil
+	}, batchOptions)
+	if err != nil {
+		return fmt.Errorf("failed setting batch update handler: %w", err)
+	}
+
 	// Wait until we can continue as new or are cancelled.
 	err = workflow.Await(ctx, func() bool { return requestCount >= u.requestsBeforeContinueAsNew && pendingUpdates == 0 })
 	if err != nil {
@@ -3,8 +3,10 @@ package reqrespupdate_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/temporalio/samples-go/reqresp"
 	"github.com/temporalio/samples-go/reqrespupdate"
 	"go.temporal.io/sdk/testsuite"
 )
@@ -46,3 +48,35 @@ func TestUppercaseWorkflow(t *testing.T) {
 	// Run workflow
 	env.ExecuteWorkflow(reqrespupdate.UppercaseWorkflow, true)
 }
+
+func TestUppercaseWorkflow_Batch(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(reqrespupdate.UppercaseWorkflow)
+	env.RegisterActivity(reqrespupdate.UppercaseActivity)
+
+	var results []reqresp.Result[string]
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(reqresp.BatchUpdateName, "batch", &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { require.Fail(t, "this update should not fail") },
+			OnComplete: func(response interface{}, err error) {
+				require.NoError(t, err)
+				results = response.([]reqresp.Result[string])
+			},
+		}, []reqresp.Envelope[string]{
+			{ID: "request1", Request: "foo"},
+			{ID: "request2", Request: "bar", Deadline: env.Now().Add(time.Minute)},
+			// Expired before it reached the workflow.
+			{ID: "request3", Request: "baz", Deadline: env.Now().Add(-time.Minute)},
+		})
+	}, 1)
+
+	env.ExecuteWorkflow(reqrespupdate.UppercaseWorkflow, true)
+
+	require.Equal(t, []reqresp.Result[string]{
+		{ID: "request1", Response: "FOO"},
+		{ID: "request2", Response: "BAR"},
+		{ID: "request3", Error: reqresp.DeadlineExceeded},
+	}, results)
+}
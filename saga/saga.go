@@ -0,0 +1,166 @@
+package saga
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+
+	"go.uber.org/multierr"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// CompensationsQueryType is the query that returns the CompensationStatus of every registered compensation.
+const CompensationsQueryType = "compensations"
+
+// Compensation states reported by the compensations query.
+const (
+	CompensationPending   = "pending"
+	CompensationRunning   = "running"
+	CompensationCompleted = "completed"
+	CompensationFailed    = "failed"
+)
+
+type (
+	// Options configure how a Saga runs its compensations.
+	Options struct {
+		// ParallelCompensation runs all compensations at the same time instead of one by one in reverse order.
+		ParallelCompensation bool
+		// ContinueWithError keeps running the remaining compensations when one of them fails. The errors of all
+		// failed compensations are returned together.
+		ContinueWithError bool
+	}
+
+	// CompensationStatus is the state of a single compensation as reported by the compensations query.
+	CompensationStatus struct {
+		Activity string
+		State    string
+		Error    string
+	}
+
+	// Saga collects a compensating activity for every step of a workflow that succeeded, and runs them when a later
+	// step fails. Create it with NewSaga; a workflow should only have one Saga because NewSaga registers the
+	// compensations query.
+	Saga struct {
+		ctx           workflow.Context
+		options       Options
+		compensations []*compensation
+	}
+
+	compensation struct {
+		activity interface{}
+		args     []interface{}
+		status   CompensationStatus
+	}
+)
+
+// NewSaga creates a Saga that runs compensations with the activity options of ctx and registers the compensations
+// query.
+func NewSaga(ctx workflow.Context, options Options) (*Saga, error) {
+	s := &Saga{ctx: ctx, options: options}
+	err := workflow.SetQueryHandler(ctx, CompensationsQueryType, func() ([]CompensationStatus, error) {
+		statuses := make([]CompensationStatus, len(s.compensations))
+		for i, c := range s.compensations {
+			statuses[i] = c.status
+		}
+		return statuses, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AddCompensation registers the activity that undoes a step that just succeeded. The activity is executed with args
+// when Compensate is called.
+func (s *Saga) AddCompensation(activity interface{}, args ...interface{}) {
+	s.compensations = append(s.compensations, &compensation{
+		activity: activity,
+		args:     args,
+		status:   CompensationStatus{Activity: activityName(activity), State: CompensationPending},
+	})
+}
+
+// Compensate runs the registered compensations, by default one by one in the reverse order they were added. It uses
+// a disconnected context so compensations still run after the workflow has been cancelled.
+func (s *Saga) Compensate() error {
+	ctx, cancel := workflow.NewDisconnectedContext(s.ctx)
+	defer cancel()
+
+	if s.options.ParallelCompensation {
+		return s.compensateParallel(ctx)
+	}
+	var err error
+	for i := len(s.compensations) - 1; i >= 0; i-- {
+		c := s.compensations[i]
+		if c.status.State != CompensationPending {
+			continue
+		}
+		if errCompensation := c.run(ctx, workflow.ExecuteActivity(ctx, c.activity, c.args...)); errCompensation != nil {
+			err = multierr.Append(err, errCompensation)
+			if !s.options.ContinueWithError {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+func (s *Saga) compensateParallel(ctx workflow.Context) error {
+	// Without ContinueWithError the first failure cancels the compensations that are still running.
+	childCtx, cancelHandler := workflow.WithCancel(ctx)
+	defer cancelHandler()
+
+	var futures []workflow.Future
+	var pending []*compensation
+	for i := len(s.compensations) - 1; i >= 0; i-- {
+		c := s.compensations[i]
+		if c.status.State != CompensationPending {
+			continue
+		}
+		futures = append(futures, workflow.ExecuteActivity(childCtx, c.activity, c.args...))
+		pending = append(pending, c)
+	}
+
+	var err error
+	selector := workflow.NewSelector(ctx)
+	for i := range futures {
+		c := pending[i]
+		c.status.State = CompensationRunning
+		selector.AddFuture(futures[i], func(f workflow.Future) {
+			if errCompensation := c.run(ctx, f); errCompensation != nil {
+				err = multierr.Append(err, errCompensation)
+				if !s.options.ContinueWithError {
+					cancelHandler()
+				}
+			}
+		})
+	}
+	for range futures {
+		selector.Select(ctx)
+	}
+	return err
+}
+
+// run waits for the compensation activity and records its outcome.
+func (c *compensation) run(ctx workflow.Context, f workflow.Future) error {
+	c.status.State = CompensationRunning
+	if err := f.Get(ctx, nil); err != nil {
+		c.status.State = CompensationFailed
+		c.status.Error = err.Error()
+		return err
+	}
+	c.status.State = CompensationCompleted
+	return nil
+}
+
+// activityName returns the name an activity function is registered under, or the name itself for a string.
+func activityName(activity interface{}) string {
+	if name, ok := activity.(string); ok {
+		return name
+	}
+	fullName := runtime.FuncForPC(reflect.ValueOf(activity).Pointer()).Name()
+	name := fullName[strings.LastIndex(fullName, ".")+1:]
+	// Methods of activity structs end in -fm.
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -27,32 +27,30 @@ func TransferMoney(ctx workflow.Context, transferDetails TransferDetails) (err e
 
 	ctx = workflow.WithActivityOptions(ctx, options)
 
-	err = workflow.ExecuteActivity(ctx, Withdraw, transferDetails).Get(ctx, nil)
+	// Keep compensating when one compensation fails so that every completed step is undone.
+	saga, err := NewSaga(ctx, Options{ContinueWithError: true})
 	if err != nil {
 		return err
 	}
-
 	defer func() {
 		if err != nil {
-			errCompensation := workflow.ExecuteActivity(ctx, WithdrawCompensation, transferDetails).Get(ctx, nil)
-			err = multierr.Append(err, errCompensation)
+			// uncomment to have time to shut down worker to simulate worker rolling update and ensure that compensation sequence preserves after restart
+			// workflow.Sleep(ctx, 10*time.Second)
+			err = multierr.Append(err, saga.Compensate())
 		}
 	}()
 
-	err = workflow.ExecuteActivity(ctx, Deposit, transferDetails).Get(ctx, nil)
+	err = workflow.ExecuteActivity(ctx, Withdraw, transferDetails).Get(ctx, nil)
 	if err != nil {
 		return err
 	}
+	saga.AddCompensation(WithdrawCompensation, transferDetails)
 
-	defer func() {
-		if err != nil {
-			errCompensation := workflow.ExecuteActivity(ctx, DepositCompensation, transferDetails).Get(ctx, nil)
-			err = multierr.Append(err, errCompensation)
-		}
-
-		// uncomment to have time to shut down worker to simulate worker rolling update and ensure that compensation sequence preserves after restart
-		// workflow.Sleep(ctx, 10*time.Second)
-	}()
+	err = workflow.ExecuteActivity(ctx, Deposit, transferDetails).Get(ctx, nil)
+	if err != nil {
+		return err
+	}
+	saga.AddCompensation(DepositCompensation, transferDetails)
 
 	err = workflow.ExecuteActivity(ctx, StepWithError, transferDetails).Get(ctx, nil)
 	if err != nil {
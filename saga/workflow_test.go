@@ -1,13 +1,18 @@
 package saga
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
 
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
 )
 
 func Test_Workflow(t *testing.T) {
@@ -29,3 +34,96 @@ func Test_Workflow(t *testing.T) {
 	require.True(t, env.IsWorkflowCompleted())
 	require.Error(t, env.GetWorkflowError())
 }
+
+func Test_WorkflowCompensatesInReverseOrder(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	testDetails := TransferDetails{Amount: 1.00, FromAccount: "001-001", ToAccount: "002-002", ReferenceID: "1234"}
+	var compensations []string
+	env.OnActivity(Withdraw, mock.Anything, testDetails).Return(nil)
+	env.OnActivity(Deposit, mock.Anything, testDetails).Return(nil)
+	env.OnActivity(StepWithError, mock.Anything, testDetails).Return(errors.New("some error"))
+	env.OnActivity(DepositCompensation, mock.Anything, testDetails).Return(func(context.Context, TransferDetails) error {
+		compensations = append(compensations, "DepositCompensation")
+		return errors.New("deposit compensation error")
+	})
+	env.OnActivity(WithdrawCompensation, mock.Anything, testDetails).Return(func(context.Context, TransferDetails) error {
+		compensations = append(compensations, "WithdrawCompensation")
+		return nil
+	})
+	env.ExecuteWorkflow(TransferMoney, testDetails)
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), "some error")
+
+	// The deposit compensation is retried by the retry policy, and its failure does not stop the withdraw compensation.
+	require.Equal(t, []string{
+		"DepositCompensation", "DepositCompensation", "DepositCompensation", "WithdrawCompensation",
+	}, compensations)
+
+	value, err := env.QueryWorkflow(CompensationsQueryType)
+	require.NoError(t, err)
+	var statuses []CompensationStatus
+	require.NoError(t, value.Get(&statuses))
+	require.Len(t, statuses, 2)
+	require.Equal(t, CompensationStatus{Activity: "WithdrawCompensation", State: CompensationCompleted}, statuses[0])
+	require.Equal(t, "DepositCompensation", statuses[1].Activity)
+	require.Equal(t, CompensationFailed, statuses[1].State)
+	require.Contains(t, statuses[1].Error, "deposit compensation error")
+}
+
+func Test_SagaStopsOnCompensationError(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(WithdrawCompensation)
+	env.RegisterActivity(DepositCompensation)
+	env.OnActivity(DepositCompensation, mock.Anything, mock.Anything).Return(errors.New("deposit compensation error"))
+	env.OnActivity(WithdrawCompensation, mock.Anything, mock.Anything).Never()
+
+	env.ExecuteWorkflow(func(ctx workflow.Context) ([]CompensationStatus, error) {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: time.Minute,
+			RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+		})
+		saga, err := NewSaga(ctx, Options{})
+		if err != nil {
+			return nil, err
+		}
+		saga.AddCompensation(WithdrawCompensation, TransferDetails{})
+		saga.AddCompensation(DepositCompensation, TransferDetails{})
+		err = saga.Compensate()
+		return nil, err
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.ErrorContains(t, env.GetWorkflowError(), "deposit compensation error")
+	env.AssertExpectations(t)
+}
+
+func Test_SagaCompensatesInParallelAfterCancel(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(WithdrawCompensation)
+	env.RegisterActivity(DepositCompensation)
+	env.OnActivity(WithdrawCompensation, mock.Anything, mock.Anything).Return(nil).Once()
+	env.OnActivity(DepositCompensation, mock.Anything, mock.Anything).Return(nil).Once()
+
+	env.RegisterDelayedCallback(env.CancelWorkflow, time.Minute)
+	env.ExecuteWorkflow(func(ctx workflow.Context) (err error) {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Minute})
+		saga, err := NewSaga(ctx, Options{ParallelCompensation: true})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				err = multierr.Append(err, saga.Compensate())
+			}
+		}()
+		saga.AddCompensation(WithdrawCompensation, TransferDetails{})
+		saga.AddCompensation(DepositCompensation, TransferDetails{})
+		// Wait until the workflow is cancelled.
+		return workflow.Sleep(ctx, time.Hour)
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.True(t, temporal.IsCanceledError(env.GetWorkflowError()))
+	env.AssertExpectations(t)
+}
@@ -0,0 +1,155 @@
+package shoppingcart
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// defaultStock is the number of units of each catalog item in stock when the worker starts.
+const defaultStock = 10
+
+type (
+	// Reservation is the number of units of an item reserved for a cart.
+	Reservation struct {
+		CartID   string
+		ItemID   string
+		Quantity int
+	}
+
+	// PaymentRequest charges the total of a cart.
+	PaymentRequest struct {
+		CartID string
+		Amount int // in cents
+	}
+
+	// ShipmentRequest ships the items of a cart.
+	ShipmentRequest struct {
+		CartID string
+		Items  map[string]int
+	}
+)
+
+// Activities reserves inventory, takes payments and ships orders. It keeps everything in memory,
+// a real implementation would call the inventory, payment and shipping services.
+// All activities are idempotent, so they can be retried.
+type Activities struct {
+	mu sync.Mutex
+	// stock is the number of units of each item that are not reserved.
+	stock map[string]int
+	// reserved is the number of units of each item reserved by each cart.
+	reserved map[string]map[string]int
+	payments map[string]int
+	// FailShipping makes ShipOrder fail, to show how checkout compensates the payment.
+	FailShipping bool
+}
+
+// NewActivities creates Activities with defaultStock units of every catalog item.
+func NewActivities() *Activities {
+	stock := make(map[string]int, len(Catalog))
+	//workflowcheck:ignore activity code
+	for itemID := range Catalog {
+		stock[itemID] = defaultStock
+	}
+	return &Activities{
+		stock:    stock,
+		reserved: make(map[string]map[string]int),
+		payments: make(map[string]int),
+	}
+}
+
+// ReserveInventory raises the units of the item reserved for the cart to the reservation quantity.
+// It fails without retries when there are not enough units in stock.
+func (a *Activities) ReserveInventory(ctx context.Context, r Reservation) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	reserved := a.reserved[r.CartID]
+	missing := r.Quantity - reserved[r.ItemID]
+	if missing <= 0 {
+		return nil
+	}
+	if a.stock[r.ItemID] < missing {
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("%s is out of stock", r.ItemID), "OutOfStock", nil)
+	}
+	if reserved == nil {
+		reserved = make(map[string]int)
+		a.reserved[r.CartID] = reserved
+	}
+	a.stock[r.ItemID] -= missing
+	reserved[r.ItemID] = r.Quantity
+	activity.GetLogger(ctx).Info("Reserved inventory.", "CartID", r.CartID, "ItemID", r.ItemID, "Quantity", r.Quantity)
+	return nil
+}
+
+// ReleaseInventory lowers the units of the item reserved for the cart to the reservation quantity,
+// returning the others to stock.
+func (a *Activities) ReleaseInventory(ctx context.Context, r Reservation) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	reserved := a.reserved[r.CartID]
+	excess := reserved[r.ItemID] - r.Quantity
+	if excess <= 0 {
+		return nil
+	}
+	a.stock[r.ItemID] += excess
+	if r.Quantity == 0 {
+		delete(reserved, r.ItemID)
+	} else {
+		reserved[r.ItemID] = r.Quantity
+	}
+	activity.GetLogger(ctx).Info("Released inventory.", "CartID", r.CartID, "ItemID", r.ItemID, "Quantity", r.Quantity)
+	return nil
+}
+
+// CommitInventory turns the reservations of the cart into sold units.
+func (a *Activities) CommitInventory(ctx context.Context, cartID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.reserved, cartID)
+	activity.GetLogger(ctx).Info("Committed inventory.", "CartID", cartID)
+	return nil
+}
+
+// ChargePayment charges the amount and returns the payment ID.
+func (a *Activities) ChargePayment(ctx context.Context, request PaymentRequest) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	paymentID := "payment-" + request.CartID
+	a.payments[paymentID] = request.Amount
+	activity.GetLogger(ctx).Info("Charged payment.", "PaymentID", paymentID, "Amount", request.Amount)
+	return paymentID, nil
+}
+
+// RefundPayment refunds a payment taken by ChargePayment.
+func (a *Activities) RefundPayment(ctx context.Context, paymentID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.payments, paymentID)
+	activity.GetLogger(ctx).Info("Refunded payment.", "PaymentID", paymentID)
+	return nil
+}
+
+// ShipOrder ships the items and returns the shipment ID.
+func (a *Activities) ShipOrder(ctx context.Context, request ShipmentRequest) (string, error) {
+	if a.FailShipping {
+		return "", temporal.NewNonRetryableApplicationError("shipping is not available", "ShippingUnavailable", nil)
+	}
+	shipmentID := "shipment-" + request.CartID
+	activity.GetLogger(ctx).Info("Shipped order.", "ShipmentID", shipmentID)
+	return shipmentID, nil
+}
+
+// CancelShipment cancels a shipment created by ShipOrder.
+func (a *Activities) CancelShipment(ctx context.Context, shipmentID string) error {
+	activity.GetLogger(ctx).Info("Cancelled shipment.", "ShipmentID", shipmentID)
+	return nil
+}
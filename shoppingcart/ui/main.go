@@ -14,17 +14,7 @@ import (
 
 var (
 	workflowClient client.Client
-	// Units are in cents
-	itemCosts = map[string]int{
-		"apple":      200,
-		"banana":     100,
-		"watermelon": 500,
-		"television": 100000,
-		"house":      100000000,
-		"car":        5000000,
-		"binder":     1000,
-	}
-	sessionId = newSession()
+	sessionId      = newSession()
 )
 
 func main() {
@@ -52,20 +42,23 @@ func listHandler(w http.ResponseWriter, _ *http.Request) {
 		"<h3>Available Items to Purchase</h3><table border=1><tr><th>Item</th><th>Cost</th><th>Action</th>")
 
 	keys := make([]string, 0)
-	for k := range itemCosts {
+	for k := range shoppingcart.Catalog {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 	for _, k := range keys {
 		actionButton := fmt.Sprintf("<a href=\"/action?type=add&itemID=%s\">"+
 			"<button style=\"background-color:#4CAF50;\">Add to Cart</button></a>", k)
-		dollars := float64(itemCosts[k]) / 100
-		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>$%.2f</td><td>%s</td></tr>", k, dollars, actionButton)
+		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td></tr>", k, dollars(shoppingcart.Catalog[k]), actionButton)
 	}
 	_, _ = fmt.Fprint(w, "</table><h3>Current items in cart:</h3>"+
 		"<table border=1><tr><th>Item</th><th>Quantity</th><th>Action</th>")
 
-	cartState := updateWithStartCart("list", "")
+	cartState, err := updateWithStartCart("list", "")
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "</table><p>Error listing the cart: %v</p>", err)
+		return
+	}
 
 	// List current items in cart
 	keys = make([]string, 0)
@@ -78,22 +71,30 @@ func listHandler(w http.ResponseWriter, _ *http.Request) {
 			"<button style=\"background-color:#f44336;\">Remove Item</button></a>", k)
 		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%d</td><td>%s</td></tr>", k, cartState.Items[k], removeButton)
 	}
-	_, _ = fmt.Fprint(w, "</table>")
+	_, _ = fmt.Fprintf(w, "</table><p>Total: %s</p>", dollars(cartState.Total))
 }
 
 func actionHandler(w http.ResponseWriter, r *http.Request) {
 	actionType := r.URL.Query().Get("type")
 	switch actionType {
 	case "checkout":
-		err := workflowClient.SignalWorkflow(context.Background(), sessionId, "", "checkout", nil)
+		order, err := checkout()
 		if err != nil {
-			log.Fatalln("Error signaling checkout:", err)
+			_, _ = fmt.Fprintf(w, "<p>Checkout failed: %v</p>", err)
+			break
 		}
+		_, _ = fmt.Fprintf(w, "<p>Order placed, paid %s with payment %s, shipment %s.</p>",
+			dollars(order.Total), order.PaymentID, order.ShipmentID)
+		// The cart workflow completes on checkout, the next action starts a new cart.
+		sessionId = newSession()
 	case "add", "remove", "list":
 		itemID := r.URL.Query().Get("itemID")
-		updateWithStartCart(actionType, itemID)
+		if _, err := updateWithStartCart(actionType, itemID); err != nil {
+			_, _ = fmt.Fprintf(w, "<p>Error updating the cart: %v</p>", err)
+		}
 	default:
-		log.Fatalln("Invalid action type:", actionType)
+		http.Error(w, "Invalid action type: "+actionType, http.StatusBadRequest)
+		return
 	}
 
 	// Generate the HTML after communicating with the Temporal workflow.
@@ -103,7 +104,7 @@ func actionHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func updateWithStartCart(actionType string, itemID string) shoppingcart.CartState {
+func updateWithStartCart(actionType string, itemID string) (shoppingcart.CartState, error) {
 	// Handle a client request to add an item to the shopping cart. The user is not logged in, but a session ID is
 	// available from a cookie, and we use this as the cart ID. The Temporal client was created at service-start
 	// time and is shared by all request handlers.
@@ -135,7 +136,8 @@ func updateWithStartCart(actionType string, itemID string) shoppingcart.CartStat
 		// policy or invalid workflow argument types in the start operation), or
 		// a server-side failure (e.g. failed to start workflow, or exceeded
 		// limit on concurrent update per workflow execution).
-		log.Fatalln("Error issuing update-with-start:", err)
+		log.Println("Error issuing update-with-start:", err)
+		return shoppingcart.CartState{}, err
 	}
 
 	log.Println("Updated workflow",
@@ -144,10 +146,36 @@ func updateWithStartCart(actionType string, itemID string) shoppingcart.CartStat
 
 	// Always use a zero variable before calling Get for any Go SDK API
 	cartState := shoppingcart.CartState{Items: make(map[string]int)}
+	// The update fails, for example, when an item is out of stock.
 	if err = updateHandle.Get(ctx, &cartState); err != nil {
-		log.Fatalln("Error obtaining update result:", err)
+		log.Println("Error obtaining update result:", err)
+		return shoppingcart.CartState{}, err
 	}
-	return cartState
+	return cartState, nil
+}
+
+// checkout pays and ships the items of the cart with the checkout update, which waits for the payment
+// and shipping activities and returns the order. A failed checkout leaves the cart open.
+func checkout() (*shoppingcart.Order, error) {
+	ctx := context.Background()
+	updateHandle, err := workflowClient.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   sessionId,
+		UpdateName:   shoppingcart.CheckoutUpdateName,
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var order shoppingcart.Order
+	if err := updateHandle.Get(ctx, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// dollars formats an amount in cents.
+func dollars(cents int) string {
+	return fmt.Sprintf("$%.2f", float64(cents)/100)
 }
 
 func newSession() string {
@@ -22,6 +22,7 @@ func main() {
 	w := worker.New(c, shoppingcart.TaskQueueName, worker.Options{})
 
 	w.RegisterWorkflow(shoppingcart.CartWorkflow)
+	w.RegisterActivity(shoppingcart.NewActivities())
 
 	err = w.Run(worker.InterruptCh())
 	if err != nil {
@@ -1,31 +1,105 @@
 package shoppingcart
 
 import (
+	"errors"
 	"fmt"
+	"time"
+
 	"go.temporal.io/sdk/workflow"
+	"go.uber.org/multierr"
 )
 
 var (
-	UpdateName    = "shopping-cart"
-	TaskQueueName = "shopping-cart-tq"
+	UpdateName         = "shopping-cart"
+	CheckoutUpdateName = "checkout"
+	TaskQueueName      = "shopping-cart-tq"
+	// AbandonmentTimeout is how long a cart waits for an update before it is abandoned
+	// and the inventory reserved for it is released.
+	AbandonmentTimeout = 30 * time.Minute
 )
 
+var activityOptions = workflow.ActivityOptions{
+	StartToCloseTimeout: 10 * time.Second,
+}
+
+// Catalog is the price of the items that can be added to a cart, in cents.
+var Catalog = map[string]int{
+	"apple":      200,
+	"banana":     100,
+	"watermelon": 500,
+	"television": 100000,
+	"house":      100000000,
+	"car":        5000000,
+	"binder":     1000,
+}
+
 type CartState struct {
 	Items map[string]int // itemID -> quantity
+	Total int            // in cents
+}
+
+// Order is the result of a successful checkout.
+type Order struct {
+	CartID     string
+	Items      map[string]int
+	Total      int // in cents
+	PaymentID  string
+	ShipmentID string
+}
+
+func (c *CartState) computeTotal() {
+	c.Total = 0
+	//workflowcheck:ignore the sum does not depend on the iteration order
+	for itemID, quantity := range c.Items {
+		c.Total += Catalog[itemID] * quantity
+	}
 }
 
 func CartWorkflow(ctx workflow.Context, cart *CartState) error {
 	if cart == nil {
-		cart = &CartState{make(map[string]int)}
+		cart = &CartState{Items: make(map[string]int)}
 	}
 	logger := workflow.GetLogger(ctx)
+	cartID := workflow.GetInfo(ctx).WorkflowExecution.ID
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+
+	// The handlers wait for activities, so the mutex keeps them from changing the cart concurrently.
+	mutex := workflow.NewMutex(ctx)
+	// updates counts the requests received so far, any of them restarts the abandonment timer. They are
+	// counted before waiting for the mutex, so that abandonment knows about the requests still waiting.
+	updates := 0
+	var order *Order
+	done := false
 
 	if err := workflow.SetUpdateHandlerWithOptions(ctx, UpdateName, func(ctx workflow.Context, actionType string, itemID string) (*CartState, error) {
 		logger.Info("Received update,", actionType, itemID)
+		updates++
+		// Update handlers do not inherit the activity options of the workflow context.
+		ctx = workflow.WithActivityOptions(ctx, activityOptions)
+		if err := mutex.Lock(ctx); err != nil {
+			return nil, err
+		}
+		defer mutex.Unlock()
+		if order != nil {
+			return nil, errors.New("cart is already checked out")
+		}
+
+		var a *Activities
 		switch actionType {
 		case "add":
+			reservation := Reservation{CartID: cartID, ItemID: itemID, Quantity: cart.Items[itemID] + 1}
+			if err := workflow.ExecuteActivity(ctx, a.ReserveInventory, reservation).Get(ctx, nil); err != nil {
+				return nil, err
+			}
 			cart.Items[itemID] += 1
 		case "remove":
+			if cart.Items[itemID] == 0 {
+				break
+			}
+			reservation := Reservation{CartID: cartID, ItemID: itemID, Quantity: cart.Items[itemID] - 1}
+			if err := workflow.ExecuteActivity(ctx, a.ReleaseInventory, reservation).Get(ctx, nil); err != nil {
+				return nil, err
+			}
 			cart.Items[itemID] -= 1
 			if cart.Items[itemID] <= 0 {
 				delete(cart.Items, itemID)
@@ -34,6 +108,7 @@ func CartWorkflow(ctx workflow.Context, cart *CartState) error {
 		default:
 			logger.Error("Unsupported action type.")
 		}
+		cart.computeTotal()
 
 		return cart, nil
 	}, workflow.UpdateHandlerOptions{
@@ -43,6 +118,9 @@ func CartWorkflow(ctx workflow.Context, cart *CartState) error {
 				if itemID == "" {
 					return fmt.Errorf("itemID must be specified for add or remove actionType")
 				}
+				if _, ok := Catalog[itemID]; !ok {
+					return fmt.Errorf("unknown item: %s", itemID)
+				}
 			case "list":
 				if itemID != "" {
 					logger.Warn("ItemID not needed for \"list\" actionType.")
@@ -56,32 +134,189 @@ func CartWorkflow(ctx workflow.Context, cart *CartState) error {
 		return err
 	}
 
-	signalChan := workflow.GetSignalChannel(ctx, "checkout")
-
-	err := workflow.Await(ctx, func() bool {
-		if workflow.GetInfo(ctx).GetContinueAsNewSuggested() {
-			return true
+	if err := workflow.SetUpdateHandlerWithOptions(ctx, CheckoutUpdateName, func(ctx workflow.Context) (*Order, error) {
+		logger.Info("Received checkout update.")
+		updates++
+		ctx = workflow.WithActivityOptions(ctx, activityOptions)
+		if err := mutex.Lock(ctx); err != nil {
+			return nil, err
+		}
+		defer mutex.Unlock()
+		if order != nil {
+			return nil, errors.New("cart is already checked out")
+		}
+		if len(cart.Items) == 0 {
+			return nil, errors.New("cart is empty")
 		}
-		signalChan.Receive(ctx, nil)
 
-		return true
-	})
-	if err != nil {
+		result, err := checkout(ctx, cartID, cart)
+		if err != nil {
+			return nil, err
+		}
+		order = result
+		done = true
+		return order, nil
+	}, workflow.UpdateHandlerOptions{
+		Validator: func(ctx workflow.Context) error {
+			if order != nil {
+				return errors.New("cart is already checked out")
+			}
+			if len(cart.Items) == 0 {
+				return errors.New("cart is empty")
+			}
+			return nil
+		},
+	}); err != nil {
 		return err
 	}
-	if workflow.GetInfo(ctx).GetContinueAsNewSuggested() {
-		err := workflow.Await(ctx, func() bool {
-			return workflow.AllHandlersFinished(ctx)
+
+	// The checkout signal is kept for clients that do not use the checkout update. The signal has
+	// no result, so a failed checkout is only logged and the cart stays open.
+	signalChan := workflow.GetSignalChannel(ctx, "checkout")
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		for !done {
+			signalChan.Receive(ctx, nil)
+			updates++
+			if err := mutex.Lock(ctx); err != nil {
+				return
+			}
+			if order == nil && len(cart.Items) > 0 {
+				result, err := checkout(ctx, cartID, cart)
+				if err != nil {
+					logger.Error("Checkout failed.", "Error", err)
+				} else {
+					order = result
+					done = true
+				}
+			} else {
+				done = true
+			}
+			mutex.Unlock()
+		}
+	})
+
+	for !done {
+		seen := updates
+		ok, err := workflow.AwaitWithTimeout(ctx, AbandonmentTimeout, func() bool {
+			return done || updates != seen || workflow.GetInfo(ctx).GetContinueAsNewSuggested()
 		})
 		if err != nil {
 			return err
 		}
-		logger.Info("Continuing as new")
+		if !ok {
+			abandoned, err := abandon(ctx, cartID, cart, mutex, func() bool { return updates == seen })
+			if err != nil {
+				return err
+			}
+			if !abandoned {
+				continue
+			}
+			// Requests that arrived while the inventory was released wait for the mutex; they start
+			// over with the emptied cart.
+			if err := workflow.Await(ctx, func() bool {
+				return workflow.AllHandlersFinished(ctx)
+			}); err != nil {
+				return err
+			}
+			if updates == seen {
+				logger.Info("Cart abandoned, cart workflow exiting.")
+				return nil
+			}
+			continue
+		}
+		if !done && workflow.GetInfo(ctx).GetContinueAsNewSuggested() {
+			err := workflow.Await(ctx, func() bool {
+				return workflow.AllHandlersFinished(ctx)
+			})
+			if err != nil {
+				return err
+			}
+			logger.Info("Continuing as new")
 
-		return workflow.NewContinueAsNewError(ctx, CartWorkflow, cart)
+			return workflow.NewContinueAsNewError(ctx, CartWorkflow, cart)
+		}
+	}
+	// Lets the checkout update return its order before the workflow completes.
+	if err := workflow.Await(ctx, func() bool {
+		return workflow.AllHandlersFinished(ctx)
+	}); err != nil {
+		return err
 	}
 	logger.Info("User has checked out, cart workflow exiting.")
 
 	return nil
 
 }
+
+// checkout charges the payment, ships the order and commits the reserved inventory. When a step
+// fails, the completed steps are compensated in reverse order and the reservations are kept, so
+// that the checkout can be retried.
+func checkout(ctx workflow.Context, cartID string, cart *CartState) (_ *Order, err error) {
+	var compensations []func(ctx workflow.Context) error
+	defer func() {
+		if err == nil {
+			return
+		}
+		// The compensations run on a disconnected context to undo the checkout even if the workflow is cancelled.
+		ctx, _ := workflow.NewDisconnectedContext(ctx)
+		for i := len(compensations) - 1; i >= 0; i-- {
+			err = multierr.Append(err, compensations[i](ctx))
+		}
+	}()
+
+	items := make(map[string]int, len(cart.Items))
+	//workflowcheck:ignore the copy does not depend on the iteration order
+	for itemID, quantity := range cart.Items {
+		items[itemID] = quantity
+	}
+	order := &Order{CartID: cartID, Items: items, Total: cart.Total}
+
+	var a *Activities
+	var paymentID string
+	err = workflow.ExecuteActivity(ctx, a.ChargePayment, PaymentRequest{CartID: cartID, Amount: cart.Total}).Get(ctx, &paymentID)
+	if err != nil {
+		return nil, err
+	}
+	compensations = append(compensations, func(ctx workflow.Context) error {
+		return workflow.ExecuteActivity(ctx, a.RefundPayment, paymentID).Get(ctx, nil)
+	})
+	order.PaymentID = paymentID
+
+	var shipmentID string
+	err = workflow.ExecuteActivity(ctx, a.ShipOrder, ShipmentRequest{CartID: cartID, Items: items}).Get(ctx, &shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	compensations = append(compensations, func(ctx workflow.Context) error {
+		return workflow.ExecuteActivity(ctx, a.CancelShipment, shipmentID).Get(ctx, nil)
+	})
+	order.ShipmentID = shipmentID
+
+	if err = workflow.ExecuteActivity(ctx, a.CommitInventory, cartID).Get(ctx, nil); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// abandon releases the inventory reserved for the cart and empties it, unless idle reports that
+// the cart was updated while waiting for the handlers in progress.
+func abandon(ctx workflow.Context, cartID string, cart *CartState, mutex workflow.Mutex, idle func() bool) (bool, error) {
+	if err := mutex.Lock(ctx); err != nil {
+		return false, err
+	}
+	defer mutex.Unlock()
+	if !idle() {
+		return false, nil
+	}
+
+	var a *Activities
+	for _, itemID := range workflow.DeterministicKeys(cart.Items) {
+		reservation := Reservation{CartID: cartID, ItemID: itemID}
+		if err := workflow.ExecuteActivity(ctx, a.ReleaseInventory, reservation).Get(ctx, nil); err != nil {
+			return false, err
+		}
+		delete(cart.Items, itemID)
+	}
+	cart.computeTotal()
+	return true, nil
+}
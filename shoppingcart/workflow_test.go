@@ -1,10 +1,13 @@
 package shoppingcart
 
 import (
+	"context"
 	"fmt"
 	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 
 	"go.temporal.io/sdk/testsuite"
 )
@@ -12,6 +15,7 @@ import (
 func Test_ShoppingCartWorkflow(t *testing.T) {
 	testSuite := &testsuite.WorkflowTestSuite{}
 	env := testSuite.NewTestWorkflowEnvironment()
+	env.RegisterActivity(NewActivities())
 	updatesCompleted := 0
 
 	env.RegisterDelayedCallback(func() {
@@ -25,6 +29,7 @@ func Test_ShoppingCartWorkflow(t *testing.T) {
 					require.Fail(t, "Invalid return type")
 				}
 				require.Equal(t, cartState.Items["apple"], 1)
+				require.Equal(t, Catalog["apple"], cartState.Total)
 				updatesCompleted++
 			},
 		}, "add", "apple")
@@ -67,3 +72,194 @@ func Test_ShoppingCartWorkflow(t *testing.T) {
 	require.True(t, env.IsWorkflowCompleted())
 	require.Equal(t, 2, updatesCompleted)
 }
+
+// testCartID is the ID the test environment gives to the workflow.
+const testCartID = "default-test-workflow-id"
+
+func addItems(t *testing.T, env *testsuite.TestWorkflowEnvironment, itemIDs ...string) {
+	for _, itemID := range itemIDs {
+		env.UpdateWorkflow(UpdateName, uuid.New(), &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { require.Fail(t, "unexpected rejection") },
+			OnComplete: func(i interface{}, err error) {
+				require.NoError(t, err)
+			},
+		}, "add", itemID)
+	}
+}
+
+func Test_ShoppingCartWorkflow_Checkout(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := NewActivities()
+	env.RegisterActivity(activities)
+
+	var order *Order
+	env.RegisterDelayedCallback(func() {
+		addItems(t, env, "apple", "apple", "binder")
+	}, 0)
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(CheckoutUpdateName, uuid.New(), &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { require.Fail(t, "unexpected rejection") },
+			OnComplete: func(i interface{}, err error) {
+				require.NoError(t, err)
+				order = i.(*Order)
+			},
+		})
+	}, time.Minute)
+	env.ExecuteWorkflow(CartWorkflow, nil)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.NotNil(t, order)
+	require.Equal(t, map[string]int{"apple": 2, "binder": 1}, order.Items)
+	require.Equal(t, 2*Catalog["apple"]+Catalog["binder"], order.Total)
+	require.Equal(t, order.Total, activities.payments[order.PaymentID])
+	require.NotEmpty(t, order.ShipmentID)
+	// The reserved units are sold.
+	require.Empty(t, activities.reserved)
+	require.Equal(t, defaultStock-2, activities.stock["apple"])
+}
+
+func Test_ShoppingCartWorkflow_CheckoutCompensation(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := NewActivities()
+	activities.FailShipping = true
+	env.RegisterActivity(activities)
+
+	env.RegisterDelayedCallback(func() {
+		addItems(t, env, "watermelon")
+	}, 0)
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(CheckoutUpdateName, uuid.New(), &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { require.Fail(t, "unexpected rejection") },
+			OnComplete: func(i interface{}, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "shipping is not available")
+			},
+		})
+	}, time.Minute)
+	env.ExecuteWorkflow(CartWorkflow, nil)
+
+	// The cart stays open after the failed checkout until it is abandoned.
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	// The payment is refunded.
+	require.Empty(t, activities.payments)
+	require.Equal(t, defaultStock, activities.stock["watermelon"])
+}
+
+func Test_ShoppingCartWorkflow_Abandonment(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := NewActivities()
+	env.RegisterActivity(activities)
+
+	env.RegisterDelayedCallback(func() {
+		addItems(t, env, "car", "banana")
+	}, 0)
+	env.RegisterDelayedCallback(func() {
+		// Restarts the abandonment timer.
+		addItems(t, env, "banana")
+	}, AbandonmentTimeout-time.Minute)
+	env.RegisterDelayedCallback(func() {
+		require.False(t, env.IsWorkflowCompleted())
+		require.Equal(t, defaultStock-2, activities.stock["banana"])
+	}, AbandonmentTimeout+time.Minute)
+	env.ExecuteWorkflow(CartWorkflow, nil)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Empty(t, activities.reserved[testCartID])
+	require.Equal(t, defaultStock, activities.stock["car"])
+	require.Equal(t, defaultStock, activities.stock["banana"])
+}
+
+func Test_ShoppingCartWorkflow_UpdateWhileAbandoning(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := NewActivities()
+	env.RegisterActivity(activities)
+	// Releasing the inventory takes a while, so that an update arrives in the meantime.
+	env.OnActivity(activities.ReleaseInventory, mock.Anything, mock.Anything).After(time.Minute).Return(
+		func(ctx context.Context, r Reservation) error {
+			return activities.ReleaseInventory(ctx, r)
+		})
+
+	env.RegisterDelayedCallback(func() {
+		addItems(t, env, "car")
+	}, 0)
+	var items map[string]int
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(UpdateName, uuid.New(), &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { require.Fail(t, "unexpected rejection") },
+			OnComplete: func(i interface{}, err error) {
+				require.NoError(t, err)
+				items = map[string]int{}
+				for itemID, quantity := range i.(*CartState).Items {
+					items[itemID] = quantity
+				}
+			},
+		}, "add", "banana")
+	}, AbandonmentTimeout+30*time.Second)
+	env.RegisterDelayedCallback(func() {
+		// The update restarted the cart after the abandonment.
+		require.False(t, env.IsWorkflowCompleted())
+		require.Equal(t, defaultStock, activities.stock["car"])
+		require.Equal(t, defaultStock-1, activities.stock["banana"])
+	}, AbandonmentTimeout+2*time.Minute)
+	env.ExecuteWorkflow(CartWorkflow, nil)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, map[string]int{"banana": 1}, items)
+	require.Empty(t, activities.reserved[testCartID])
+	require.Equal(t, defaultStock, activities.stock["banana"])
+}
+
+func Test_ShoppingCartWorkflow_OutOfStock(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	activities := NewActivities()
+	activities.stock["house"] = 1
+	env.RegisterActivity(activities)
+
+	env.RegisterDelayedCallback(func() {
+		addItems(t, env, "house")
+	}, 0)
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(UpdateName, uuid.New(), &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { require.Fail(t, "unexpected rejection") },
+			OnComplete: func(i interface{}, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "house is out of stock")
+			},
+		}, "add", "house")
+	}, time.Minute)
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(UpdateName, uuid.New(), &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { require.Fail(t, "unexpected rejection") },
+			OnComplete: func(i interface{}, err error) {
+				require.NoError(t, err)
+				cartState := i.(*CartState)
+				require.Equal(t, map[string]int{"house": 1}, cartState.Items)
+				require.Equal(t, Catalog["house"], cartState.Total)
+			},
+		}, "list", "")
+	}, 2*time.Minute)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("checkout", nil)
+	}, 3*time.Minute)
+	env.ExecuteWorkflow(CartWorkflow, nil)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Empty(t, activities.reserved)
+	require.Zero(t, activities.stock["house"])
+}
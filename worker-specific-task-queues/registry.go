@@ -0,0 +1,223 @@
+package worker_specific_task_queues
+
+import (
+	"sort"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// RegistryWorkflowID is the ID of the HostRegistryWorkflow of the hosts.
+	RegistryWorkflowID = "worker-specific-task-queues-registry"
+	// HeartbeatSignal carries a Host that is alive.
+	HeartbeatSignal = "heartbeat"
+	// LeaveSignal carries the ID of a host that stops.
+	LeaveSignal = "leave"
+	// RouteUpdate accepts a RouteRequest and returns the Host to run on.
+	RouteUpdate = "route"
+	// HostsQuery returns the RegistryStatus.
+	HostsQuery = "hosts"
+	// NoHostErrorType is the type of the application error of the route update when no host is alive.
+	NoHostErrorType = "NoHost"
+)
+
+// Host is a worker listening on its own task queue.
+type Host struct {
+	ID        string
+	TaskQueue string
+	// Time of the last heartbeat, set by the registry
+	LastHeartbeat time.Time
+}
+
+// RouteRequest is the argument of the route update.
+type RouteRequest struct {
+	// Affinity key. The same key is routed to the same host as long as it heartbeats. An empty
+	// key is routed to the least loaded host without affinity.
+	Key string
+	// IDs of the hosts to avoid, for instance because they failed the key already. They are only
+	// picked when no other host is alive.
+	Exclude []string
+}
+
+// RegistryStatus is returned by the hosts query.
+type RegistryStatus struct {
+	// Hosts are not alive anymore once they did not heartbeat for TTL.
+	TTL   time.Duration
+	Hosts []Host
+	// Affinity keys by host ID.
+	Keys map[string][]string
+}
+
+// Alive returns whether a host heartbeated within the TTL at now.
+func (s RegistryStatus) Alive(hostID string, now time.Time) bool {
+	for _, host := range s.Hosts {
+		if host.ID == hostID {
+			return now.Sub(host.LastHeartbeat) <= s.TTL
+		}
+	}
+	return false
+}
+
+// RegistryState is the argument of HostRegistryWorkflow, carried over on continue-as-new.
+type RegistryState struct {
+	// Default 15 seconds.
+	TTL time.Duration
+	// Hosts by ID.
+	Hosts map[string]Host
+	// Host IDs by affinity key.
+	Affinity map[string]string
+}
+
+// HostRegistryWorkflow keeps the hosts that heartbeat and routes affinity keys to them. Hosts start
+// it with their first heartbeat, see Heartbeat. A key stays on its host as long as the host
+// heartbeats, then fails over to the least loaded host alive.
+func HostRegistryWorkflow(ctx workflow.Context, state RegistryState) error {
+	logger := workflow.GetLogger(ctx)
+	if state.TTL <= 0 {
+		state.TTL = 15 * time.Second
+	}
+	if state.Hosts == nil {
+		state.Hosts = map[string]Host{}
+	}
+	if state.Affinity == nil {
+		state.Affinity = map[string]string{}
+	}
+
+	err := workflow.SetQueryHandler(ctx, HostsQuery, func() (RegistryStatus, error) {
+		return state.status(), nil
+	})
+	if err != nil {
+		return err
+	}
+	// Hosts of the affinities are assigned by the update, so that the workflow tasks serialize them
+	var events int
+	err = workflow.SetUpdateHandler(ctx, RouteUpdate, func(ctx workflow.Context, request RouteRequest) (Host, error) {
+		events++
+		host, ok := state.route(request, workflow.Now(ctx))
+		if !ok {
+			return Host{}, temporal.NewApplicationError("no host alive", NoHostErrorType)
+		}
+		return host, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	heartbeats := workflow.GetSignalChannel(ctx, HeartbeatSignal)
+	leaves := workflow.GetSignalChannel(ctx, LeaveSignal)
+	drain := func() {
+		var host Host
+		for heartbeats.ReceiveAsync(&host) {
+			events++
+			if _, ok := state.Hosts[host.ID]; !ok {
+				logger.Info("Host joined.", "HostID", host.ID, "TaskQueue", host.TaskQueue)
+			}
+			host.LastHeartbeat = workflow.Now(ctx)
+			state.Hosts[host.ID] = host
+		}
+		var hostID string
+		for leaves.ReceiveAsync(&hostID) {
+			events++
+			logger.Info("Host left.", "HostID", hostID)
+			state.remove(hostID)
+		}
+		state.prune(workflow.Now(ctx))
+	}
+
+	// Continue as new from time to time, since every heartbeat is in the history
+	for events < 1000 && !workflow.GetInfo(ctx).GetContinueAsNewSuggested() {
+		err := workflow.Await(ctx, func() bool {
+			return heartbeats.Len() > 0 || leaves.Len() > 0 || events >= 1000 || workflow.GetInfo(ctx).GetContinueAsNewSuggested()
+		})
+		if err != nil {
+			return err
+		}
+		drain()
+	}
+	if err := workflow.Await(ctx, func() bool { return workflow.AllHandlersFinished(ctx) }); err != nil {
+		return err
+	}
+	drain()
+	return workflow.NewContinueAsNewError(ctx, HostRegistryWorkflow, state)
+}
+
+// route returns the host of the key of a request, assigning one if the key has no host alive.
+func (s *RegistryState) route(request RouteRequest, now time.Time) (Host, bool) {
+	excluded := map[string]bool{}
+	for _, id := range request.Exclude {
+		excluded[id] = true
+	}
+	if id, ok := s.Affinity[request.Key]; ok && request.Key != "" {
+		if host := s.Hosts[id]; s.alive(host, now) && !excluded[id] {
+			return host, true
+		}
+	}
+
+	// Pick the host alive with the fewest keys, preferring the ones not excluded
+	load := map[string]int{}
+	//workflowcheck:ignore
+	for _, id := range s.Affinity {
+		load[id]++
+	}
+	var best Host
+	found, bestExcluded := false, false
+	for _, id := range workflow.DeterministicKeys(s.Hosts) {
+		host := s.Hosts[id]
+		if !s.alive(host, now) {
+			continue
+		}
+		better := !found ||
+			(bestExcluded && !excluded[id]) ||
+			(bestExcluded == excluded[id] && load[id] < load[best.ID])
+		if better {
+			best, found, bestExcluded = host, true, excluded[id]
+		}
+	}
+	if found && request.Key != "" {
+		s.Affinity[request.Key] = best.ID
+	}
+	return best, found
+}
+
+func (s *RegistryState) alive(host Host, now time.Time) bool {
+	return host.ID != "" && now.Sub(host.LastHeartbeat) <= s.TTL
+}
+
+// remove removes a host and the affinities to it.
+func (s *RegistryState) remove(hostID string) {
+	delete(s.Hosts, hostID)
+	//workflowcheck:ignore
+	for key, id := range s.Affinity {
+		if id == hostID {
+			delete(s.Affinity, key)
+		}
+	}
+}
+
+// prune removes the hosts that did not heartbeat for a long time.
+func (s *RegistryState) prune(now time.Time) {
+	//workflowcheck:ignore
+	for id, host := range s.Hosts {
+		if now.Sub(host.LastHeartbeat) > 10*s.TTL {
+			s.remove(id)
+		}
+	}
+}
+
+func (s *RegistryState) status() RegistryStatus {
+	status := RegistryStatus{TTL: s.TTL, Keys: map[string][]string{}}
+	for _, id := range workflow.DeterministicKeys(s.Hosts) {
+		status.Hosts = append(status.Hosts, s.Hosts[id])
+	}
+	//workflowcheck:ignore
+	for key, id := range s.Affinity {
+		status.Keys[id] = append(status.Keys[id], key)
+	}
+	//workflowcheck:ignore
+	for _, keys := range status.Keys {
+		sort.Strings(keys)
+	}
+	return status
+}
@@ -0,0 +1,78 @@
+package worker_specific_task_queues
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func Test_HostRegistryWorkflow(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+
+	var routes []string
+	route := func(key string, exclude ...string) {
+		env.UpdateWorkflow(RouteUpdate, "", &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { require.Fail(t, "unexpected rejection") },
+			OnComplete: func(i interface{}, err error) {
+				if err != nil {
+					routes = append(routes, key+":"+err.Error())
+					return
+				}
+				routes = append(routes, key+":"+i.(Host).ID)
+			},
+		}, RouteRequest{Key: key, Exclude: exclude})
+	}
+
+	env.RegisterDelayedCallback(func() {
+		route("a")
+		env.SignalWorkflow(HeartbeatSignal, host1)
+		env.SignalWorkflow(HeartbeatSignal, host2)
+	}, time.Second)
+	env.RegisterDelayedCallback(func() {
+		// Keys are spread across the hosts and stick to them
+		route("a")
+		route("b")
+		route("a")
+		// Excluded hosts are avoided, unless they are the only ones alive
+		route("c", "host1")
+		route("d", "host1", "host2")
+	}, 2*time.Second)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(HeartbeatSignal, host2)
+	}, 10*time.Second)
+	env.RegisterDelayedCallback(func() {
+		// host1 did not heartbeat for the TTL, its keys fail over
+		route("a")
+		route("b")
+	}, 20*time.Second)
+	env.RegisterDelayedCallback(func() {
+		val, err := env.QueryWorkflow(HostsQuery)
+		require.NoError(t, err)
+		var status RegistryStatus
+		require.NoError(t, val.Get(&status))
+		require.Len(t, status.Hosts, 2)
+		require.False(t, status.Alive("host1", env.Now()))
+		require.True(t, status.Alive("host2", env.Now()))
+		// Keys fail over when they are routed again
+		require.Equal(t, map[string][]string{"host1": {"d"}, "host2": {"a", "b", "c"}}, status.Keys)
+
+		// The keys of a host that left fail over right away
+		env.SignalWorkflow(LeaveSignal, "host2")
+		route("a")
+	}, 21*time.Second)
+	env.RegisterDelayedCallback(env.CancelWorkflow, 22*time.Second)
+
+	env.ExecuteWorkflow(HostRegistryWorkflow, RegistryState{TTL: 15 * time.Second})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Equal(t, []string{
+		"a:no host alive (type: NoHost, retryable: true)",
+		"a:host1", "b:host2", "a:host1", "c:host2", "d:host1",
+		"a:host2", "b:host2",
+		"a:no host alive (type: NoHost, retryable: true)",
+	}, routes)
+}
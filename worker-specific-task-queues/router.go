@@ -0,0 +1,70 @@
+package worker_specific_task_queues
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+// Router holds the activities that route workflows to the hosts of the HostRegistryWorkflow. They
+// run on the shared task queue.
+type Router struct {
+	Client client.Client
+}
+
+// RouteToHost is an activity that returns the host to run on for a request.
+func (r *Router) RouteToHost(ctx context.Context, request RouteRequest) (Host, error) {
+	handle, err := r.Client.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   RegistryWorkflowID,
+		UpdateName:   RouteUpdate,
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+		Args:         []interface{}{request},
+	})
+	if err != nil {
+		return Host{}, fmt.Errorf("failed routing: %w", err)
+	}
+	var host Host
+	err = handle.Get(ctx, &host)
+	return host, err
+}
+
+// IsHostAlive is an activity that returns whether a host still heartbeats.
+func (r *Router) IsHostAlive(ctx context.Context, hostID string) (bool, error) {
+	val, err := r.Client.QueryWorkflow(ctx, RegistryWorkflowID, "", HostsQuery)
+	if err != nil {
+		return false, fmt.Errorf("failed querying hosts: %w", err)
+	}
+	var status RegistryStatus
+	if err := val.Get(&status); err != nil {
+		return false, err
+	}
+	return status.Alive(hostID, time.Now()), nil
+}
+
+// Heartbeat registers a host with the HostRegistryWorkflow, starting the workflow on taskQueue if
+// needed, and keeps it alive by signaling it every interval until ctx is done. The host then
+// leaves the registry, so that its keys fail over right away.
+func Heartbeat(ctx context.Context, c client.Client, host Host, taskQueue string, interval time.Duration) {
+	options := client.StartWorkflowOptions{ID: RegistryWorkflowID, TaskQueue: taskQueue}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, err := c.SignalWithStartWorkflow(ctx, RegistryWorkflowID, HeartbeatSignal, host, options, HostRegistryWorkflow, RegistryState{})
+		if err != nil && ctx.Err() == nil {
+			log.Println("Heartbeat failed", err)
+		}
+		select {
+		case <-ctx.Done():
+			leaveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := c.SignalWorkflow(leaveCtx, RegistryWorkflowID, "", LeaveSignal, host.ID); err != nil {
+				log.Println("Leave failed", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package worker_specific_task_queues
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// SameHostOptions are options for RunOnSameHost.
+type SameHostOptions struct {
+	// Affinity key, see RouteRequest.
+	Key string
+	// Attempts of the function, each on another host when possible. Default 5.
+	MaxAttempts int
+	// How often the host is checked while the function runs. Default 10 seconds.
+	HealthCheckInterval time.Duration
+	// Options of the activities of the function. TaskQueue is replaced by the one of the host.
+	// Default ScheduleToCloseTimeout of 1 minute, since the activities cannot progress anymore
+	// once the worker of the host goes away.
+	ActivityOptions workflow.ActivityOptions
+}
+
+// RunOnSameHost runs fn with a context whose activities run on the host routed for the key, as
+// long as the host is healthy. When the host stops heartbeating, fn is cancelled and run again
+// from the start on another host. When fn fails, it is also run again, on another host when
+// possible, up to MaxAttempts. The Router activities must run on the task queue of ctx.
+func RunOnSameHost(ctx workflow.Context, options SameHostOptions, fn func(ctx workflow.Context, host Host) error) error {
+	logger := workflow.GetLogger(ctx)
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 5
+	}
+	if options.HealthCheckInterval <= 0 {
+		options.HealthCheckInterval = 10 * time.Second
+	}
+	if options.ActivityOptions.ScheduleToCloseTimeout == 0 && options.ActivityOptions.StartToCloseTimeout == 0 {
+		options.ActivityOptions.ScheduleToCloseTimeout = time.Minute
+	}
+	routerCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 1,
+		},
+	})
+
+	var r *Router
+	var failedHosts []string
+	var err error
+	for attempt := 1; attempt <= options.MaxAttempts; attempt++ {
+		var host Host
+		err = workflow.ExecuteActivity(routerCtx, r.RouteToHost, RouteRequest{Key: options.Key, Exclude: failedHosts}).Get(ctx, &host)
+		if err == nil {
+			err = runWhileHealthy(ctx, routerCtx, options, host, fn)
+			if err == nil {
+				return nil
+			}
+			failedHosts = append(failedHosts, host.ID)
+		}
+		logger.Error("Attempt failed, trying on another host.", "Attempt", attempt, "HostID", host.ID, "Error", err)
+	}
+	return err
+}
+
+// runWhileHealthy runs fn on a host, checking every HealthCheckInterval that the host is alive.
+func runWhileHealthy(ctx, routerCtx workflow.Context, options SameHostOptions, host Host, fn func(ctx workflow.Context, host Host) error) error {
+	hostCtx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+	ao := options.ActivityOptions
+	ao.TaskQueue = host.TaskQueue
+	hostCtx = workflow.WithActivityOptions(hostCtx, ao)
+
+	var done bool
+	var err error
+	workflow.Go(hostCtx, func(ctx workflow.Context) {
+		err = fn(ctx, host)
+		done = true
+	})
+
+	var r *Router
+	for {
+		ok, awaitErr := workflow.AwaitWithTimeout(ctx, options.HealthCheckInterval, func() bool { return done })
+		if awaitErr != nil {
+			return awaitErr
+		} else if ok {
+			return err
+		}
+
+		// A failed check does not tell anything about the host, so only a host known dead stops fn
+		var alive bool
+		if checkErr := workflow.ExecuteActivity(routerCtx, r.IsHostAlive, host.ID).Get(ctx, &alive); checkErr != nil || alive {
+			continue
+		}
+		cancel()
+		if err := workflow.Await(ctx, func() bool { return done }); err != nil {
+			return err
+		}
+		return fmt.Errorf("host %s stopped heartbeating", host.ID)
+	}
+}
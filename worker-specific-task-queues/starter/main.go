@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 
+	"github.com/google/uuid"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/contrib/envconfig"
 
@@ -11,6 +13,10 @@ import (
 )
 
 func main() {
+	var customerID string
+	flag.StringVar(&customerID, "customer", "customer-1", "Customer of the file, the files of a customer are processed on the same host")
+	flag.Parse()
+
 	// The client is a heavyweight object that should be created once per process.
 	c, err := client.Dial(envconfig.MustLoadDefaultClientOptions())
 	if err != nil {
@@ -19,11 +25,11 @@ func main() {
 	defer c.Close()
 
 	workflowOptions := client.StartWorkflowOptions{
-		ID:        "worker_specific_task_queues_WorkflowID",
+		ID:        "worker_specific_task_queues_WorkflowID_" + customerID + "_" + uuid.New().String(),
 		TaskQueue: "shared-task-queue",
 	}
 
-	we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, worker_specific_task_queues.FileProcessingWorkflow)
+	we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, worker_specific_task_queues.FileProcessingWorkflow, customerID)
 	if err != nil {
 		log.Fatalln("Unable to execute workflow", err)
 	}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"sync"
+	"time"
 
 	worker_specific_task_queues "github.com/temporalio/samples-go/worker-specific-task-queues"
 
-	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/contrib/envconfig"
 	"go.temporal.io/sdk/worker"
@@ -22,7 +26,9 @@ func main() {
 		log.Fatalln("Unable to create client", err)
 	}
 	defer c.Close()
-	uniqueTaskQueue := worker_specific_task_queues.WorkerSpecificTaskQueue{
+	hostname, _ := os.Hostname()
+	host := worker_specific_task_queues.Host{
+		ID:        fmt.Sprintf("%s-%d", hostname, os.Getpid()),
 		TaskQueue: uuid.New().String(),
 	}
 	var wg sync.WaitGroup
@@ -31,10 +37,9 @@ func main() {
 		defer wg.Done()
 		w := worker.New(c, "shared-task-queue", worker.Options{})
 		w.RegisterWorkflow(worker_specific_task_queues.FileProcessingWorkflow)
+		w.RegisterWorkflow(worker_specific_task_queues.HostRegistryWorkflow)
+		w.RegisterActivity(&worker_specific_task_queues.Router{Client: c})
 
-		w.RegisterActivityWithOptions(uniqueTaskQueue.GetWorkerSpecificTaskQueue, activity.RegisterOptions{
-			Name: "GetWorkerSpecificTaskQueue",
-		})
 		err = w.Run(worker.InterruptCh())
 		if err != nil {
 			log.Fatalln("Unable to start worker", err)
@@ -45,7 +50,7 @@ func main() {
 	go func() {
 		defer wg.Done()
 		// Create a new worker listening on the unique queue
-		uniqueTaskQueueWorker := worker.New(c, uniqueTaskQueue.TaskQueue, worker.Options{})
+		uniqueTaskQueueWorker := worker.New(c, host.TaskQueue, worker.Options{})
 
 		uniqueTaskQueueWorker.RegisterActivity(worker_specific_task_queues.DownloadFile)
 		uniqueTaskQueueWorker.RegisterActivity(worker_specific_task_queues.ProcessFile)
@@ -56,6 +61,16 @@ func main() {
 			log.Fatalln("Unable to start worker", err)
 		}
 	}()
-	// Wait for both workers to close
+
+	// Register the unique queue with the host registry until interrupted
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		log.Println("Heartbeating", "HostID", host.ID, "TaskQueue", host.TaskQueue)
+		worker_specific_task_queues.Heartbeat(ctx, c, host, "shared-task-queue", 5*time.Second)
+	}()
+	// Wait for the workers and the heartbeat to stop
 	wg.Wait()
 }
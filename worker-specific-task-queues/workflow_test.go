@@ -4,32 +4,43 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
 	"github.com/stretchr/testify/require"
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
 	"go.temporal.io/sdk/testsuite"
 )
 
+var (
+	host1 = Host{ID: "host1", TaskQueue: "host1-task-queue"}
+	host2 = Host{ID: "host2", TaskQueue: "host2-task-queue"}
+)
+
 func Test_Workflow(t *testing.T) {
 	testSuite := &testsuite.WorkflowTestSuite{}
 	env := testSuite.NewTestWorkflowEnvironment()
 
 	// Mock activity implementation
-	var a WorkerSpecificTaskQueue
-	env.RegisterActivityWithOptions(a.GetWorkerSpecificTaskQueue, activity.RegisterOptions{
-		Name: "GetWorkerSpecificTaskQueue",
-	})
-	env.OnActivity("GetWorkerSpecificTaskQueue", mock.Anything).Return("unique-task-queue", nil)
+	var r *Router
+	env.RegisterActivity(r)
+	env.OnActivity(r.RouteToHost, mock.Anything, RouteRequest{Key: "customer-1"}).Return(host1, nil)
 	env.OnActivity(DownloadFile, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity(ProcessFile, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity(DeleteFile, mock.Anything, mock.Anything).Return(nil)
 
-	env.ExecuteWorkflow(FileProcessingWorkflow)
+	var taskQueues []string
+	env.SetOnActivityStartedListener(func(activityInfo *activity.Info, ctx context.Context, args converter.EncodedValues) {
+		taskQueues = append(taskQueues, activityInfo.TaskQueue)
+	})
+
+	env.ExecuteWorkflow(FileProcessingWorkflow, "customer-1")
 
 	require.True(t, env.IsWorkflowCompleted())
 	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, []string{"host1-task-queue", "host1-task-queue", "host1-task-queue"}, taskQueues[1:])
 }
 
 func Test_RetrySuccess(t *testing.T) {
@@ -37,25 +48,23 @@ func Test_RetrySuccess(t *testing.T) {
 	env := testSuite.NewTestWorkflowEnvironment()
 
 	// Mock activity implementation
-	var a WorkerSpecificTaskQueue
-	env.RegisterActivityWithOptions(a.GetWorkerSpecificTaskQueue, activity.RegisterOptions{
-		Name: "GetWorkerSpecificTaskQueue",
-	})
+	var r *Router
+	env.RegisterActivity(r)
 
 	counter := 0
-	env.OnActivity("GetWorkerSpecificTaskQueue", mock.Anything).Return(func(ctx context.Context) (string, error) {
+	env.OnActivity(r.RouteToHost, mock.Anything, mock.Anything).Return(func(ctx context.Context, request RouteRequest) (Host, error) {
 		counter++
 		// Workflow retries up to 5 times
 		if counter < 3 {
-			return "", errors.New("temporary error")
+			return Host{}, errors.New("temporary error")
 		}
-		return "unique-task-queue", nil
+		return host1, nil
 	})
 	env.OnActivity(DownloadFile, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity(ProcessFile, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity(DeleteFile, mock.Anything, mock.Anything).Return(nil)
 
-	env.ExecuteWorkflow(FileProcessingWorkflow)
+	env.ExecuteWorkflow(FileProcessingWorkflow, "customer-1")
 
 	require.True(t, env.IsWorkflowCompleted())
 	require.NoError(t, env.GetWorkflowError())
@@ -66,19 +75,47 @@ func Test_RetryFail(t *testing.T) {
 	env := testSuite.NewTestWorkflowEnvironment()
 
 	// Mock activity implementation
-	var a WorkerSpecificTaskQueue
-	env.RegisterActivityWithOptions(a.GetWorkerSpecificTaskQueue, activity.RegisterOptions{
-		Name: "GetWorkerSpecificTaskQueue",
-	})
-	env.OnActivity("GetWorkerSpecificTaskQueue", mock.Anything).Return(func(ctx context.Context) (string, error) {
-		return "", errors.New("error to show a retry mechanic failure")
+	var r *Router
+	env.RegisterActivity(r)
+	env.OnActivity(r.RouteToHost, mock.Anything, mock.Anything).Return(func(ctx context.Context, request RouteRequest) (Host, error) {
+		return Host{}, errors.New("error to show a retry mechanic failure")
 	})
 	env.OnActivity(DownloadFile, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity(ProcessFile, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity(DeleteFile, mock.Anything, mock.Anything).Return(nil)
 
-	env.ExecuteWorkflow(FileProcessingWorkflow)
+	env.ExecuteWorkflow(FileProcessingWorkflow, "customer-1")
 
 	require.True(t, env.IsWorkflowCompleted())
 	require.Error(t, env.GetWorkflowError())
 }
+
+func Test_Failover(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+
+	// host1 stops heartbeating while downloading, the file is processed again on host2
+	var r *Router
+	env.RegisterActivity(r)
+	env.OnActivity(r.RouteToHost, mock.Anything, RouteRequest{Key: "customer-1"}).Return(host1, nil).Once()
+	env.OnActivity(r.RouteToHost, mock.Anything, RouteRequest{Key: "customer-1", Exclude: []string{"host1"}}).Return(host2, nil).Once()
+	env.OnActivity(r.IsHostAlive, mock.Anything, "host1").Return(false, nil).Once()
+	env.OnActivity(DownloadFile, mock.Anything, mock.Anything, mock.Anything).After(time.Hour).Return(nil).Once()
+	env.OnActivity(DownloadFile, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	env.OnActivity(ProcessFile, mock.Anything, mock.Anything).Return(nil).Once()
+	env.OnActivity(DeleteFile, mock.Anything, mock.Anything).Return(nil).Once()
+
+	var taskQueues []string
+	env.SetOnActivityStartedListener(func(activityInfo *activity.Info, ctx context.Context, args converter.EncodedValues) {
+		if activityInfo.ActivityType.Name == "DownloadFile" {
+			taskQueues = append(taskQueues, activityInfo.TaskQueue)
+		}
+	})
+
+	env.ExecuteWorkflow(FileProcessingWorkflow, "customer-1")
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, []string{"host1-task-queue", "host2-task-queue"}, taskQueues)
+	env.AssertExpectations(t)
+}
@@ -2,10 +2,22 @@ package streams
 
 import (
 	"context"
+	"encoding/json"
+	"sync/atomic"
 	"time"
 
+	enumspb "go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/contrib/workflowstreams"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	// followHeartbeatInterval keeps FollowSource alive while its source is idle.
+	followHeartbeatInterval = 5 * time.Second
+	// offsetReportInterval is how often FollowSource reports its progress to the aggregator.
+	offsetReportInterval = time.Second
 )
 
 // ChargeCard (scenario 1) charges a card and publishes fine-grained progress
@@ -30,3 +42,130 @@ func ChargeCard(ctx context.Context, orderID string) (string, error) {
 	progress.Publish(ProgressEvent{Message: "card charged"}, false)
 	return "charge-" + orderID, nil
 }
+
+// FollowSource (scenario 7) subscribes to the topics of a source workflow's
+// stream and re-publishes the items that pass the topic filters, projected, to
+// the stream of the aggregator workflow that scheduled it. It reports its
+// progress with SourceOffsetSignal, only after the re-published items were
+// flushed, and returns the next offset to read when the source workflow has
+// completed. An item may be re-published twice if the activity fails between
+// the flush and the report.
+func FollowSource(ctx context.Context, input FollowSourceInput) (int64, error) {
+	logger := activity.GetLogger(ctx)
+	info := activity.GetInfo(ctx)
+	c := activity.GetClient(ctx)
+	dc := converter.GetDefaultDataConverter()
+	sourceID := input.Source.WorkflowID
+
+	next := input.FromOffset
+	// A previous attempt has re-published the items up to its last heartbeat.
+	var heartbeatOffset int64
+	if activity.HasHeartbeatDetails(ctx) && activity.GetHeartbeatDetails(ctx, &heartbeatOffset) == nil &&
+		heartbeatOffset > next {
+		next = heartbeatOffset
+	}
+
+	derived, err := workflowstreams.NewClientFromActivity(ctx, workflowstreams.Options{
+		BatchInterval: 200 * time.Millisecond,
+	})
+	if err != nil {
+		return next, err
+	}
+	// Close flushes any buffered items, also after the activity was cancelled.
+	defer func() { _ = derived.Close(context.Background()) }()
+	source := workflowstreams.NewClient(c, sourceID, workflowstreams.Options{})
+	defer func() { _ = source.Close(context.Background()) }()
+
+	var reported atomic.Int64
+	reported.Store(next)
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	go func() {
+		ticker := time.NewTicker(followHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				activity.RecordHeartbeat(ctx, reported.Load())
+			case <-heartbeatCtx.Done():
+				return
+			}
+		}
+	}()
+
+	// report flushes the re-published items and then tells the aggregator that
+	// the source was read up to next. It doesn't use ctx, as it also runs to
+	// save the progress when the activity was cancelled for continue-as-new.
+	report := func() error {
+		if next == reported.Load() {
+			return nil
+		}
+		reportCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := derived.Flush(reportCtx); err != nil {
+			return err
+		}
+		reported.Store(next)
+		activity.RecordHeartbeat(ctx, next)
+		// Doesn't specify runId as the aggregator calls continue-as-new.
+		return c.SignalWorkflow(reportCtx, info.WorkflowExecution.ID, "", SourceOffsetSignal,
+			SourceOffset{WorkflowID: sourceID, NextOffset: next})
+	}
+
+	topics := make(map[string]SourceTopic, len(input.Source.Topics))
+	names := make([]string, 0, len(input.Source.Topics))
+	for _, topic := range input.Source.Topics {
+		topics[topic.Topic] = topic
+		names = append(names, topic.Topic)
+	}
+
+	lastReport := time.Now()
+	for item, err := range source.Subscribe(ctx, workflowstreams.SubscribeOptions{Topics: names, FromOffset: next}) {
+		if err != nil {
+			reportErr := report()
+			if ctx.Err() != nil {
+				return next, ctx.Err()
+			}
+			if closed, describeErr := isWorkflowClosed(ctx, c, sourceID); describeErr == nil && closed {
+				return next, reportErr
+			}
+			return next, err
+		}
+
+		topic := topics[item.Topic]
+		var data json.RawMessage
+		if err := dc.FromPayload(item.Data, &data); err != nil {
+			logger.Warn("Skipping item that is not JSON", "Source", sourceID, "Offset", item.Offset, "Error", err)
+		} else if projected, ok := topic.transform(data); ok {
+			derivedTopic := topic.DerivedTopic
+			if derivedTopic == "" {
+				derivedTopic = item.Topic
+			}
+			derived.Topic(derivedTopic).Publish(AggregatedEvent{
+				Source: sourceID,
+				Topic:  item.Topic,
+				Offset: item.Offset,
+				Data:   projected,
+			}, false)
+		}
+		next = item.Offset + 1
+
+		if time.Since(lastReport) >= offsetReportInterval {
+			if err := report(); err != nil {
+				return next, err
+			}
+			lastReport = time.Now()
+		}
+	}
+
+	// The subscription ends when the source workflow has completed.
+	return next, report()
+}
+
+func isWorkflowClosed(ctx context.Context, c client.Client, workflowID string) (bool, error) {
+	resp, err := c.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		return false, err
+	}
+	return resp.GetWorkflowExecutionInfo().GetStatus() != enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/contrib/envconfig"
+	"go.temporal.io/sdk/contrib/workflowstreams"
+	"go.temporal.io/sdk/converter"
+
+	streams "github.com/temporalio/samples-go/workflowstreams"
+)
+
+var orderIDs = []string{"order-1", "order-2", "order-3"}
+
+// Scenario 7: fan-in. Starts a few order workflows and an aggregator workflow
+// that re-publishes the shipped and complete status events of all of them, with
+// just the fields a dashboard needs, into a single feed topic. A subscriber
+// follows the feed until every order is complete.
+func main() {
+	c, err := client.Dial(envconfig.MustLoadDefaultClientOptions())
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	var sources []streams.AggregatorSource
+	for _, orderID := range orderIDs {
+		workflowID := "workflow-streams-order-" + uuid.NewString()
+		we, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+			ID:        workflowID,
+			TaskQueue: streams.TaskQueue,
+		}, streams.OrderWorkflow, streams.OrderInput{OrderID: orderID})
+		if err != nil {
+			log.Fatalln("Unable to execute workflow", err)
+		}
+		log.Println("Started workflow", "WorkflowID", we.GetID(), "RunID", we.GetRunID())
+
+		sources = append(sources, streams.AggregatorSource{
+			WorkflowID: workflowID,
+			Topics: []streams.SourceTopic{{
+				Topic:        streams.TopicStatus,
+				DerivedTopic: streams.TopicFeed,
+				Filters: []streams.FilterRule{
+					{Field: "kind", Values: []interface{}{"shipped", "complete"}},
+				},
+				Fields: []string{"kind", "orderId"},
+			}},
+		})
+	}
+
+	workflowID := "workflow-streams-aggregator-" + uuid.NewString()
+	we, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        workflowID,
+		TaskQueue: streams.TaskQueue,
+	}, streams.AggregatorWorkflow, streams.AggregatorInput{Sources: sources})
+	if err != nil {
+		log.Fatalln("Unable to execute workflow", err)
+	}
+	log.Println("Started workflow", "WorkflowID", we.GetID(), "RunID", we.GetRunID())
+
+	dc := converter.GetDefaultDataConverter()
+	stream := workflowstreams.NewClient(c, workflowID, workflowstreams.Options{})
+	defer func() { _ = stream.Close(ctx) }()
+
+	completed := 0
+	for item, err := range stream.Topic(streams.TopicFeed).Subscribe(ctx, 0) {
+		if err != nil {
+			log.Fatalln("subscribe:", err)
+		}
+		var evt streams.AggregatedEvent
+		if err := dc.FromPayload(item.Data, &evt); err != nil {
+			log.Fatalln("decode aggregated event:", err)
+		}
+		var status streams.StatusEvent
+		if err := json.Unmarshal(evt.Data, &status); err != nil {
+			log.Fatalln("decode status:", err)
+		}
+		fmt.Printf("[feed] offset=%d  %s\n", item.Offset, evt.Data)
+		if status.Kind == "complete" {
+			completed++
+		}
+		if completed == len(orderIDs) {
+			return
+		}
+	}
+}
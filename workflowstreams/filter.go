@@ -0,0 +1,93 @@
+package streams
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// transform applies the filters and the projection of the topic to a JSON item.
+// It returns false when the item is filtered out. Items that are not JSON
+// objects have no fields, so they only pass a topic without filters, as is.
+func (t SourceTopic) transform(data json.RawMessage) (json.RawMessage, bool) {
+	if len(t.Filters) == 0 && len(t.Fields) == 0 {
+		return data, true
+	}
+	var item map[string]interface{}
+	if err := json.Unmarshal(data, &item); err != nil || item == nil {
+		return data, len(t.Filters) == 0
+	}
+	for _, rule := range t.Filters {
+		if !rule.matches(item) {
+			return nil, false
+		}
+	}
+	if len(t.Fields) == 0 {
+		return data, true
+	}
+	projected, err := json.Marshal(project(item, t.Fields))
+	if err != nil {
+		return nil, false
+	}
+	return projected, true
+}
+
+func (r FilterRule) matches(item map[string]interface{}) bool {
+	matched := false
+	if value, ok := lookup(item, r.Field); ok {
+		for _, v := range r.Values {
+			if equalJSON(value, v) {
+				matched = true
+				break
+			}
+		}
+	}
+	return matched != r.Negate
+}
+
+// equalJSON compares values by their JSON encoding, so that for example a rule
+// value of 1 matches the float64 1 an item decodes to.
+func equalJSON(a, b interface{}) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// lookup returns the value at the dot-separated path of the item.
+func lookup(item map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = item
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// project returns the fields of the item, keeping nested fields at their path.
+// Fields the item doesn't have are left out.
+func project(item map[string]interface{}, fields []string) map[string]interface{} {
+	projected := map[string]interface{}{}
+	for _, field := range fields {
+		value, ok := lookup(item, field)
+		if !ok {
+			continue
+		}
+		keys := strings.Split(field, ".")
+		object := projected
+		for _, key := range keys[:len(keys)-1] {
+			nested, ok := object[key].(map[string]interface{})
+			if !ok {
+				nested = map[string]interface{}{}
+				object[key] = nested
+			}
+			object = nested
+		}
+		object[keys[len(keys)-1]] = value
+	}
+	return projected
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/contrib/workflowstreams"
+	"go.temporal.io/sdk/converter"
+)
+
+// Event types sent to gateway clients. Stream items are sent as eventItem over
+// WebSocket and as an SSE event named after their topic, so topics cannot use
+// the other names.
+const (
+	eventItem = "item"
+	// eventTruncated tells the client that the items from RequestedOffset up to
+	// Offset were truncated before it could read them. The stream continues at Offset.
+	eventTruncated = "truncated"
+	// eventEnd is sent when the stream has no more items, for example because the
+	// workflow completed. Clients should not reconnect after it.
+	eventEnd = "end"
+	// eventError is sent when the subscription failed.
+	eventError = "error"
+)
+
+// keepAliveInterval is how often idle connections are kept alive, with an SSE
+// comment or a WebSocket ping, so that proxies don't close them.
+const keepAliveInterval = 15 * time.Second
+
+// event is a message sent to gateway clients. It is the JSON message of the
+// WebSocket endpoint; the SSE endpoint sends the same fields as SSE events.
+type event struct {
+	Type            string          `json:"type"`
+	Topic           string          `json:"topic,omitempty"`
+	Offset          int64           `json:"offset"`
+	RequestedOffset int64           `json:"requestedOffset,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// gateway exposes the stream topics of any workflow over HTTP.
+type gateway struct {
+	client      client.Client
+	dc          converter.DataConverter
+	allowOrigin string
+	upgrader    websocket.Upgrader
+}
+
+func newGateway(c client.Client, allowOrigin string) *gateway {
+	g := &gateway{
+		client:      c,
+		dc:          converter.GetDefaultDataConverter(),
+		allowOrigin: allowOrigin,
+	}
+	g.upgrader.CheckOrigin = func(r *http.Request) bool {
+		return allowOrigin == "*" || r.Header.Get("Origin") == allowOrigin
+	}
+	return g
+}
+
+func (g *gateway) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /workflows/{workflowID}/events", g.serveSSE)
+	mux.HandleFunc("GET /workflows/{workflowID}/ws", g.serveWebSocket)
+	return mux
+}
+
+// serveSSE streams the topics of the workflow as Server-Sent Events. Every
+// item carries its offset as event ID, so a reconnecting EventSource resumes
+// after the last item it received through the Last-Event-ID header.
+func (g *gateway) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	fromOffset, err := resumeOffset(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", g.allowOrigin)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	events := g.subscribe(ctx, r.PathValue("workflowID"), r.URL.Query()["topic"], fromOffset)
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+			if e.Type == eventEnd || e.Type == eventError {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, e event) error {
+	var err error
+	switch e.Type {
+	case eventItem:
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Offset, e.Topic, e.Data)
+	default:
+		// The offset of these events is not an item the client received, so they have no ID.
+		data, _ := json.Marshal(e)
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	}
+	return err
+}
+
+// serveWebSocket streams the topics of the workflow as JSON event messages over
+// WebSocket. Browsers cannot set headers on WebSocket connections, so clients
+// resume with the offset query parameter set to one past the last item they received.
+func (g *gateway) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	fromOffset, err := resumeOffset(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Upgrade replies with an error itself.
+	conn, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	// Reads until the client closes the connection, which stops the subscription.
+	// The client is not expected to send any messages.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	events := g.subscribe(ctx, r.PathValue("workflowID"), r.URL.Query()["topic"], fromOffset)
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+			if e.Type == eventEnd || e.Type == eventError {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, e.Type), time.Now().Add(time.Second))
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(keepAliveInterval)); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resumeOffset returns the offset to start streaming from: one past the
+// Last-Event-ID header, or else the offset query parameter. Zero starts from
+// the beginning of the stream. Negative offsets are rejected.
+func resumeOffset(r *http.Request) (int64, error) {
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		offset, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, fmt.Errorf("invalid Last-Event-ID %q", lastEventID)
+		}
+		return offset + 1, nil
+	}
+	if param := r.URL.Query().Get("offset"); param != "" {
+		offset, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, fmt.Errorf("invalid offset %q", param)
+		}
+		return offset, nil
+	}
+	return 0, nil
+}
+
+// streamItem is an item of a workflow stream, as read by subscribe.
+type streamItem struct {
+	Topic  string
+	Offset int64
+	Data   *commonpb.Payload
+}
+
+// subscribe streams the items of the topics of the workflow, or of all topics
+// if there are none, from fromOffset until ctx is done. The channel is closed
+// after an eventEnd or eventError event.
+func (g *gateway) subscribe(ctx context.Context, workflowID string, topics []string, fromOffset int64) <-chan event {
+	// Offsets are global across topics. Subscribing to all topics and
+	// filtering in streamEvents makes every gap in the offsets a truncation.
+	items := func(yield func(streamItem, error) bool) {
+		stream := workflowstreams.NewClient(g.client, workflowID, workflowstreams.Options{})
+		defer func() { _ = stream.Close(context.Background()) }()
+		for item, err := range stream.Subscribe(ctx, workflowstreams.SubscribeOptions{FromOffset: fromOffset}) {
+			if !yield(streamItem{Topic: item.Topic, Offset: item.Offset, Data: item.Data}, err) {
+				return
+			}
+		}
+	}
+	return g.streamEvents(ctx, workflowID, items, topics, fromOffset)
+}
+
+// streamEvents turns the items of the workflow starting at fromOffset into
+// events for the topics, sending an eventTruncated event for every gap in the
+// offsets and an eventEnd or eventError event once items ends.
+func (g *gateway) streamEvents(ctx context.Context, workflowID string, items iter.Seq2[streamItem, error], topics []string, fromOffset int64) <-chan event {
+	events := make(chan event)
+	go func() {
+		defer close(events)
+		send := func(e event) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		wanted := make(map[string]bool, len(topics))
+		for _, topic := range topics {
+			wanted[topic] = true
+		}
+		next := fromOffset
+		for item, err := range items {
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Println("Subscription failed", "WorkflowID", workflowID, "Error", err)
+					send(event{Type: eventError, Offset: next, Error: err.Error()})
+				}
+				return
+			}
+			if item.Offset > next {
+				if !send(event{Type: eventTruncated, RequestedOffset: next, Offset: item.Offset}) {
+					return
+				}
+			}
+			next = item.Offset + 1
+			if len(wanted) > 0 && !wanted[item.Topic] {
+				continue
+			}
+			if !send(event{Type: eventItem, Topic: item.Topic, Offset: item.Offset, Data: g.decode(item.Data)}) {
+				return
+			}
+		}
+		send(event{Type: eventEnd, Offset: next})
+	}()
+	return events
+}
+
+// decode returns the payload as single-line JSON. Payloads that are not JSON
+// are sent as a JSON string of their data converter representation.
+func (g *gateway) decode(payload *commonpb.Payload) json.RawMessage {
+	var data json.RawMessage
+	if err := g.dc.FromPayload(payload, &data); err == nil && json.Valid(data) {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, data); err == nil {
+			return compacted.Bytes()
+		}
+	}
+	data, _ = json.Marshal(g.dc.ToString(payload))
+	return data
+}
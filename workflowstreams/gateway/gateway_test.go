@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+)
+
+func TestResumeOffset(t *testing.T) {
+	tests := map[string]struct {
+		lastEventID string
+		query       string
+		offset      int64
+		err         string
+	}{
+		"none":                      {},
+		"last event id":             {lastEventID: "41", offset: 42},
+		"offset":                    {query: "offset=7", offset: 7},
+		"last event id over offset": {lastEventID: "41", query: "offset=7", offset: 42},
+		"invalid last event id":     {lastEventID: "abc", err: `invalid Last-Event-ID "abc"`},
+		"negative last event id":    {lastEventID: "-1", err: `invalid Last-Event-ID "-1"`},
+		"invalid offset":            {query: "offset=abc", err: `invalid offset "abc"`},
+		"negative offset":           {query: "offset=-3", err: `invalid offset "-3"`},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/workflows/wf/events?"+test.query, nil)
+			if test.lastEventID != "" {
+				r.Header.Set("Last-Event-ID", test.lastEventID)
+			}
+			offset, err := resumeOffset(r)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.offset, offset)
+		})
+	}
+}
+
+func TestServeSSE_InvalidOffset(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/workflows/wf/events", nil)
+	r.Header.Set("Last-Event-ID", "-1")
+	w := httptest.NewRecorder()
+	newGateway(nil, "*").routes().ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWriteSSE(t *testing.T) {
+	tests := map[string]struct {
+		event event
+		sse   string
+	}{
+		"item": {
+			event: event{Type: eventItem, Topic: "status", Offset: 3, Data: []byte(`{"stage":"shipped"}`)},
+			sse:   "id: 3\nevent: status\ndata: {\"stage\":\"shipped\"}\n\n",
+		},
+		"truncated": {
+			event: event{Type: eventTruncated, RequestedOffset: 2, Offset: 5},
+			sse:   "event: truncated\ndata: {\"type\":\"truncated\",\"offset\":5,\"requestedOffset\":2}\n\n",
+		},
+		"end": {
+			event: event{Type: eventEnd, Offset: 6},
+			sse:   "event: end\ndata: {\"type\":\"end\",\"offset\":6}\n\n",
+		},
+		"error": {
+			event: event{Type: eventError, Offset: 6, Error: "not found"},
+			sse:   "event: error\ndata: {\"type\":\"error\",\"offset\":6,\"error\":\"not found\"}\n\n",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			require.NoError(t, writeSSE(w, test.event))
+			require.Equal(t, test.sse, w.Body.String())
+		})
+	}
+}
+
+// seq returns an iterator over the items followed by err, if not nil.
+func seq(err error, items ...streamItem) func(func(streamItem, error) bool) {
+	return func(yield func(streamItem, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(streamItem{}, err)
+		}
+	}
+}
+
+func newItem(t *testing.T, topic string, offset int64, value any) streamItem {
+	payload, err := converter.GetDefaultDataConverter().ToPayload(value)
+	require.NoError(t, err)
+	return streamItem{Topic: topic, Offset: offset, Data: payload}
+}
+
+func collect(events <-chan event) []event {
+	var collected []event
+	for e := range events {
+		collected = append(collected, e)
+	}
+	return collected
+}
+
+func TestStreamEvents(t *testing.T) {
+	g := newGateway(nil, "*")
+	stream := seq(nil,
+		newItem(t, "status", 2, map[string]string{"stage": "received"}),
+		newItem(t, "progress", 3, map[string]int{"percent": 50}),
+		newItem(t, "status", 6, map[string]string{"stage": "shipped"}),
+	)
+
+	events := collect(g.streamEvents(context.Background(), "wf", stream, []string{"status"}, 2))
+	require.Equal(t, []event{
+		{Type: eventItem, Topic: "status", Offset: 2, Data: []byte(`{"stage":"received"}`)},
+		// The progress item at offset 3 is filtered out, so only 4 and 5 were truncated.
+		{Type: eventTruncated, RequestedOffset: 4, Offset: 6},
+		{Type: eventItem, Topic: "status", Offset: 6, Data: []byte(`{"stage":"shipped"}`)},
+		{Type: eventEnd, Offset: 7},
+	}, events)
+}
+
+func TestStreamEvents_TruncatedBeforeFirstItem(t *testing.T) {
+	g := newGateway(nil, "*")
+	stream := seq(nil, newItem(t, "status", 10, "late"))
+
+	events := collect(g.streamEvents(context.Background(), "wf", stream, nil, 4))
+	require.Equal(t, []event{
+		{Type: eventTruncated, RequestedOffset: 4, Offset: 10},
+		{Type: eventItem, Topic: "status", Offset: 10, Data: []byte(`"late"`)},
+		{Type: eventEnd, Offset: 11},
+	}, events)
+}
+
+func TestStreamEvents_Error(t *testing.T) {
+	g := newGateway(nil, "*")
+	stream := seq(errors.New("workflow not found"), newItem(t, "status", 0, "received"))
+
+	events := collect(g.streamEvents(context.Background(), "wf", stream, nil, 0))
+	require.Equal(t, []event{
+		{Type: eventItem, Topic: "status", Offset: 0, Data: []byte(`"received"`)},
+		{Type: eventError, Offset: 1, Error: "workflow not found"},
+	}, events)
+}
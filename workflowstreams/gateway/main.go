@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/contrib/envconfig"
+)
+
+// Scenario 6: HTTP gateway. Exposes the stream topics of any workflow to
+// browsers and other HTTP clients, as Server-Sent Events on
+// /workflows/{workflowID}/events and as WebSocket on /workflows/{workflowID}/ws.
+// Select topics with repeated topic query parameters, all topics are streamed
+// without them. Resume with the Last-Event-ID header or the offset query parameter.
+func main() {
+	var addr, allowOrigin string
+	flag.StringVar(&addr, "addr", ":8080", "Address to listen on.")
+	flag.StringVar(&allowOrigin, "allow-origin", "*", "Origin allowed to connect from a browser, * for any.")
+	flag.Parse()
+
+	c, err := client.Dial(envconfig.MustLoadDefaultClientOptions())
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	log.Println("Gateway listening", "Addr", addr)
+	if err := http.ListenAndServe(addr, newGateway(c, allowOrigin).routes()); err != nil {
+		log.Fatalln("Gateway failed", err)
+	}
+}
@@ -6,6 +6,7 @@
 package streams
 
 import (
+	"encoding/json"
 	"time"
 
 	"go.temporal.io/sdk/contrib/workflowstreams"
@@ -27,11 +28,16 @@ const (
 	TopicDelta    = "delta"
 	TopicComplete = "complete"
 	TopicRetry    = "retry"
+	TopicFeed     = "feed"
 )
 
-// CloseSignal tells the hub workflow (scenario 3) to stop hosting its stream.
+// CloseSignal tells the hub workflow (scenario 3) and the aggregator workflow
+// (scenario 7) to stop hosting their stream.
 const CloseSignal = "close"
 
+// SourceOffsetSignal reports a SourceOffset to the aggregator workflow (scenario 7).
+const SourceOffsetSignal = "source-offset"
+
 // Each workflow input carries an optional *workflowstreams.WorkflowStreamState
 // so the stream can survive continue-as-new: thread the prior run's state back
 // in and pass it to NewWorkflowStream. It is nil on a fresh start.
@@ -71,6 +77,61 @@ type LLMInput struct {
 	StreamState *workflowstreams.WorkflowStreamState `json:"streamState,omitempty"`
 }
 
+// AggregatorInput is the input to AggregatorWorkflow (scenario 7).
+type AggregatorInput struct {
+	Sources []AggregatorSource `json:"sources"`
+	// Offsets is the next offset to read from each source workflow's stream,
+	// keyed by workflow ID. It is carried over continue-as-new with StreamState.
+	Offsets     map[string]int64                     `json:"offsets,omitempty"`
+	StreamState *workflowstreams.WorkflowStreamState `json:"streamState,omitempty"`
+	// SourceCount is the number of sources of the first run, carried over
+	// continue-as-new since only the sources still running are. It defaults to
+	// the number of Sources.
+	SourceCount int `json:"sourceCount,omitempty"`
+}
+
+// AggregatorSource is a workflow whose stream topics the aggregator re-publishes.
+type AggregatorSource struct {
+	WorkflowID string        `json:"workflowId"`
+	Topics     []SourceTopic `json:"topics"`
+}
+
+// SourceTopic selects a topic of a source workflow's stream, the items of it to
+// re-publish and the fields to keep.
+type SourceTopic struct {
+	Topic string `json:"topic"`
+	// DerivedTopic is the aggregator topic the items are re-published to. It
+	// defaults to Topic.
+	DerivedTopic string `json:"derivedTopic,omitempty"`
+	// Filters must all match for an item to be re-published. No filters match
+	// every item.
+	Filters []FilterRule `json:"filters,omitempty"`
+	// Fields are the fields of an item to keep, as dot-separated paths. No fields
+	// keep the whole item.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// FilterRule matches items whose field, a dot-separated path, equals one of
+// Values. With Negate it matches the other items.
+type FilterRule struct {
+	Field  string        `json:"field"`
+	Values []interface{} `json:"values"`
+	Negate bool          `json:"negate,omitempty"`
+}
+
+// SourceOffset is sent with SourceOffsetSignal once the items of a source up
+// to NextOffset have been re-published.
+type SourceOffset struct {
+	WorkflowID string `json:"workflowId"`
+	NextOffset int64  `json:"nextOffset"`
+}
+
+// FollowSourceInput is the input to the FollowSource activity.
+type FollowSourceInput struct {
+	Source     AggregatorSource `json:"source"`
+	FromOffset int64            `json:"fromOffset"`
+}
+
 // Event types published to the stream. They are JSON-encoded by the default data
 // converter on the way in and decoded by subscribers on the way out.
 
@@ -110,6 +171,15 @@ type TextComplete struct {
 	FullText string `json:"fullText"`
 }
 
+// AggregatedEvent is an item of a source workflow's stream re-published by the
+// aggregator. Data holds the projected fields of the item.
+type AggregatedEvent struct {
+	Source string          `json:"source"`
+	Topic  string          `json:"topic"`
+	Offset int64           `json:"offset"`
+	Data   json.RawMessage `json:"data"`
+}
+
 // RetryEvent signals that the streaming activity is on a retry attempt, so
 // subscribers can reset any partially rendered output.
 type RetryEvent struct {
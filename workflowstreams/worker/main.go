@@ -24,7 +24,9 @@ func main() {
 	w.RegisterWorkflow(streams.PipelineWorkflow)
 	w.RegisterWorkflow(streams.HubWorkflow)
 	w.RegisterWorkflow(streams.TickerWorkflow)
+	w.RegisterWorkflow(streams.AggregatorWorkflow)
 	w.RegisterActivity(streams.ChargeCard)
+	w.RegisterActivity(streams.FollowSource)
 
 	if err := w.Run(worker.InterruptCh()); err != nil {
 		log.Fatalln("Unable to start worker", err)
@@ -164,3 +164,103 @@ func LLMWorkflow(ctx workflow.Context, input LLMInput) (string, error) {
 	_ = workflow.Sleep(ctx, drainDelay)
 	return result, nil
 }
+
+// AggregatorWorkflow (scenario 7) fans in the topics of many source workflows
+// into its own stream, for example a single dashboard feed across many orders.
+// A FollowSource activity per source re-publishes the items that pass the topic
+// filters, projected to the selected fields. The offset up to which each source
+// was re-published is carried over continue-as-new with the stream state, so
+// the next run resumes every subscription where it left off. The workflow
+// completes when all sources have completed, or on a close signal.
+func AggregatorWorkflow(ctx workflow.Context, input AggregatorInput) (string, error) {
+	logger := workflow.GetLogger(ctx)
+	//workflowcheck:ignore order-independent map copy in NewWorkflowStream; see OrderWorkflow
+	stream, err := workflowstreams.NewWorkflowStream(ctx, input.StreamState)
+	if err != nil {
+		return "", err
+	}
+	if input.SourceCount == 0 {
+		input.SourceCount = len(input.Sources)
+	}
+
+	offsets := make(map[string]int64, len(input.Sources))
+	for _, source := range input.Sources {
+		offsets[source.WorkflowID] = input.Offsets[source.WorkflowID]
+	}
+	recordOffset := func(offset SourceOffset) {
+		if offset.NextOffset > offsets[offset.WorkflowID] {
+			offsets[offset.WorkflowID] = offset.NextOffset
+		}
+	}
+	offsetCh := workflow.GetSignalChannel(ctx, SourceOffsetSignal)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		for {
+			var offset SourceOffset
+			offsetCh.Receive(ctx, &offset)
+			recordOffset(offset)
+		}
+	})
+	closed := false
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		workflow.GetSignalChannel(ctx, CloseSignal).Receive(ctx, nil)
+		closed = true
+	})
+
+	followCtx, cancelFollow := workflow.WithCancel(ctx)
+	followCtx = workflow.WithActivityOptions(followCtx, workflow.ActivityOptions{
+		StartToCloseTimeout: 24 * time.Hour,
+		HeartbeatTimeout:    30 * time.Second,
+		// Waits for the activities to report their progress before continue-as-new.
+		WaitForCancellation: true,
+	})
+	following := 0
+	var remaining []AggregatorSource
+	for _, source := range input.Sources {
+		following++
+		done := workflow.ExecuteActivity(followCtx, FollowSource, FollowSourceInput{
+			Source:     source,
+			FromOffset: offsets[source.WorkflowID],
+		})
+		workflow.Go(ctx, func(ctx workflow.Context) {
+			var next int64
+			if err := done.Get(ctx, &next); err != nil {
+				logger.Info("Stopped following source", "Source", source.WorkflowID, "Error", err)
+				remaining = append(remaining, source)
+			} else {
+				recordOffset(SourceOffset{WorkflowID: source.WorkflowID, NextOffset: next})
+			}
+			following--
+		})
+	}
+
+	continueAsNew := false
+	err = workflow.Await(ctx, func() bool {
+		continueAsNew = workflow.GetInfo(ctx).GetContinueAsNewSuggested()
+		return following == 0 || closed || continueAsNew
+	})
+	if err != nil {
+		return "", err
+	}
+	cancelFollow()
+	if err := workflow.Await(ctx, func() bool { return following == 0 }); err != nil {
+		return "", err
+	}
+	// Offsets reported right before the activities completed.
+	var offset SourceOffset
+	for offsetCh.ReceiveAsync(&offset) {
+		recordOffset(offset)
+	}
+
+	if continueAsNew && !closed && len(remaining) > 0 {
+		logger.Info("Continuing as new", "Sources", len(remaining))
+		return "", workflow.NewContinueAsNewError(ctx, AggregatorWorkflow, AggregatorInput{
+			Sources:     remaining,
+			Offsets:     offsets,
+			StreamState: stream.State(),
+			SourceCount: input.SourceCount,
+		})
+	}
+
+	_ = workflow.Sleep(ctx, drainDelay)
+	return fmt.Sprintf("aggregated %d sources", input.SourceCount), nil
+}
@@ -2,6 +2,7 @@ package streams
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"go.temporal.io/sdk/converter"
 	"go.temporal.io/sdk/testsuite"
 	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 )
 
 // The unit tests below cover the workflow side only. The client Subscribe path
@@ -85,6 +87,114 @@ func Test_LLMWorkflow(t *testing.T) {
 	require.Equal(t, "a streamed answer", result)
 }
 
+func Test_AggregatorWorkflow(t *testing.T) {
+	env := (&testsuite.WorkflowTestSuite{}).NewTestWorkflowEnvironment()
+	var followed []FollowSourceInput
+	env.OnActivity(FollowSource, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input FollowSourceInput) (int64, error) {
+			followed = append(followed, input)
+			return input.FromOffset + 3, nil
+		})
+
+	env.ExecuteWorkflow(AggregatorWorkflow, AggregatorInput{
+		Sources: []AggregatorSource{
+			{WorkflowID: "order-1", Topics: []SourceTopic{{Topic: TopicStatus}}},
+			{WorkflowID: "order-2", Topics: []SourceTopic{{Topic: TopicStatus}}},
+		},
+		Offsets: map[string]int64{"order-2": 5},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result string
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, "aggregated 2 sources", result)
+	require.Len(t, followed, 2)
+	require.ElementsMatch(t, []int64{0, 5}, []int64{followed[0].FromOffset, followed[1].FromOffset})
+}
+
+func Test_AggregatorWorkflow_ContinueAsNew(t *testing.T) {
+	env := (&testsuite.WorkflowTestSuite{}).NewTestWorkflowEnvironment()
+	followsSource := func(workflowID string) interface{} {
+		return mock.MatchedBy(func(input FollowSourceInput) bool { return input.Source.WorkflowID == workflowID })
+	}
+	// order-1 completes, the others are still followed when continue-as-new is suggested.
+	env.OnActivity(FollowSource, mock.Anything, followsSource("order-1")).Return(int64(3), nil)
+	env.OnActivity(FollowSource, mock.Anything, mock.Anything).After(time.Hour).Return(int64(0), nil)
+	env.RegisterDelayedCallback(func() {
+		env.SetContinueAsNewSuggested(true)
+		env.SignalWorkflow(SourceOffsetSignal, SourceOffset{WorkflowID: "order-2", NextOffset: 7})
+	}, time.Minute)
+
+	env.ExecuteWorkflow(AggregatorWorkflow, AggregatorInput{
+		Sources: []AggregatorSource{
+			{WorkflowID: "order-1", Topics: []SourceTopic{{Topic: TopicStatus}}},
+			{WorkflowID: "order-2", Topics: []SourceTopic{{Topic: TopicStatus}}},
+			{WorkflowID: "order-3", Topics: []SourceTopic{{Topic: TopicStatus}}},
+		},
+		Offsets: map[string]int64{"order-3": 2},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	var continueAsNewErr *workflow.ContinueAsNewError
+	require.ErrorAs(t, env.GetWorkflowError(), &continueAsNewErr)
+	var input AggregatorInput
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayloads(continueAsNewErr.Input, &input))
+	var remaining []string
+	for _, source := range input.Sources {
+		remaining = append(remaining, source.WorkflowID)
+	}
+	require.ElementsMatch(t, []string{"order-2", "order-3"}, remaining)
+	require.Equal(t, 3, input.SourceCount)
+	require.Equal(t, int64(7), input.Offsets["order-2"])
+	require.Equal(t, int64(2), input.Offsets["order-3"])
+
+	// The next run only follows the remaining sources, from their offsets.
+	env = (&testsuite.WorkflowTestSuite{}).NewTestWorkflowEnvironment()
+	var followed []FollowSourceInput
+	env.OnActivity(FollowSource, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input FollowSourceInput) (int64, error) {
+			followed = append(followed, input)
+			return input.FromOffset + 3, nil
+		})
+
+	env.ExecuteWorkflow(AggregatorWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result string
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, "aggregated 3 sources", result)
+	require.Len(t, followed, 2)
+	require.ElementsMatch(t, []int64{7, 2}, []int64{followed[0].FromOffset, followed[1].FromOffset})
+}
+
+func Test_SourceTopic_transform(t *testing.T) {
+	topic := SourceTopic{
+		Topic: TopicStatus,
+		Filters: []FilterRule{
+			{Field: "kind", Values: []interface{}{"shipped", "complete"}},
+			{Field: "order.priority", Values: []interface{}{0}, Negate: true},
+		},
+		Fields: []string{"kind", "order.id"},
+	}
+
+	data, ok := topic.transform(json.RawMessage(`{"kind":"shipped","order":{"id":"order-42","priority":1,"total":10}}`))
+	require.True(t, ok)
+	require.JSONEq(t, `{"kind":"shipped","order":{"id":"order-42"}}`, string(data))
+
+	_, ok = topic.transform(json.RawMessage(`{"kind":"received","order":{"id":"order-42","priority":1}}`))
+	require.False(t, ok)
+	_, ok = topic.transform(json.RawMessage(`{"kind":"complete","order":{"id":"order-42","priority":0}}`))
+	require.False(t, ok)
+	_, ok = topic.transform(json.RawMessage(`"not an object"`))
+	require.False(t, ok)
+
+	data, ok = SourceTopic{Topic: TopicTick}.transform(json.RawMessage(`{"n":1}`))
+	require.True(t, ok)
+	require.JSONEq(t, `{"n":1}`, string(data))
+}
+
 // Test_OrderWorkflow_DevServer runs the basic publish/subscribe scenario end to
 // end against a local dev server: it exercises publishing from both the workflow
 // and the activity (via the real client) and consuming via Subscribe.